@@ -18,6 +18,8 @@ type Config struct {
 	Port                      string `mapstructure:"PORT"`
 	SecretKey                 string `mapstructure:"SECRET_KEY"`
 	SecretKeyByte             []byte
+	JwtAlgorithm              string `mapstructure:"JWT_ALGORITHM"`         // EdDSA (default) or RS256
+	JwtKeyRotationDays        int    `mapstructure:"JWT_KEY_ROTATION_DAYS"` // Defaults to 30
 	FirstAdminEmail           string `mapstructure:"FIRST_ADMIN_EMAIL"`
 	FirstAdminPassword        string `mapstructure:"FIRST_ADMIN_PASSWORD"`
 	FirstInstructorEmail      string `mapstructure:"FIRST_INSTRUCTOR_EMAIL"`
@@ -37,8 +39,18 @@ type Config struct {
 	MailSenderPort            int    `mapstructure:"MAIL_SENDER_PORT"`
 	CORSAllowedOrigins        string `mapstructure:"CORS_ALLOWED_ORIGINS"`
 	CORSAllowCredentials      bool   `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	FrontendUrl               string `mapstructure:"FRONTEND_URL"`
 	GoogleClientID            string `mapstructure:"GOOGLE_CLIENT_ID"`
 	GoogleClientSecret        string `mapstructure:"GOOGLE_CLIENT_SECRET"`
+	GithubClientID            string `mapstructure:"GITHUB_CLIENT_ID"`
+	GithubClientSecret        string `mapstructure:"GITHUB_CLIENT_SECRET"`
+	MicrosoftClientID         string `mapstructure:"MICROSOFT_CLIENT_ID"`
+	MicrosoftClientSecret     string `mapstructure:"MICROSOFT_CLIENT_SECRET"`
+	MicrosoftTenant           string `mapstructure:"MICROSOFT_TENANT"`
+	OIDCProviderName          string `mapstructure:"OIDC_PROVIDER_NAME"`
+	OIDCIssuer                string `mapstructure:"OIDC_ISSUER"`
+	OIDCClientID              string `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret          string `mapstructure:"OIDC_CLIENT_SECRET"`
 	SocialsPassword           string `mapstructure:"SOCIALS_PASSWORD"`
 	StripePublicKey           string `mapstructure:"STRIPE_PUBLIC_KEY"`
 	StripeSecretKey           string `mapstructure:"STRIPE_SECRET_KEY"`
@@ -100,5 +112,11 @@ func GetConfig() (config Config) {
 	fmt.Println("----------------------------")
 
 	config.SecretKeyByte = []byte(config.SecretKey)
+	if config.JwtAlgorithm == "" {
+		config.JwtAlgorithm = "EdDSA"
+	}
+	if config.JwtKeyRotationDays == 0 {
+		config.JwtKeyRotationDays = 30
+	}
 	return
 }
\ No newline at end of file
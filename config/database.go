@@ -2,9 +2,12 @@ package config
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 
+	entsql "entgo.io/ent/dialect/sql"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/migrate"
 	_ "github.com/lib/pq"
@@ -27,10 +30,14 @@ func ConnectDb(cfg Config, ctx context.Context) *ent.Client {
 		sslmode,
 	)
 
-	client, err := ent.Open("postgres", dbUrl)
+	drv, err := entsql.Open("postgres", dbUrl)
 	if err != nil {
 		log.Fatalf("failed opening connection to postgres: %v", err)
 	}
+	if err := normalizeUserEmails(ctx, drv.DB()); err != nil {
+		log.Fatalf("failed normalizing user emails: %v", err)
+	}
+	client := ent.NewClient(ent.Driver(drv))
 	// Run the auto migration tool.
 	if err := client.Schema.Create(
 		ctx,
@@ -41,3 +48,55 @@ func ConnectDb(cfg Config, ctx context.Context) *ent.Client {
 	}
 	return client
 }
+
+// normalizeUserEmails lower-cases existing emails so that the unique index on users.email can be created and ignores case.
+// Accounts that share an email apart from case can't be told apart automatically, so startup stops with the
+// list of them until an operator merges them or changes their emails.
+func normalizeUserEmails(ctx context.Context, db *sql.DB) error {
+	var usersTable sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT to_regclass('users')::text").Scan(&usersTable); err != nil {
+		return err
+	}
+	if !usersTable.Valid {
+		return nil // Fresh database
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT lower(email), string_agg(id::text, ', ' ORDER BY created_at, id) FROM users
+		GROUP BY lower(email) HAVING count(*) > 1 ORDER BY lower(email)`)
+	if err != nil {
+		return err
+	}
+	duplicates := []string{}
+	for rows.Next() {
+		var email, ids string
+		if err := rows.Scan(&email, &ids); err != nil {
+			rows.Close()
+			return err
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s (users %s)", email, ids))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf(
+			"%d emails are shared by several users apart from case. Merge them or change all but one of each email, then restart: %s",
+			len(duplicates), strings.Join(duplicates, "; "),
+		)
+	}
+
+	if _, err := db.ExecContext(ctx, "UPDATE users SET email = lower(email) WHERE email <> lower(email)"); err != nil {
+		return err
+	}
+	// pending_email only exists once email changes have been migrated in
+	var hasPendingEmail bool
+	if err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'pending_email')`,
+	).Scan(&hasPendingEmail); err != nil || !hasPendingEmail {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "UPDATE users SET pending_email = lower(pending_email) WHERE pending_email <> lower(pending_email)")
+	return err
+}
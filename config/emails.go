@@ -22,6 +22,19 @@ const (
 	ET_PAYMENT_SUCC          EmailTypeChoice = "payment-succeeded"
 	ET_PAYMENT_FAIL          EmailTypeChoice = "payment-failed"
 	ET_PAYMENT_CANCEL        EmailTypeChoice = "payment-canceled"
+	ET_ACCOUNT_LOCKED        EmailTypeChoice = "account-locked"
+	ET_INSTRUCTOR_APP_RECV   EmailTypeChoice = "instructor-application-received"
+	ET_INSTRUCTOR_APP_APPR   EmailTypeChoice = "instructor-application-approved"
+	ET_INSTRUCTOR_APP_REJ    EmailTypeChoice = "instructor-application-rejected"
+	ET_ACCOUNT_DELETION      EmailTypeChoice = "account-deletion-scheduled"
+	ET_EMAIL_CHANGE          EmailTypeChoice = "email-change"
+	ET_EMAIL_CHANGE_NOTICE   EmailTypeChoice = "email-change-notice"
+	ET_MAGIC_LINK            EmailTypeChoice = "magic-link"
+	ET_NEW_LOGIN             EmailTypeChoice = "new-login"
+	ET_COURSE_APPROVED       EmailTypeChoice = "course-approved"
+	ET_COURSE_REJECTED       EmailTypeChoice = "course-rejected"
+	ET_NEW_DIRECT_MESSAGE    EmailTypeChoice = "new-direct-message"
+	ET_NOTIFICATION          EmailTypeChoice = "notification"
 )
 
 func sortEmail(emailType EmailTypeChoice, otp *uint32) map[string]interface{} {
@@ -53,6 +66,85 @@ func sortEmail(emailType EmailTypeChoice, otp *uint32) map[string]interface{} {
 		subject = "Password reset successfully"
 		data["template_file"] = templateFile
 		data["subject"] = subject
+
+	case ET_ACCOUNT_LOCKED:
+		templateFile = "templates/account-locked.html"
+		subject = "Your account has been locked"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_INSTRUCTOR_APP_RECV:
+		templateFile = "templates/instructor-application-received.html"
+		subject = "We received your instructor application"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_INSTRUCTOR_APP_APPR:
+		templateFile = "templates/instructor-application-approved.html"
+		subject = "Your instructor application was approved"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_INSTRUCTOR_APP_REJ:
+		templateFile = "templates/instructor-application-rejected.html"
+		subject = "Your instructor application was not approved"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_ACCOUNT_DELETION:
+		templateFile = "templates/account-deletion-scheduled.html"
+		subject = "Your account is scheduled for deletion"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_EMAIL_CHANGE:
+		templateFile = "templates/email-change.html"
+		subject = "Confirm your new email address"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+		data["otp"] = otp
+
+	case ET_EMAIL_CHANGE_NOTICE:
+		templateFile = "templates/email-change-notice.html"
+		subject = "Email change requested"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_MAGIC_LINK:
+		templateFile = "templates/magic-link.html"
+		subject = "Your login link"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_NEW_LOGIN:
+		templateFile = "templates/new-login.html"
+		subject = "New login to your account"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_COURSE_APPROVED:
+		templateFile = "templates/course-approved.html"
+		subject = "Your course was approved"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_COURSE_REJECTED:
+		templateFile = "templates/course-rejected.html"
+		subject = "Your course was not approved"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_NEW_DIRECT_MESSAGE:
+		templateFile = "templates/new-direct-message.html"
+		subject = "You have a new message"
+		data["template_file"] = templateFile
+		data["subject"] = subject
+
+	case ET_NOTIFICATION:
+		templateFile = "templates/notification.html"
+		subject = "You have a new notification"
+		data["template_file"] = templateFile
+		data["subject"] = subject
 	}
 	return data
 }
@@ -60,21 +152,51 @@ func sortEmail(emailType EmailTypeChoice, otp *uint32) map[string]interface{} {
 type EmailContext struct {
 	Name string
 	Otp *uint32
+	Token string
+	Link string
+	Message string
+	Subject string // Overrides the email type's subject when set
 }
 
 func SendEmail(user *ent.User, emailType EmailTypeChoice, otp *uint32) {
+	sendEmail(user, emailType, EmailContext{Otp: otp})
+}
+
+// SendTokenEmail sends an email carrying a one-time token (e.g to unlock an account) instead of an otp
+func SendTokenEmail(user *ent.User, emailType EmailTypeChoice, token string) {
+	sendEmail(user, emailType, EmailContext{Token: token})
+}
+
+// SendLinkEmail sends an email with a one-time token, along with a link carrying it when a frontend is configured
+func SendLinkEmail(user *ent.User, emailType EmailTypeChoice, token string, link string) {
+	sendEmail(user, emailType, EmailContext{Token: token, Link: link})
+}
+
+// SendMessageEmail sends an email with a free text message in it (e.g a rejection reason)
+func SendMessageEmail(user *ent.User, emailType EmailTypeChoice, message string) {
+	sendEmail(user, emailType, EmailContext{Message: message})
+}
+
+// SendNotificationEmail sends a notification by email, with its title as the subject
+func SendNotificationEmail(user *ent.User, title string, message string) {
+	sendEmail(user, ET_NOTIFICATION, EmailContext{Subject: title, Message: message})
+}
+
+func sendEmail(user *ent.User, emailType EmailTypeChoice, data EmailContext) {
 	if os.Getenv("ENVIRONMENT") == "test" {
 		return
 	}
 	cfg := GetConfig()
-	emailData := sortEmail(emailType, otp)
+	emailData := sortEmail(emailType, data.Otp)
 	templateFile := emailData["template_file"]
 	subject := emailData["subject"]
-
-	// Create a context with dynamic data
-	data := EmailContext{
-		Name: user.Name,
+	if data.Subject != "" {
+		subject = data.Subject
 	}
+
+	// Fill in the context with dynamic data
+	data.Name = user.Name
+	data.Otp = nil
 	if otp, ok := emailData["otp"]; ok {
 		otp := otp.(*uint32)
 		data.Otp = otp
@@ -4,17 +4,91 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log"
 	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 )
 
 var cld *cloudinary.Cloudinary
 var err error
 
+const (
+	DefaultMaxUploadSizeMB = 10
+	DefaultBodyLimitMB     = 10  // Body limit of every route but the upload ones
+	MaxRequestBodySizeMB   = 100 // Body limit of upload routes. Upload sizes can't be set above it
+	UploadRouteName        = "upload"
+)
+
+// Maximum size in MB of a single uploaded file. It is kept in sync with the platform settings
+var maxUploadSizeMB atomic.Int64
+
+func init() {
+	maxUploadSizeMB.Store(DefaultMaxUploadSizeMB)
+}
+
+func SetMaxUploadSizeMB(size int) {
+	maxUploadSizeMB.Store(int64(size))
+}
+
+func MaxUploadSizeMB() int {
+	return int(maxUploadSizeMB.Load())
+}
+
+// UploadBodyLimits raises the body limit of the routes named UploadRouteName to MaxRequestBodySizeMB.
+// Every other route keeps the app's BodyLimit. fasthttp applies the limit once the headers are in,
+// so larger bodies are refused before they are read. Call it after every route is registered.
+func UploadBodyLimits(app *fiber.App) {
+	uploadRoutes := []fiber.Route{}
+	for _, route := range app.GetRoutes(true) {
+		if route.Name == UploadRouteName {
+			uploadRoutes = append(uploadRoutes, route)
+		}
+	}
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+		for _, route := range uploadRoutes {
+			if route.Method == string(header.Method()) && routePathMatches(route.Path, path) {
+				return fasthttp.RequestConfig{MaxRequestBodySize: MaxRequestBodySizeMB * 1024 * 1024}
+			}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
+
+// routePathMatches compares a request path with a route path, where :params match any segment
+func routePathMatches(routePath string, path string) bool {
+	routeParts := strings.Split(strings.TrimSuffix(routePath, "/"), "/")
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(routeParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range routeParts {
+		if !strings.HasPrefix(part, ":") && !strings.EqualFold(part, pathParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a validation error when the file is larger than the configured upload size
+func validateFileSize(name string, file *multipart.FileHeader) *ErrorResponse {
+	maxSize := MaxUploadSizeMB()
+	if file.Size > int64(maxSize)*1024*1024 {
+		errData := ValidationErr(name, fmt.Sprintf("File cannot be larger than %dMB", maxSize))
+		return &errData
+	}
+	return nil
+}
+
 func initializeCloudinary() Config {
 	cfg := GetConfig()
 	// Initialize Cloudinary client
@@ -73,6 +147,34 @@ func UploadGeneratedCert(buf *bytes.Buffer, filename string) string {
 	return uploadResult.SecureURL
 }
 
+// DeleteUploadedFile removes a file stored by UploadFile, given its url
+// (e.g https://res.cloudinary.com/<cloud>/image/upload/v1712345678/production/chat_attachments/abc.png)
+func DeleteUploadedFile(fileUrl string) {
+	cfg := initializeCloudinary()
+	if cfg.Environment == "test" {
+		return
+	}
+	base, path, found := strings.Cut(fileUrl, "/upload/")
+	if !found {
+		log.Printf("not an uploaded file: %s\n", fileUrl)
+		return
+	}
+	resourceType := base[strings.LastIndex(base, "/")+1:]
+	if version, rest, found := strings.Cut(path, "/"); found && strings.HasPrefix(version, "v") {
+		if _, err := strconv.Atoi(version[1:]); err == nil {
+			path = rest
+		}
+	}
+	publicID := path
+	if resourceType != "raw" {
+		// Only raw files keep their extension in the public id
+		publicID = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	if _, err := cld.Upload.Destroy(context.Background(), uploader.DestroyParams{PublicID: publicID, ResourceType: resourceType}); err != nil {
+		log.Printf("failed to delete %s from Cloudinary: %v\n", fileUrl, err)
+	}
+}
+
 func ValidateFile(c *fiber.Ctx, name string, required bool, isVideo bool) (*multipart.FileHeader, *ErrorResponse) {
 	file, err := c.FormFile(name)
 	errData := ValidationErr(name, "Invalid file type")
@@ -84,6 +186,9 @@ func ValidateFile(c *fiber.Ctx, name string, required bool, isVideo bool) (*mult
 
 	// Open the file
 	if file != nil {
+		if errData := validateFileSize(name, file); errData != nil {
+			return nil, errData
+		}
 		fileHandle, err := file.Open()
 		if err != nil {
 			return nil, &errData
@@ -113,11 +218,41 @@ func ValidateFile(c *fiber.Ctx, name string, required bool, isVideo bool) (*mult
 	return nil, nil
 }
 
+// ValidateAttachment accepts the files that can be shared in messages: images, pdfs and mp4 videos
+func ValidateAttachment(c *fiber.Ctx, name string) (*multipart.FileHeader, *ErrorResponse) {
+	file, err := c.FormFile(name)
+	if err != nil {
+		return nil, nil
+	}
+	errData := ValidationErr(name, "Invalid file type. Attach an image, a pdf or an mp4 video")
+	if errData := validateFileSize(name, file); errData != nil {
+		return nil, errData
+	}
+	fileHandle, err := file.Open()
+	if err != nil {
+		return nil, &errData
+	}
+	defer fileHandle.Close()
+
+	buffer := make([]byte, 512)
+	if _, err := fileHandle.Read(buffer); err != nil {
+		return nil, &errData
+	}
+	switch http.DetectContentType(buffer) {
+	case "image/jpeg", "image/png", "image/gif", "application/pdf", "video/mp4":
+		return file, nil
+	}
+	return nil, &errData
+}
+
 type FILE_FOLDER_CHOICES string
 
 const (
-	FF_AVATARS       = "avatars"
-	FF_THUMBNAIL     = "thumbnails"
-	FF_INTRO_VIDEOS  = "intro_videos"
-	FF_LESSON_VIDEOS = "lesson_videos"
+	FF_AVATARS            = "avatars"
+	FF_THUMBNAIL          = "thumbnails"
+	FF_INTRO_VIDEOS       = "intro_videos"
+	FF_LESSON_VIDEOS      = "lesson_videos"
+	FF_INSTRUCTOR_SAMPLES = "instructor_samples"
+	FF_LOGOS              = "logos"
+	FF_CHAT_ATTACHMENTS   = "chat_attachments"
 )
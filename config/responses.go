@@ -41,6 +41,8 @@ var ERR_NOT_FOUND = "not_found"
 var ERR_LIMITS_REACHED = "limits_reached"
 var ERR_FORBIDDEN = "forbidden"
 var ERR_TOO_MANY_REQUESTS = "too_many_requests"
+var ERR_ACCOUNT_LOCKED = "account_locked"
+var ERR_ACCOUNT_DEACTIVATED = "account_deactivated"
 
 func RequestErr(code string, message string, opts ...map[string]string) ErrorResponse {
 	var data *map[string]string
@@ -1,9 +1,16 @@
 package config
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"math/rand"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -69,6 +76,64 @@ func CheckPasswordHash(password, hash string) bool {
     return err == nil
 }
 
+// NormalizeEmail lower-cases an email so that lookups and the unique index ignore case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// TOKEN HASHING
+// HashToken returns a sha256 hex digest for long random tokens that must be looked up but not stored in plain text
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateSecureToken returns a hex encoded token of n cryptographically random bytes
+func GenerateSecureToken(n int) string {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// EncryptWithSecret encrypts data at rest with AES-GCM, using a key derived from SECRET_KEY
+func EncryptWithSecret(plaintext []byte) string {
+	gcm := secretCipher()
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil))
+}
+
+// DecryptWithSecret reverses EncryptWithSecret. It fails if SECRET_KEY has changed since.
+func DecryptWithSecret(ciphertext string) ([]byte, error) {
+	data, err := hex.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	gcm := secretCipher()
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func secretCipher() cipher.AEAD {
+	key := sha256.Sum256(GetConfig().SecretKeyByte)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return gcm
+}
+
 // UUID PARSER
 func ParseUUID(input string) (*uuid.UUID, *ErrorResponse) {
 	uuidVal, err := uuid.Parse(input)
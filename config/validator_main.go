@@ -38,6 +38,9 @@ func init() {
 	// Register Custom Validators
 	customValidator.RegisterValidation("difficulty_type_validator", DifficultyTypeValidator)
 	customValidator.RegisterValidation("enrollment_type_validator", EnrollmentTypeValidator)
+	customValidator.RegisterValidation("user_role_type_validator", UserRoleTypeValidator)
+	customValidator.RegisterValidation("review_report_reason_validator", ReviewReportReasonValidator)
+	customValidator.RegisterValidation("notification_type_validator", NotificationTypeValidator)
 
 	RegisterTagName()
 }
@@ -61,6 +64,9 @@ func registerTranslations(param string) {
 	registerTranslation("required_without", "This field is required.", translator)
 	registerTranslation("difficulty_type_validator", "Invalid difficulty type. Choices are beginner, intermediate, advanced", translator)
 	registerTranslation("enrollment_type_validator", "Invalid difficulty type. Choices are open, restricted, inviteOnly", translator)
+	registerTranslation("user_role_type_validator", "Invalid role. Choices are student, instructor, admin", translator)
+	registerTranslation("review_report_reason_validator", "Invalid reason. Choices are spam, offensive, off_topic, other", translator)
+	registerTranslation("notification_type_validator", "Invalid type. Choices are payment_succeeded, payment_failed, payment_canceled, certificate_issued, review_reply, quiz_graded, announcement", translator)
 
 	minErrMsg := fmt.Sprintf("%s characters min", param)
 	registerTranslation("min", minErrMsg, translator)
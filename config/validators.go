@@ -7,6 +7,9 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notificationpreference"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewreport"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
 )
 
 // Validates if a account type value is the correct one
@@ -25,6 +28,9 @@ func ValidateImage(c *fiber.Ctx, name string, required bool) (*multipart.FileHea
 
 	// Open the file
 	if file != nil {
+		if errData := validateFileSize(name, file); errData != nil {
+			return nil, errData
+		}
 		fileHandle, err := file.Open()
 		if err != nil {
 			return nil, &errData
@@ -55,6 +61,18 @@ func DifficultyTypeValidator(fl validator.FieldLevel) bool {
 	return fieldVal == course.DifficultyBeginner || fieldVal == course.DifficultyAdvanced || fieldVal == course.DifficultyIntermediate
 }
 
+func UserRoleTypeValidator(fl validator.FieldLevel) bool {
+	return user.RoleValidator(fl.Field().Interface().(user.Role)) == nil
+}
+
+func ReviewReportReasonValidator(fl validator.FieldLevel) bool {
+	return reviewreport.ReasonValidator(fl.Field().Interface().(reviewreport.Reason)) == nil
+}
+
+func NotificationTypeValidator(fl validator.FieldLevel) bool {
+	return notificationpreference.TypeValidator(fl.Field().Interface().(notificationpreference.Type)) == nil
+}
+
 func EnrollmentTypeValidator(fl validator.FieldLevel) bool {
 	fieldVal := fl.Field().Interface().(course.EnrollmentType)
 	return fieldVal == course.EnrollmentTypeOpen || fieldVal == course.EnrollmentTypeInviteOnly || fieldVal == course.EnrollmentTypeRestricted
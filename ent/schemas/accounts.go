@@ -1,10 +1,13 @@
 package schemas
 
 import (
+	"time"
+
 	"entgo.io/ent"
 	"entgo.io/ent/dialect/entsql"
 	"entgo.io/ent/schema/edge"
 	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
 	"github.com/google/uuid"
 )
 
@@ -18,7 +21,7 @@ func (User) Fields() []ent.Field {
 		CommonFields,
 		field.String("name").NotEmpty().MaxLen(200).MinLen(2),
 		field.String("username").NotEmpty().Unique().MaxLen(100).MinLen(2),
-		field.String("email").NotEmpty(),
+		field.String("email").NotEmpty().Unique(),
 		field.String("password").MinLen(8).NotEmpty(),
 		field.Bool("is_verified").Default(false),
 		field.Bool("is_active").Default(true),
@@ -31,6 +34,24 @@ func (User) Fields() []ent.Field {
 		field.Time("last_summary_date").Optional().Nillable(),
 		field.Int("summary_count").Default(0),
 		field.Enum("role").Values("student", "instructor", "admin").Default("student"),
+		field.String("totp_secret").Optional().Nillable().Sensitive(),
+		field.Int64("totp_last_step").Default(0), // Time step of the last accepted TOTP code. Codes can't be used twice
+		field.Bool("two_factor_enabled").Default(false),
+		field.Strings("recovery_codes").Optional().Sensitive(),
+		field.Int("failed_login_attempts").Default(0),
+		field.Time("last_failed_login_at").Optional().Nillable(),
+		field.Time("locked_until").Optional().Nillable(),
+		field.String("unlock_token").Optional().Nillable().Sensitive(), // hashed
+		field.Int("otp_attempts").Default(0),
+		field.String("magic_link_token").Optional().Nillable().Sensitive(), // hashed
+		field.Time("magic_link_expiry").Optional().Nillable(),
+		field.String("pending_email").Optional().Nillable(), // Awaiting confirmation with pending_email_otp
+		// Kept apart from otp so a code sent to an unverified address can't reset the password or verify the account
+		field.Uint32("pending_email_otp").Optional().Nillable(),
+		field.Time("pending_email_otp_expiry").Optional().Nillable(),
+		field.Int("pending_email_otp_attempts").Default(0),
+		field.Time("deletion_scheduled_at").Optional().Nillable(),
+		field.Time("deleted_at").Optional().Nillable(), // Set once the account has been anonymised
 	)
 }
 
@@ -44,6 +65,26 @@ func (User) Edges() []ent.Edge {
 		edge.To("payments", Payment.Type).Annotations(entsql.OnDelete(entsql.SetNull)),
 		edge.To("quiz_results", QuizResult.Type).Annotations(entsql.OnDelete(entsql.SetNull)),
         edge.To("progress", LessonProgress.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("used_refresh_tokens", UsedRefreshToken.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("identities", UserIdentity.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("lockouts", AccountLockout.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("roles", Role.Type),
+		edge.To("instructor_applications", InstructorApplication.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("reviewed_applications", InstructorApplication.Type).Annotations(entsql.OnDelete(entsql.SetNull)),
+		edge.To("access_tokens", PersonalAccessToken.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("auth_events", AuthEvent.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("review_reports", ReviewReport.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("review_votes", ReviewVote.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("chat_messages", ChatMessage.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("student_conversations", Conversation.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("instructor_conversations", Conversation.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("direct_messages", DirectMessage.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("lesson_questions", LessonQuestion.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("lesson_answers", LessonAnswer.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("question_votes", QuestionVote.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("answer_votes", AnswerVote.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("notifications", Notification.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("notification_preferences", NotificationPreference.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
 	}
 }
 
@@ -57,8 +98,13 @@ func (Token) Fields() []ent.Field {
 	return append(
 		CommonFields,
 		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("family_id", uuid.UUID{}).Default(uuid.New),
 		field.String("access").NotEmpty(),
 		field.String("refresh").NotEmpty(),
+		field.String("device_name").Optional(),
+		field.String("user_agent").Optional(),
+		field.String("ip").Optional(),
+		field.Time("last_used_at").Default(time.Now),
 	)
 }
 
@@ -68,3 +114,210 @@ func (Token) Edges() []ent.Edge {
 		edge.From("user", User.Type).Ref("tokens").Field("user_id").Unique().Required(),
 	}
 }
+
+// UsedRefreshToken remembers refresh tokens that have already been rotated,
+// so that presenting one again can be detected as reuse of a leaked token.
+type UsedRefreshToken struct {
+	ent.Schema
+}
+
+// Fields of the UsedRefreshToken.
+func (UsedRefreshToken) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("family_id", uuid.UUID{}),
+		field.String("token_hash").Unique().NotEmpty(),
+		field.Time("expires_at"),
+	)
+}
+
+// Edges of the UsedRefreshToken.
+func (UsedRefreshToken) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("used_refresh_tokens").Field("user_id").Unique().Required(),
+	}
+}
+
+// UserIdentity links a user to an account on an external identity provider (google, github...)
+type UserIdentity struct {
+	ent.Schema
+}
+
+// Fields of the UserIdentity.
+func (UserIdentity) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.String("provider").NotEmpty(),
+		field.String("provider_user_id").NotEmpty(),
+		field.String("email").Optional(),
+	)
+}
+
+// Edges of the UserIdentity.
+func (UserIdentity) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("identities").Field("user_id").Unique().Required(),
+	}
+}
+
+func (UserIdentity) Indexes() []ent.Index {
+	return []ent.Index{
+		// An external account can only be linked to one user, and a user can only link one account per provider
+		index.Fields("provider", "provider_user_id").Unique(),
+		index.Fields("user_id", "provider").Unique(),
+	}
+}
+
+// AccountLockout records every time an account or an ip address was locked out after too many failed attempts
+type AccountLockout struct {
+	ent.Schema
+}
+
+// Fields of the AccountLockout.
+func (AccountLockout) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}).Optional().Nillable(), // Empty for ip lockouts
+		field.String("ip").Optional(),
+		field.Enum("reason").Values("login_failures", "ip_failures"),
+		field.Int("failed_attempts"),
+		field.Time("locked_until"),
+		field.Time("unlocked_at").Optional().Nillable(),
+	)
+}
+
+// Edges of the AccountLockout.
+func (AccountLockout) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("lockouts").Field("user_id").Unique(),
+	}
+}
+
+// Role groups named permissions. Every user implicitly holds the role named after their base role field
+// and can be given any number of extra roles (e.g teaching_assistant, support)
+type Role struct {
+	ent.Schema
+}
+
+// Fields of the Role.
+func (Role) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.String("name").NotEmpty().Unique().MaxLen(50),
+		field.String("description").Optional(),
+		field.Strings("permissions").Optional(),
+		field.Bool("is_system").Default(false), // student, instructor and admin cannot be renamed or deleted
+	)
+}
+
+// Edges of the Role.
+func (Role) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("users", User.Type).Ref("roles"),
+	}
+}
+
+// InstructorApplication is a student's request to become an instructor, reviewed by admins
+type InstructorApplication struct {
+	ent.Schema
+}
+
+// Fields of the InstructorApplication.
+func (InstructorApplication) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.String("bio").NotEmpty().MaxLen(2000),
+		field.Strings("expertise"),
+		field.String("sample_url").Optional().Nillable(),      // e.g a link to a talk or a published course
+		field.String("sample_file_url").Optional().Nillable(), // an uploaded sample lesson
+		field.Enum("status").Values("pending", "approved", "rejected").Default("pending"),
+		field.String("rejection_reason").Optional().Nillable(),
+		field.UUID("reviewed_by", uuid.UUID{}).Optional().Nillable(),
+		field.Time("reviewed_at").Optional().Nillable(),
+	)
+}
+
+// Edges of the InstructorApplication.
+func (InstructorApplication) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("instructor_applications").Field("user_id").Unique().Required(),
+		edge.From("reviewer", User.Type).Ref("reviewed_applications").Field("reviewed_by").Unique(),
+	}
+}
+
+// PersonalAccessToken is a long lived token for scripts and other programmatic access to the api
+type PersonalAccessToken struct {
+	ent.Schema
+}
+
+// Fields of the PersonalAccessToken.
+func (PersonalAccessToken) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.String("name").NotEmpty().MaxLen(100),
+		field.String("token_hash").Unique().NotEmpty().Sensitive(),
+		field.String("token_prefix").NotEmpty(), // The first characters of the token, to tell tokens apart
+		field.Strings("scopes"),
+		field.Time("expires_at"),
+		field.Time("last_used_at").Optional().Nillable(),
+	)
+}
+
+// Edges of the PersonalAccessToken.
+func (PersonalAccessToken) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("access_tokens").Field("user_id").Unique().Required(),
+	}
+}
+
+// SigningKey is a key pair used to sign jwts. Public keys are published at /.well-known/jwks.json
+type SigningKey struct {
+	ent.Schema
+}
+
+// Fields of the SigningKey.
+func (SigningKey) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.String("kid").Unique().NotEmpty(),
+		field.Enum("algorithm").Values("EdDSA", "RS256"),
+		field.Text("private_key").NotEmpty().Sensitive(), // PEM, encrypted with SECRET_KEY
+		field.Text("public_key").NotEmpty(),              // PEM
+		field.Time("retired_at").Optional().Nillable(),   // No longer signs, but still verifies until its tokens expire
+	)
+}
+
+// AuthEvent is an entry of the authentication audit log
+type AuthEvent struct {
+	ent.Schema
+}
+
+// Fields of the AuthEvent.
+func (AuthEvent) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}).Optional().Nillable(), // Empty when no account matched, e.g a login with an unknown email
+		field.String("event").NotEmpty(),
+		field.String("ip").Optional(),
+		field.String("user_agent").Optional(),
+		field.JSON("details", map[string]string{}).Optional(),
+	)
+}
+
+// Edges of the AuthEvent.
+func (AuthEvent) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("auth_events").Field("user_id").Unique(),
+	}
+}
+
+func (AuthEvent) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "created_at"),
+		index.Fields("event"),
+	}
+}
@@ -0,0 +1,104 @@
+package schemas
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"github.com/google/uuid"
+)
+
+// ChatMessage is a message sent in a course's chat room
+type ChatMessage struct {
+	ent.Schema
+}
+
+// Fields of the ChatMessage.
+func (ChatMessage) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("course_id", uuid.UUID{}),
+		field.UUID("sender_id", uuid.UUID{}),
+		field.Text("content").NotEmpty().MaxLen(2000),
+		field.Time("deleted_at").Optional().Nillable(), // Deleted by a moderator. Kept for audits but never shown
+		field.UUID("deleted_by", uuid.UUID{}).Optional().Nillable(),
+	)
+}
+
+// Edges of the ChatMessage.
+func (ChatMessage) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("course", Course.Type).Ref("chat_messages").Field("course_id").Unique().Required(),
+		edge.From("sender", User.Type).Ref("chat_messages").Field("sender_id").Unique().Required(),
+	}
+}
+
+func (ChatMessage) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("course_id", "created_at"),
+	}
+}
+
+// Conversation is a private thread between a student and an instructor whose course they're enrolled in
+type Conversation struct {
+	ent.Schema
+}
+
+// Fields of the Conversation.
+func (Conversation) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("student_id", uuid.UUID{}),
+		field.UUID("instructor_id", uuid.UUID{}),
+		field.UUID("course_id", uuid.UUID{}), // The course the conversation was started from
+		field.Time("last_message_at").Optional().Nillable(),
+	)
+}
+
+// Edges of the Conversation.
+func (Conversation) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("student", User.Type).Ref("student_conversations").Field("student_id").Unique().Required(),
+		edge.From("instructor", User.Type).Ref("instructor_conversations").Field("instructor_id").Unique().Required(),
+		edge.From("course", Course.Type).Ref("conversations").Field("course_id").Unique().Required(),
+		edge.To("messages", DirectMessage.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+	}
+}
+
+func (Conversation) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("student_id", "instructor_id").Unique(),
+	}
+}
+
+// DirectMessage is a message sent in a conversation
+type DirectMessage struct {
+	ent.Schema
+}
+
+// Fields of the DirectMessage.
+func (DirectMessage) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("conversation_id", uuid.UUID{}),
+		field.UUID("sender_id", uuid.UUID{}),
+		field.Text("content").MaxLen(2000).Optional(), // Can be empty when an attachment is sent
+		field.String("attachment_url").Optional().Nillable(),
+		field.Time("read_at").Optional().Nillable(),
+	)
+}
+
+// Edges of the DirectMessage.
+func (DirectMessage) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("conversation", Conversation.Type).Ref("messages").Field("conversation_id").Unique().Required(),
+		edge.From("sender", User.Type).Ref("direct_messages").Field("sender_id").Unique().Required(),
+	}
+}
+
+func (DirectMessage) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("conversation_id", "created_at"),
+	}
+}
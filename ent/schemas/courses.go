@@ -3,6 +3,7 @@ package schemas
 import (
 	"time"
 	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
 	"entgo.io/ent/schema/edge"
 	"entgo.io/ent/schema/field"
 	"entgo.io/ent/schema/index"
@@ -77,6 +78,14 @@ func (Course) Fields() []ent.Field {
 		field.Float("discount_price").Default(0.0),
 		field.Enum("enrollment_type").Values("open", "restricted", "invite_only").Default("open"),
 		field.Bool("certification").Default(true),
+		// Moderation: draft -> submitted -> approved/rejected -> published. is_published mirrors the published status
+		field.Enum("status").Values("draft", "submitted", "approved", "rejected", "published").Default("draft"),
+		field.String("rejection_reason").Optional().Nillable(),
+		field.Time("submitted_at").Optional().Nillable(),
+		field.Time("reviewed_at").Optional().Nillable(),
+		field.UUID("reviewed_by", uuid.UUID{}).Optional().Nillable(),
+		field.Time("published_at").Optional().Nillable(),   // First publication
+		field.Bool("republish_on_approval").Default(false), // Was live when major edits sent it back for review, so approval puts it live again
 	)
 }
 
@@ -86,10 +95,38 @@ func (Course) Edges() []ent.Edge {
 		edge.From("instructor", User.Type).Ref("courses").Field("instructor_id").Unique().Required(),
 		edge.From("category", Category.Type).Ref("courses").Field("category_id").Unique().Required(),
 		edge.To("tags", Tag.Type),
+		edge.To("sections", Section.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
 		edge.To("lessons", Lesson.Type),
 		edge.To("enrollments", Enrollment.Type),
 		edge.To("reviews", Review.Type),
 		edge.To("payments", Payment.Type),
+		edge.To("chat_messages", ChatMessage.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("conversations", Conversation.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("notifications", Notification.Type).Annotations(entsql.OnDelete(entsql.SetNull)),
+	}
+}
+
+// Section schema. Groups a course's lessons into a module of the curriculum
+type Section struct {
+	ent.Schema
+}
+
+// Fields of the Section.
+func (Section) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("course_id", uuid.UUID{}),
+		field.String("title").NotEmpty().MaxLen(200),
+		field.Text("description").Optional(),
+		field.Uint("order"),
+	)
+}
+
+// Edges of the Section.
+func (Section) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("course", Course.Type).Ref("sections").Field("course_id").Unique().Required(),
+		edge.To("lessons", Lesson.Type).Annotations(entsql.OnDelete(entsql.SetNull)), // Lessons of a deleted section are kept, outside any section
 	}
 }
 
@@ -103,16 +140,22 @@ func (Lesson) Fields() []ent.Field {
 	return append(
 		CommonFields,
 		field.UUID("course_id", uuid.UUID{}),
+		field.UUID("section_id", uuid.UUID{}).Optional().Nillable(),
 		field.String("title").NotEmpty(),
 		field.String("slug").Unique(),
 		field.Text("desc"),
 		field.String("thumbnail_url").NotEmpty(),
 		field.String("video_url").Optional(),
 		field.Text("content").Optional(),
-		field.Uint("order"),
+		field.Uint("order"), // Position within its section
 		field.Uint("duration").Default(1),
 		field.Bool("is_published").Default(false),
 		field.Bool("is_free_preview").Default(false),
+		// Unlock conditions. A lesson unlocks once every one that is set is met
+		field.Uint("unlock_after_days").Optional().Nillable(), // Days after enrollment
+		field.Time("unlock_at").Optional().Nillable(),
+		field.UUID("prerequisite_id", uuid.UUID{}).Optional().Nillable(),
+		field.Float("prerequisite_min_score").Optional().Nillable(), // When set, the prerequisite's quizzes must be passed with this score instead
 	)
 }
 
@@ -120,8 +163,11 @@ func (Lesson) Fields() []ent.Field {
 func (Lesson) Edges() []ent.Edge {
 	return []ent.Edge{
 		edge.From("course", Course.Type).Ref("lessons").Field("course_id").Unique().Required(),
+		edge.From("section", Section.Type).Ref("lessons").Field("section_id").Unique(),
+		edge.To("dependents", Lesson.Type).From("prerequisite").Field("prerequisite_id").Unique().Annotations(entsql.OnDelete(entsql.SetNull)),
 		edge.To("quizzes", Quiz.Type),
 		edge.To("progress", LessonProgress.Type),
+		edge.To("questions", LessonQuestion.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
 	}
 }
 
@@ -171,6 +217,7 @@ func (Enrollment) Fields() []ent.Field {
 		field.String("checkout_url").Optional(),
 		field.Int("progress").Default(0), // Percentage (0-100)
 		field.String("cert").Optional(),
+		field.Time("completed_at").Optional().Nillable(),
 	)
 }
 
@@ -202,6 +249,13 @@ func (Review) Fields() []ent.Field {
 		field.UUID("course_id", uuid.UUID{}),
 		field.Float("rating").Min(1.0).Max(5.0),
 		field.Text("comment").Optional(),
+		field.Bool("is_hidden").Default(false), // Hidden by a moderator. Left out of listings and ratings
+		field.String("hidden_reason").Optional().Nillable(),
+		field.Time("hidden_at").Optional().Nillable(),
+		field.Text("reply").Optional().Nillable(), // The instructor's public reply
+		field.Time("replied_at").Optional().Nillable(),
+		field.Int("helpful_count").Default(0),
+		field.Int("reports_count").Default(0), // Unresolved reports
 	)
 }
 
@@ -210,6 +264,8 @@ func (Review) Edges() []ent.Edge {
 	return []ent.Edge{
 		edge.From("user", User.Type).Ref("reviews").Field("user_id").Unique().Required(),
 		edge.From("course", Course.Type).Ref("reviews").Field("course_id").Unique().Required(),
+		edge.To("reports", ReviewReport.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("votes", ReviewVote.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
 	}
 }
 
@@ -220,6 +276,65 @@ func (Review) Indexes() []ent.Index {
 	}
 }
 
+// ReviewReport schema.
+type ReviewReport struct {
+	ent.Schema
+}
+
+// Fields of ReviewReport.
+func (ReviewReport) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("review_id", uuid.UUID{}),
+		field.Enum("reason").Values("spam", "offensive", "off_topic", "other"),
+		field.String("details").Optional().Nillable().MaxLen(1000),
+		field.Time("resolved_at").Optional().Nillable(), // Set once a moderator hides or restores the review
+	)
+}
+
+// Edges of ReviewReport.
+func (ReviewReport) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("review_reports").Field("user_id").Unique().Required(),
+		edge.From("review", Review.Type).Ref("reports").Field("review_id").Unique().Required(),
+	}
+}
+
+func (ReviewReport) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "review_id").Unique(),
+	}
+}
+
+// ReviewVote schema. A user's "helpful" vote on a review
+type ReviewVote struct {
+	ent.Schema
+}
+
+// Fields of ReviewVote.
+func (ReviewVote) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("review_id", uuid.UUID{}),
+	)
+}
+
+// Edges of ReviewVote.
+func (ReviewVote) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("review_votes").Field("user_id").Unique().Required(),
+		edge.From("review", Review.Type).Ref("votes").Field("review_id").Unique().Required(),
+	}
+}
+
+func (ReviewVote) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "review_id").Unique(),
+	}
+}
+
 // Quiz schema.
 type Quiz struct {
 	ent.Schema
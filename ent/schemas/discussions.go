@@ -0,0 +1,132 @@
+package schemas
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"github.com/google/uuid"
+)
+
+// LessonQuestion is a question asked on a lesson's discussion board
+type LessonQuestion struct {
+	ent.Schema
+}
+
+// Fields of the LessonQuestion.
+func (LessonQuestion) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("lesson_id", uuid.UUID{}),
+		field.UUID("user_id", uuid.UUID{}),
+		field.String("title").NotEmpty().MaxLen(200),
+		field.Text("body").NotEmpty().MaxLen(5000),
+		field.Int("upvotes_count").Default(0),
+		field.Int("answers_count").Default(0),    // Answers and their replies
+		field.Bool("is_resolved").Default(false), // Set while one of its answers is accepted
+	)
+}
+
+// Edges of the LessonQuestion.
+func (LessonQuestion) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("lesson", Lesson.Type).Ref("questions").Field("lesson_id").Unique().Required(),
+		edge.From("user", User.Type).Ref("lesson_questions").Field("user_id").Unique().Required(),
+		edge.To("answers", LessonAnswer.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+		edge.To("votes", QuestionVote.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+	}
+}
+
+func (LessonQuestion) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("lesson_id", "created_at"),
+	}
+}
+
+// LessonAnswer is an answer to a question, or a reply to an answer when it has a parent
+type LessonAnswer struct {
+	ent.Schema
+}
+
+// Fields of the LessonAnswer.
+func (LessonAnswer) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("question_id", uuid.UUID{}),
+		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("parent_id", uuid.UUID{}).Optional().Nillable(),
+		field.Text("body").NotEmpty().MaxLen(5000),
+		field.Int("upvotes_count").Default(0),
+		field.Bool("is_accepted").Default(false), // Accepted by the asker or the instructor. One per question
+		field.Bool("is_endorsed").Default(false), // Endorsed by the course's instructor
+	)
+}
+
+// Edges of the LessonAnswer.
+func (LessonAnswer) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("question", LessonQuestion.Type).Ref("answers").Field("question_id").Unique().Required(),
+		edge.From("user", User.Type).Ref("lesson_answers").Field("user_id").Unique().Required(),
+		edge.To("replies", LessonAnswer.Type).
+			Annotations(entsql.OnDelete(entsql.Cascade)).
+			From("parent").Field("parent_id").Unique(),
+		edge.To("votes", AnswerVote.Type).Annotations(entsql.OnDelete(entsql.Cascade)),
+	}
+}
+
+// QuestionVote schema. A user's upvote on a question
+type QuestionVote struct {
+	ent.Schema
+}
+
+// Fields of QuestionVote.
+func (QuestionVote) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("question_id", uuid.UUID{}),
+	)
+}
+
+// Edges of QuestionVote.
+func (QuestionVote) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("question_votes").Field("user_id").Unique().Required(),
+		edge.From("question", LessonQuestion.Type).Ref("votes").Field("question_id").Unique().Required(),
+	}
+}
+
+func (QuestionVote) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "question_id").Unique(),
+	}
+}
+
+// AnswerVote schema. A user's upvote on an answer
+type AnswerVote struct {
+	ent.Schema
+}
+
+// Fields of AnswerVote.
+func (AnswerVote) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.UUID("answer_id", uuid.UUID{}),
+	)
+}
+
+// Edges of AnswerVote.
+func (AnswerVote) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("answer_votes").Field("user_id").Unique().Required(),
+		edge.From("answer", LessonAnswer.Type).Ref("votes").Field("answer_id").Unique().Required(),
+	}
+}
+
+func (AnswerVote) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "answer_id").Unique(),
+	}
+}
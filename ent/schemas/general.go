@@ -42,5 +42,16 @@ func (SiteDetail) Fields() []ent.Field {
 			Default("https://wa.me/2348133831036"),
 		field.String("ig").
 			Default("https://instagram.com"),
+		field.String("support_email").
+			Default("kayprogrammer1@gmail.com"),
+		field.String("logo").
+			Optional().
+			Nillable(),
+		field.String("default_currency").
+			Default("usd"),
+		field.Int("ai_summary_daily_limit").
+			Default(10),
+		field.Int("max_upload_size_mb").
+			Default(10),
 	)
 }
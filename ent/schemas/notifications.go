@@ -0,0 +1,77 @@
+package schemas
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"github.com/google/uuid"
+)
+
+// Kinds of notifications. Shared by notifications and the preferences that turn them on or off
+var notificationTypes = []string{
+	"payment_succeeded", "payment_failed", "payment_canceled",
+	"certificate_issued", "review_reply", "quiz_graded", "announcement",
+}
+
+// Notification is an in-app notification delivered to a user
+type Notification struct {
+	ent.Schema
+}
+
+// Fields of the Notification.
+func (Notification) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.Enum("type").Values(notificationTypes...),
+		field.String("title").MaxLen(200),
+		field.Text("message"),
+		field.UUID("course_id", uuid.UUID{}).Optional().Nillable(), // The course it's about, if any
+		field.Time("read_at").Optional().Nillable(),
+	)
+}
+
+// Edges of the Notification.
+func (Notification) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("notifications").Field("user_id").Unique().Required(),
+		edge.From("course", Course.Type).Ref("notifications").Field("course_id").Unique(),
+	}
+}
+
+func (Notification) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "created_at"),
+	}
+}
+
+// NotificationPreference turns a type of notification on or off per channel for a user.
+// Types without a preference are delivered on every channel
+type NotificationPreference struct {
+	ent.Schema
+}
+
+// Fields of the NotificationPreference.
+func (NotificationPreference) Fields() []ent.Field {
+	return append(
+		CommonFields,
+		field.UUID("user_id", uuid.UUID{}),
+		field.Enum("type").Values(notificationTypes...),
+		field.Bool("in_app").Default(true),
+		field.Bool("email").Default(true),
+	)
+}
+
+// Edges of the NotificationPreference.
+func (NotificationPreference) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.From("user", User.Type).Ref("notification_preferences").Field("user_id").Unique().Required(),
+	}
+}
+
+func (NotificationPreference) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields("user_id", "type").Unique(),
+	}
+}
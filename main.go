@@ -13,7 +13,10 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/base/routes"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/general"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/profiles"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/seeding"
 )
 
@@ -48,12 +51,17 @@ func main() {
 	ctx := context.Background()
 	db := config.ConnectDb(cfg, ctx)
 	seeding.CreateInitialData(db, ctx, cfg)
+	accounts.InitSigningKeys(db, ctx)
+	go accounts.StartKeyRotation(db, ctx)
+	go profiles.StartAccountPurger(db, ctx)
+	general.LoadSettings(db, ctx)
+	go general.StartSettingsReloader(db, ctx)
 
 	app := fiber.New(fiber.Config{
-		BodyLimit: 10 * 1024 * 1024, // 15MB
+		BodyLimit: config.DefaultBodyLimitMB * 1024 * 1024, // Upload routes get more with config.UploadBodyLimits
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			if err == fiber.ErrRequestEntityTooLarge {
-				return config.APIError(c, fiber.StatusRequestEntityTooLarge, config.ServerErr("Request body is too large. Maximum allowed size is 10MB."))
+				return config.APIError(c, fiber.StatusRequestEntityTooLarge, config.ServerErr(fmt.Sprintf("Request body is too large. Maximum allowed size is %dMB, or %dMB for uploads.", config.DefaultBodyLimitMB, config.MaxRequestBodySizeMB)))
 			}
 			// Default error handler
 			code := fiber.StatusInternalServerError
@@ -100,6 +108,7 @@ func main() {
 	app.Use(swagger.New(swaggerCfg))
 
 	routes.SetupRoutes(app, db, cfg)
+	config.UploadBodyLimits(app)
 	app.Use(func(c *fiber.Ctx) error {
 		return config.APIError(c, 404, config.NotFoundErr("Path not found"))
 	})
@@ -0,0 +1,57 @@
+package accounts
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+const (
+	PAT_PREFIX                     = "pat_"
+	PersonalAccessTokenLength      = 24 // random bytes
+	PersonalAccessTokenDefaultDays = 30
+	personalAccessTokenShownLength = len(PAT_PREFIX) + 8
+)
+
+// GeneratePersonalAccessToken returns a new raw token. Only its hash is ever stored.
+func GeneratePersonalAccessToken() string {
+	return PAT_PREFIX + config.GenerateSecureToken(PersonalAccessTokenLength)
+}
+
+// DecodePersonalAccessToken returns the (unexpired) access token along with its user
+func DecodePersonalAccessToken(db *ent.Client, ctx context.Context, tokenStr string) (*ent.PersonalAccessToken, *string) {
+	tokenErr := "Access Token is Invalid or Expired!"
+	accessToken := userManager.GetPersonalAccessTokenByToken(db, ctx, tokenStr)
+	if accessToken == nil || time.Now().After(accessToken.ExpiresAt) {
+		return nil, &tokenErr
+	}
+	userManager.TouchPersonalAccessToken(db, ctx, accessToken)
+	return accessToken, nil
+}
+
+// RequestTokenScopes returns the scopes of the personal access token used for the request.
+// ok is false when the request was made with a regular login session.
+func RequestTokenScopes(c *fiber.Ctx) (scopes []string, ok bool) {
+	scopes, ok = c.Locals("token_scopes").([]string)
+	return scopes, ok
+}
+
+// RequireSession rejects requests made with a personal access token.
+// It guards endpoints that manage the account's credentials, so a leaked token can't be used to take the account over.
+// AuthMiddleware already turns tokens away, so this keeps those endpoints session only even if they move behind ScopedAuthMiddleware.
+// It must come after AuthMiddleware or ScopedAuthMiddleware.
+func RequireSession() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := RequestTokenScopes(c); ok {
+			return sessionRequiredErr(c)
+		}
+		return c.Next()
+	}
+}
+
+func sessionRequiredErr(c *fiber.Ctx) error {
+	return config.APIError(c, 403, config.ForbiddenErr("Personal access tokens can't be used here. Login instead"))
+}
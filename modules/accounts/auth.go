@@ -24,6 +24,17 @@ type RefreshTokenPayload struct {
 	jwt.RegisteredClaims
 }
 
+type TwoFactorChallengePayload struct {
+	UserId  uuid.UUID `json:"user_id"`
+	Purpose string    `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+const (
+	TwoFactorChallengePurpose       = "2fa"
+	TwoFactorChallengeExpireMinutes = 5
+)
+
 func GenerateAccessToken(userId uuid.UUID, userName string) string {
 	cfg := config.GetConfig()
 	expirationTime := time.Now().Add(time.Duration(cfg.AccessTokenExpireMinutes) * time.Minute)
@@ -36,10 +47,8 @@ func GenerateAccessToken(userId uuid.UUID, userName string) string {
 		},
 	}
 
-	// Declare the token with the algorithm used for signing, and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	// Create the JWT string
-	tokenString, err := token.SignedString(cfg.SecretKeyByte)
+	// Sign the claims with the current key (see keys.go)
+	tokenString, err := signToken(payload)
 	if err != nil {
 		// If there is an error in creating the JWT return an internal server error
 		log.Fatal("Error Generating Access token: ", err)
@@ -58,10 +67,8 @@ func GenerateRefreshToken() string {
 		},
 	}
 
-	// Declare the token with the algorithm used for signing, and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	// Create the JWT string
-	tokenString, err := token.SignedString(cfg.SecretKeyByte)
+	// Sign the claims with the current key (see keys.go)
+	tokenString, err := signToken(payload)
 	if err != nil {
 		// If there is an error in creating the JWT return an internal server error
 		log.Fatal("Error Generating Refresh token: ", err)
@@ -70,12 +77,9 @@ func GenerateRefreshToken() string {
 }
 
 func DecodeAccessToken(db *ent.Client, ctx context.Context, tokenStr string) (*ent.User, *string) {
-	cfg := config.GetConfig()
 	claims := &AccessTokenPayload{}
 
-	tkn, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return cfg.SecretKeyByte, nil
-	})
+	tkn, err := parseToken(tokenStr, claims)
 	tokenErr := "Auth Token is Invalid or Expired!"
 	if err != nil {
 		return nil, &tokenErr
@@ -84,35 +88,70 @@ func DecodeAccessToken(db *ent.Client, ctx context.Context, tokenStr string) (*e
 		return nil, &tokenErr
 	}
 
-	// Fetch User model object
+	// Fetch the session along with its User model object
 	userId := claims.UserId
-	user, _ := db.User.Query().Where(user.ID(userId), user.HasTokensWith(token.Access(tokenStr))).
+	session, _ := db.Token.Query().Where(token.Access(tokenStr), token.UserID(userId)).
+		WithUser().
 		Only(ctx)
 
-	if user == nil {
+	if session == nil {
 		return nil, &tokenErr
 	}
-	return user, nil
+	userManager.TouchSession(db, ctx, session)
+	return session.Edges.User, nil
 }
 
-func DecodeRefreshToken(db *ent.Client, ctx context.Context, tokenStr string) *ent.User {
-	cfg := config.GetConfig()
+// ValidateRefreshToken checks the signature and expiry of a refresh token without touching the database
+func ValidateRefreshToken(tokenStr string) bool {
 
 	claims := &RefreshTokenPayload{}
-	tkn, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return cfg.SecretKeyByte, nil
-	})
+	tkn, err := parseToken(tokenStr, claims)
 	if err != nil {
-		return nil
+		return false
 	}
 	if !tkn.Valid {
 		log.Println("Invalid Refresh Token")
+		return false
+	}
+	return true
+}
+
+// DecodeRefreshToken returns the session (with its user) currently holding the refresh token
+func DecodeRefreshToken(db *ent.Client, ctx context.Context, tokenStr string) *ent.Token {
+	if !ValidateRefreshToken(tokenStr) {
 		return nil
 	}
-	user, _ := db.User.Query().Where(user.HasTokensWith(token.Refresh(tokenStr))).
+	session, _ := db.Token.Query().Where(token.Refresh(tokenStr)).
+		WithUser().
 		Only(ctx)
-	if user == nil {
+	return session
+}
+
+// GenerateTwoFactorChallengeToken issues the short-lived token returned by login when 2FA is enabled.
+// It can only be exchanged for auth tokens alongside a valid 2FA code.
+func GenerateTwoFactorChallengeToken(userId uuid.UUID) (string, time.Time) {
+	expirationTime := time.Now().Add(TwoFactorChallengeExpireMinutes * time.Minute)
+	payload := TwoFactorChallengePayload{
+		UserId:  userId,
+		Purpose: TwoFactorChallengePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+	tokenString, err := signToken(payload)
+	if err != nil {
+		log.Fatal("Error Generating 2FA Challenge token: ", err)
+	}
+	return tokenString, expirationTime
+}
+
+func DecodeTwoFactorChallengeToken(db *ent.Client, ctx context.Context, tokenStr string) *ent.User {
+
+	claims := &TwoFactorChallengePayload{}
+	tkn, err := parseToken(tokenStr, claims)
+	if err != nil || !tkn.Valid || claims.Purpose != TwoFactorChallengePurpose {
 		return nil
 	}
+	user, _ := db.User.Query().Where(user.ID(claims.UserId), user.TwoFactorEnabled(true)).Only(ctx)
 	return user
-}
\ No newline at end of file
+}
@@ -0,0 +1,51 @@
+package accounts
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+type SecurityEventType string
+
+const (
+	SE_REGISTER                 SecurityEventType = "register"
+	SE_LOGIN                    SecurityEventType = "login"
+	SE_GOOGLE_LOGIN             SecurityEventType = "google_login"
+	SE_SOCIAL_LOGIN             SecurityEventType = "social_login"
+	SE_MAGIC_LINK_LOGIN         SecurityEventType = "magic_link_login"
+	SE_LOGIN_FAILED             SecurityEventType = "login_failed"
+	SE_REFRESH                  SecurityEventType = "refresh"
+	SE_LOGOUT                   SecurityEventType = "logout"
+	SE_LOGOUT_ALL               SecurityEventType = "logout_all"
+	SE_PASSWORD_RESET_REQUESTED SecurityEventType = "password_reset_requested"
+	SE_PASSWORD_RESET           SecurityEventType = "password_reset"
+	SE_OTP_FAILED               SecurityEventType = "otp_failed"
+	SE_REFRESH_TOKEN_REUSE      SecurityEventType = "refresh_token_reuse"
+	SE_ACCOUNT_LOCKED           SecurityEventType = "account_locked"
+	SE_ACCOUNT_UNLOCKED         SecurityEventType = "account_unlocked"
+	SE_ACCOUNT_DEACTIVATED      SecurityEventType = "account_deactivated"
+	SE_ACCOUNT_REACTIVATED      SecurityEventType = "account_reactivated"
+	SE_ROLE_CHANGED             SecurityEventType = "role_changed"
+	SE_FORCED_LOGOUT            SecurityEventType = "forced_logout"
+)
+
+// SecurityEventTypes lists every event recorded in the audit log
+var SecurityEventTypes = []SecurityEventType{
+	SE_REGISTER, SE_LOGIN, SE_GOOGLE_LOGIN, SE_SOCIAL_LOGIN, SE_MAGIC_LINK_LOGIN, SE_LOGIN_FAILED,
+	SE_REFRESH, SE_LOGOUT, SE_LOGOUT_ALL, SE_PASSWORD_RESET_REQUESTED, SE_PASSWORD_RESET,
+	SE_OTP_FAILED, SE_REFRESH_TOKEN_REUSE, SE_ACCOUNT_LOCKED, SE_ACCOUNT_UNLOCKED,
+	SE_ACCOUNT_DEACTIVATED, SE_ACCOUNT_REACTIVATED, SE_ROLE_CHANGED, SE_FORCED_LOGOUT,
+}
+
+// LoginEventTypes are the events of a successful login, whatever the method
+var LoginEventTypes = []SecurityEventType{SE_LOGIN, SE_GOOGLE_LOGIN, SE_SOCIAL_LOGIN, SE_MAGIC_LINK_LOGIN}
+
+// EmitSecurityEvent records an authentication event in the audit log, along with the ip and user agent of the request.
+// userID is nil when the event can't be tied to an account.
+func EmitSecurityEvent(db *ent.Client, c *fiber.Ctx, userID *uuid.UUID, eventType SecurityEventType, details map[string]string) {
+	log.Printf("[SECURITY] event=%s user=%v ip=%s details=%v\n", eventType, userID, c.IP(), details)
+	userManager.CreateAuthEvent(db, c.Context(), userID, eventType, c.IP(), c.Get("User-Agent"), details)
+}
@@ -0,0 +1,110 @@
+package accounts
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FakeIdentityProvider is a minimal in-memory OpenID Connect provider.
+// It lets the social login flow be exercised offline: serve it with httptest.NewServer (or any http server),
+// point OIDC_ISSUER at its url (an empty issuer is taken from the request host) and post any code registered with AddUser to /auth/social/{OIDC_PROVIDER_NAME}.
+// It performs no client authentication and only exists for tests.
+type FakeIdentityProvider struct {
+	Issuer string
+
+	mu     sync.Mutex
+	codes  map[string]fakeIdpUser // authorization code => user
+	tokens map[string]fakeIdpUser // access token => user
+}
+
+type fakeIdpUser struct {
+	info SocialUserInfo
+	// Leaves email_verified out of userinfo, as microsoft does, so it is only in the id token
+	idTokenVerificationOnly bool
+}
+
+func NewFakeIdentityProvider(issuer string) *FakeIdentityProvider {
+	return &FakeIdentityProvider{
+		Issuer: strings.TrimSuffix(issuer, "/"),
+		codes:  map[string]fakeIdpUser{},
+		tokens: map[string]fakeIdpUser{},
+	}
+}
+
+// AddUser registers an authorization code which will log the given user in
+func (f *FakeIdentityProvider) AddUser(code string, info SocialUserInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes[code] = fakeIdpUser{info: info}
+}
+
+// AddIdTokenVerifiedUser is like AddUser, but the user's userinfo response has no email_verified claim like with microsoft.
+// Whether the email is verified is only told by the id token
+func (f *FakeIdentityProvider) AddIdTokenVerifiedUser(code string, info SocialUserInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes[code] = fakeIdpUser{info: info, idTokenVerificationOnly: true}
+}
+
+func (f *FakeIdentityProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		issuer := f.Issuer
+		if issuer == "" {
+			// Handy with httptest, where the url is only known once the server is running
+			issuer = "http://" + r.Host
+		}
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			UserinfoEndpoint:      issuer + "/userinfo",
+		})
+	case "/token":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		code := r.PostFormValue("code")
+		fakeUser, ok := f.codes[code]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		// Codes are single use, like with a real provider
+		delete(f.codes, code)
+		accessToken := "fake-access-" + code
+		f.tokens[accessToken] = fakeUser
+		// The id token is left unsigned, since clients only read it when it comes straight from this endpoint
+		idTokenClaims, _ := json.Marshal(map[string]interface{}{
+			"sub": fakeUser.info.ProviderUserID, "email": fakeUser.info.Email, "email_verified": fakeUser.info.EmailVerified,
+		})
+		idToken := "e30." + base64.RawURLEncoding.EncodeToString(idTokenClaims) + "."
+		json.NewEncoder(w).Encode(map[string]string{"access_token": accessToken, "id_token": idToken, "token_type": "Bearer"})
+	case "/userinfo":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		fakeUser, ok := f.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		info := fakeUser.info
+		claims := map[string]interface{}{
+			"sub":   info.ProviderUserID,
+			"email": info.Email,
+			"name":  info.Name,
+		}
+		if !fakeUser.idTokenVerificationOnly {
+			claims["email_verified"] = info.EmailVerified
+		}
+		if info.Avatar != nil {
+			claims["picture"] = *info.Avatar
+		}
+		json.NewEncoder(w).Encode(claims)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
@@ -0,0 +1,267 @@
+package accounts
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/signingkey"
+)
+
+// Jwts are signed with an asymmetric key pair identified by the kid header.
+// A new pair is generated every JWT_KEY_ROTATION_DAYS. Retired pairs no longer sign but keep verifying
+// until every token they signed has expired, and all of them are published at /.well-known/jwks.json
+// so that other services can verify EDNET tokens without knowing any secret.
+
+const (
+	keyRotationCheckInterval = time.Hour
+	keyReloadMinInterval     = 10 * time.Second // Throttles reloads caused by unknown kids
+	rsaKeyBits               = 2048
+)
+
+var jwtSigningMethods = map[string]jwt.SigningMethod{
+	"EdDSA": jwt.SigningMethodEdDSA,
+	"RS256": jwt.SigningMethodRS256,
+}
+
+type signingKeyPair struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+type keyRing struct {
+	mu       sync.RWMutex
+	db       *ent.Client
+	current  *signingKeyPair
+	keys     map[string]*signingKeyPair
+	loadedAt time.Time
+}
+
+var signingKeys = &keyRing{keys: map[string]*signingKeyPair{}}
+
+// InitSigningKeys makes sure a signing key exists and loads the active keys. It must run before any token is issued.
+func InitSigningKeys(db *ent.Client, ctx context.Context) {
+	cfg := config.GetConfig()
+	if _, ok := jwtSigningMethods[cfg.JwtAlgorithm]; !ok {
+		log.Fatalf("Unsupported JWT_ALGORITHM %q. Use EdDSA or RS256", cfg.JwtAlgorithm)
+	}
+	signingKeys.db = db
+	RotateSigningKeys(db, ctx)
+}
+
+// StartKeyRotation checks periodically whether the signing key is due for rotation, until the context is cancelled
+func StartKeyRotation(db *ent.Client, ctx context.Context) {
+	ticker := time.NewTicker(keyRotationCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("Signing key rotation failed: %v\n", r)
+					}
+				}()
+				RotateSigningKeys(db, ctx)
+			}()
+		}
+	}
+}
+
+// RotateSigningKeys generates a new signing key when the current one is too old (or uses another algorithm),
+// and deletes retired keys whose tokens have all expired
+func RotateSigningKeys(db *ent.Client, ctx context.Context) {
+	cfg := config.GetConfig()
+	db.SigningKey.Delete().
+		Where(signingkey.RetiredAtLT(time.Now().Add(-maxTokenLifetime(cfg)))).
+		ExecX(ctx)
+
+	signingKeys.load(ctx)
+	current := signingKeys.signingKey()
+	if current != nil && current.method.Alg() == cfg.JwtAlgorithm {
+		currentObj, _ := db.SigningKey.Query().Where(signingkey.Kid(current.kid)).Only(ctx)
+		if currentObj != nil && time.Since(currentObj.CreatedAt) < time.Duration(cfg.JwtKeyRotationDays)*24*time.Hour {
+			return
+		}
+	}
+
+	newKey := generateSigningKey(db, ctx, cfg.JwtAlgorithm)
+	db.SigningKey.Update().
+		Where(signingkey.KidNEQ(newKey.Kid), signingkey.RetiredAtIsNil()).
+		SetRetiredAt(time.Now()).
+		ExecX(ctx)
+	log.Printf("Rotated jwt signing key, new kid=%s\n", newKey.Kid)
+	signingKeys.load(ctx)
+}
+
+// maxTokenLifetime is how long a retired key must keep verifying tokens
+func maxTokenLifetime(cfg config.Config) time.Duration {
+	minutes := max(cfg.AccessTokenExpireMinutes, cfg.RefreshTokenExpireMinutes, TwoFactorChallengeExpireMinutes)
+	return time.Duration(minutes) * time.Minute
+}
+
+func generateSigningKey(db *ent.Client, ctx context.Context, algorithm string) *ent.SigningKey {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		panic(err)
+	}
+	privateDer, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		panic(err)
+	}
+	publicDer, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		panic(err)
+	}
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer})
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})
+	return db.SigningKey.Create().
+		SetKid(config.GenerateSecureToken(8)).
+		SetAlgorithm(signingkey.Algorithm(algorithm)).
+		SetPrivateKey(config.EncryptWithSecret(privatePem)).
+		SetPublicKey(string(publicPem)).
+		SaveX(ctx)
+}
+
+// load replaces the keys in memory with those in the database
+func (r *keyRing) load(ctx context.Context) {
+	keyObjs := r.db.SigningKey.Query().Order(ent.Desc(signingkey.FieldCreatedAt)).AllX(ctx)
+	keys := map[string]*signingKeyPair{}
+	var current *signingKeyPair
+	for _, keyObj := range keyObjs {
+		pair, err := parseSigningKey(keyObj)
+		if err != nil {
+			log.Printf("Skipping unreadable signing key %s: %v\n", keyObj.Kid, err)
+			continue
+		}
+		keys[pair.kid] = pair
+		if current == nil && keyObj.RetiredAt == nil {
+			current = pair
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+	r.current = current
+	r.loadedAt = time.Now()
+}
+
+func parseSigningKey(keyObj *ent.SigningKey) (*signingKeyPair, error) {
+	privatePem, err := config.DecryptWithSecret(keyObj.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(privatePem)
+	if block == nil {
+		return nil, errors.New("invalid private key pem")
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key can't sign")
+	}
+	return &signingKeyPair{
+		kid:     keyObj.Kid,
+		method:  jwtSigningMethods[string(keyObj.Algorithm)],
+		private: signer,
+		public:  signer.Public(),
+	}, nil
+}
+
+func (r *keyRing) signingKey() *signingKeyPair {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// verificationKey finds the key a token was signed with.
+// Another instance may have rotated keys in the meantime, so an unknown kid triggers a reload.
+func (r *keyRing) verificationKey(kid string) *signingKeyPair {
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	stale := time.Since(r.loadedAt) > keyReloadMinInterval
+	r.mu.RUnlock()
+	if !ok && stale && r.db != nil {
+		r.load(context.Background())
+		r.mu.RLock()
+		key = r.keys[kid]
+		r.mu.RUnlock()
+	}
+	return key
+}
+
+// signToken signs the claims with the current key, setting the kid header
+func signToken(claims jwt.Claims) (string, error) {
+	key := signingKeys.signingKey()
+	if key == nil {
+		return "", errors.New("no signing key loaded")
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// parseToken verifies a token signed by signToken and fills in the claims
+func parseToken(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key := signingKeys.verificationKey(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{"EdDSA", "RS256"}))
+}
+
+// PublicJWKs returns every key that can currently verify tokens, in JSON Web Key format
+func PublicJWKs() []JWKSchema {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+	jwks := make([]JWKSchema, 0, len(signingKeys.keys))
+	for _, key := range signingKeys.keys {
+		jwk := JWKSchema{Kid: key.kid, Alg: key.method.Alg(), Use: "sig"}
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
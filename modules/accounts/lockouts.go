@@ -0,0 +1,196 @@
+package accounts
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+const (
+	LoginDelayAfter   = 3 // failed logins allowed before delays kick in
+	MaxLoginDelay     = 60 * time.Second
+	MaxFailedLogins   = 10 // failed logins before the account is locked
+	LockoutDuration   = 30 * time.Minute
+	MaxOtpAttempts    = 5 // wrong otps before the otp is invalidated
+	MaxIPFailures     = 30
+	IPFailureWindow   = 15 * time.Minute
+	UnlockTokenLength = 16 // in bytes
+)
+
+// LoginDelay is the wait imposed after a failed login. It doubles with every failure past LoginDelayAfter
+func LoginDelay(failedAttempts int) time.Duration {
+	if failedAttempts < LoginDelayAfter {
+		return 0
+	}
+	delay := time.Second << (failedAttempts - LoginDelayAfter)
+	if delay > MaxLoginDelay || delay <= 0 {
+		delay = MaxLoginDelay
+	}
+	return delay
+}
+
+// LoginRetryAfter returns how long the user must wait before another login attempt is accepted
+func LoginRetryAfter(userObj *ent.User) time.Duration {
+	now := time.Now()
+	if userObj.LockedUntil != nil && now.Before(*userObj.LockedUntil) {
+		return userObj.LockedUntil.Sub(now)
+	}
+	if userObj.LastFailedLoginAt != nil {
+		if wait := userObj.LastFailedLoginAt.Add(LoginDelay(userObj.FailedLoginAttempts)).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+func IsLocked(userObj *ent.User) bool {
+	return userObj.LockedUntil != nil && time.Now().Before(*userObj.LockedUntil)
+}
+
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// TooManyAttemptsErr responds with a 429 and tells the client when to retry
+func TooManyAttemptsErr(c *fiber.Ctx, wait time.Duration, message string) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(wait)))
+	return config.APIError(c, 429, config.RequestErr(config.ERR_TOO_MANY_REQUESTS, message))
+}
+
+func AccountLockedErr(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(wait)))
+	return config.APIError(c, 403, config.RequestErr(config.ERR_ACCOUNT_LOCKED, "Account locked after too many failed login attempts. Check your email to unlock it or try again later"))
+}
+
+func AccountDeactivatedErr(c *fiber.Ctx) error {
+	return config.APIError(c, 403, config.RequestErr(config.ERR_ACCOUNT_DEACTIVATED, "This account has been deactivated. Contact support"))
+}
+
+// checkLoginAllowed rejects attempts on deactivated or locked accounts and attempts made before the progressive delay has passed
+func checkLoginAllowed(c *fiber.Ctx, userObj *ent.User) error {
+	if !userObj.IsActive {
+		return AccountDeactivatedErr(c)
+	}
+	if IsLocked(userObj) {
+		return AccountLockedErr(c, time.Until(*userObj.LockedUntil))
+	}
+	if wait := LoginRetryAfter(userObj); wait > 0 {
+		return TooManyAttemptsErr(c, wait, fmt.Sprintf("Too many failed login attempts. Try again in %d seconds", retryAfterSeconds(wait)))
+	}
+	return nil
+}
+
+// recordFailedLogin counts a failure against both the account and the ip.
+// It reports whether the account just got locked, in which case the user is emailed an unlock token.
+func recordFailedLogin(db *ent.Client, c *fiber.Ctx, userObj *ent.User) bool {
+	ctx := c.Context()
+	ip := c.IP()
+	userManager.RecordIPFailure(db, ctx, ip)
+	unlockToken := userManager.RecordFailedLogin(db, ctx, userObj, ip)
+	if unlockToken == nil {
+		return false
+	}
+	go config.SendTokenEmail(userObj, config.ET_ACCOUNT_LOCKED, *unlockToken)
+	EmitSecurityEvent(db, c, &userObj.ID, SE_ACCOUNT_LOCKED, nil)
+	return true
+}
+
+// CheckOtp validates an email otp. Wrong guesses count against the ip, and the otp is invalidated after MaxOtpAttempts
+func CheckOtp(db *ent.Client, c *fiber.Ctx, userObj *ent.User, otp uint32) error {
+	return checkOtp(db, c, userObj, otp, userObj.Otp, userObj.OtpExpiry, func() bool {
+		return userManager.RecordFailedOtp(db, c.Context(), userObj)
+	})
+}
+
+// CheckEmailChangeOtp validates the otp sent to a pending new email, the same way as CheckOtp
+func CheckEmailChangeOtp(db *ent.Client, c *fiber.Ctx, userObj *ent.User, otp uint32) error {
+	return checkOtp(db, c, userObj, otp, userObj.PendingEmailOtp, userObj.PendingEmailOtpExpiry, func() bool {
+		return userManager.RecordFailedEmailChangeOtp(db, c.Context(), userObj)
+	})
+}
+
+// checkOtp compares otp with the expected one. recordFailure counts a wrong guess and reports whether it invalidated the otp
+func checkOtp(db *ent.Client, c *fiber.Ctx, userObj *ent.User, otp uint32, expected *uint32, expiry *time.Time, recordFailure func() bool) error {
+	ctx := c.Context()
+	if expected == nil || *expected != otp {
+		userManager.RecordIPFailure(db, ctx, c.IP())
+		EmitSecurityEvent(db, c, &userObj.ID, SE_OTP_FAILED, map[string]string{"reason": "incorrect"})
+		if expected != nil && recordFailure() {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_EXPIRED_OTP, "Too many incorrect attempts. Request a new otp"))
+		}
+		return config.APIError(c, 404, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect Otp"))
+	}
+	if expiry == nil || time.Now().After(*expiry) {
+		EmitSecurityEvent(db, c, &userObj.ID, SE_OTP_FAILED, map[string]string{"reason": "expired"})
+		return config.APIError(c, 400, config.RequestErr(config.ERR_EXPIRED_OTP, "Expired Otp"))
+	}
+	return nil
+}
+
+// ----------------------------------
+// PER-IP FAILURE TRACKING
+// --------------------------------
+// Failures are counted in memory over a fixed window, like the global rate limiter in main.go
+type ipFailures struct {
+	count       int
+	windowStart time.Time
+	blockedTill time.Time
+}
+
+type ipFailureTracker struct {
+	mu       sync.Mutex
+	failures map[string]*ipFailures
+}
+
+var ipTracker = &ipFailureTracker{failures: map[string]*ipFailures{}}
+
+// RetryAfter returns how long an ip is still blocked for
+func (t *ipFailureTracker) RetryAfter(ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.failures[ip]
+	if !ok {
+		return 0
+	}
+	if wait := time.Until(entry.blockedTill); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Record counts a failure and reports whether it just got the ip blocked
+func (t *ipFailureTracker) Record(ip string) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	entry, ok := t.failures[ip]
+	if !ok || now.Sub(entry.windowStart) > IPFailureWindow {
+		blockedTill := time.Time{}
+		if ok {
+			blockedTill = entry.blockedTill
+		}
+		entry = &ipFailures{windowStart: now, blockedTill: blockedTill}
+		t.failures[ip] = entry
+		t.purge(now)
+	}
+	entry.count++
+	if entry.count == MaxIPFailures {
+		entry.blockedTill = now.Add(IPFailureWindow)
+		return entry.count, true
+	}
+	return entry.count, false
+}
+
+func (t *ipFailureTracker) purge(now time.Time) {
+	for ip, entry := range t.failures {
+		if now.Sub(entry.windowStart) > IPFailureWindow && now.After(entry.blockedTill) {
+			delete(t.failures, ip)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package accounts
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+)
+
+const (
+	MagicLinkExpiry      = 15 * time.Minute
+	MagicLinkTokenLength = 32 // in bytes
+)
+
+// MagicLinkUrl points the emailed link at the frontend, which posts the token to /auth/magic-link/verify.
+// It is empty when no FRONTEND_URL is configured, in which case only the token is emailed.
+func MagicLinkUrl(cfg config.Config, token string) string {
+	if cfg.FrontendUrl == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/auth/magic-link?token=%s", strings.TrimSuffix(cfg.FrontendUrl, "/"), url.QueryEscape(token))
+}
@@ -0,0 +1,33 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enttest"
+)
+
+func TestConsumeMagicLinkTokenWorksOnce(t *testing.T) {
+	db := enttest.Open(t, "sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared&_fk=1")
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	userObj := db.User.Create().
+		SetName("Magic User").
+		SetEmail("magic@example.com").
+		SetUsername("magic").
+		SetPassword(config.HashPassword("password")).
+		SaveX(ctx)
+
+	magicToken := userManager.SetMagicLinkToken(db, ctx, userObj)
+	consumed := userManager.ConsumeMagicLinkToken(db, ctx, magicToken)
+	if consumed == nil || consumed.ID != userObj.ID {
+		t.Fatalf("expected the link to log in %s, got %v", userObj.ID, consumed)
+	}
+	if consumed.MagicLinkExpiry == nil {
+		t.Error("expected the consumed user to keep the link expiry")
+	}
+	if again := userManager.ConsumeMagicLinkToken(db, ctx, magicToken); again != nil {
+		t.Error("expected a used link to be rejected")
+	}
+}
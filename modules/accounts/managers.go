@@ -2,13 +2,24 @@ package accounts
 
 import (
 	"context"
+	"log"
+	"strings"
 	"time"
 
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/sqljson"
+	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/accountlockout"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/authevent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/personalaccesstoken"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/role"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/token"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/usedrefreshtoken"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/useridentity"
 )
 
 // ----------------------------------
@@ -36,7 +47,7 @@ func (obj UserManager) GetByRefreshToken(db *ent.Client, ctx context.Context, to
 func (obj UserManager) GetByEmail(db *ent.Client, ctx context.Context, email string) *ent.User {
 	u, _ := db.User.
 		Query().
-		Where(user.Email(email)).
+		Where(user.Email(config.NormalizeEmail(email))).
 		Only(ctx)
 	return u
 }
@@ -54,7 +65,7 @@ func (obj UserManager) GetByEmailOrUsername(db *ent.Client, ctx context.Context,
 		Query().
 		Where(
 			user.Or(
-				user.Email(emailOrUsername), 
+				user.Email(config.NormalizeEmail(emailOrUsername)),
 				user.Username(emailOrUsername),
 			),
 		).
@@ -79,7 +90,7 @@ func (obj UserManager) Create(db *ent.Client, ctx context.Context, userData Regi
 
 	u := db.User.Create().
 		SetName(userData.Name).
-		SetEmail(userData.Email).
+		SetEmail(config.NormalizeEmail(userData.Email)).
 		SetUsername(userData.Username).
 		SetPassword(password).
 		SetRole(role).
@@ -110,22 +121,92 @@ func (obj UserManager) GetOrCreate(db *ent.Client, ctx context.Context, userData
 	return user
 }
 
-func (obj UserManager) AddTokens(db *ent.Client, ctx context.Context, user *ent.User, access string, refresh string) {
+func (obj UserManager) AddTokens(db *ent.Client, ctx context.Context, user *ent.User, access string, refresh string, session SessionInfo) {
 	db.Token.
         Create().
 		SetUserID(user.ID).
         SetAccess(access).
         SetRefresh(refresh).
+		SetDeviceName(session.DeviceName).
+		SetUserAgent(session.UserAgent).
+		SetIP(session.IP).
         SaveX(ctx)
 }
 
-func (obj UserManager) UpdateTokens(db *ent.Client, ctx context.Context, access string, refresh string, oldRefresh string) {
+// RotateTokens replaces a session's tokens and remembers the old refresh token so it can never be used again.
+// The swap only happens while the session still holds the old refresh token, so when concurrent requests
+// rotate the same token just one of them wins. It reports whether this one did.
+func (obj UserManager) RotateTokens(db *ent.Client, ctx context.Context, session *ent.Token, access string, refresh string, sessionInfo SessionInfo) bool {
+	cfg := config.GetConfig()
+	rotated := db.Token.
+		Update().
+		Where(token.ID(session.ID), token.Refresh(session.Refresh)).
+		SetAccess(access).
+		SetRefresh(refresh).
+		SetUserAgent(sessionInfo.UserAgent).
+		SetIP(sessionInfo.IP).
+		SetLastUsedAt(time.Now()).
+		SaveX(ctx)
+	if rotated == 0 {
+		return false
+	}
+	db.UsedRefreshToken.Delete().Where(usedrefreshtoken.ExpiresAtLT(time.Now())).ExecX(ctx)
+	db.UsedRefreshToken.
+		Create().
+		SetUserID(session.UserID).
+		SetFamilyID(session.FamilyID).
+		SetTokenHash(config.HashToken(session.Refresh)).
+		SetExpiresAt(time.Now().Add(time.Duration(cfg.RefreshTokenExpireMinutes) * time.Minute)).
+		SaveX(ctx)
+	return true
+}
+
+func (obj UserManager) GetUsedRefreshToken(db *ent.Client, ctx context.Context, refresh string) *ent.UsedRefreshToken {
+	usedToken, _ := db.UsedRefreshToken.
+		Query().
+		Where(usedrefreshtoken.TokenHash(config.HashToken(refresh))).
+		Only(ctx)
+	return usedToken
+}
+
+// RevokeTokenFamily logs out every session descended from the same login
+func (obj UserManager) RevokeTokenFamily(db *ent.Client, ctx context.Context, familyID uuid.UUID) {
 	db.Token.
-        Update().
-        Where(token.Refresh(oldRefresh)).
-        SetAccess(access).
-        SetRefresh(refresh).
-        SaveX(ctx)
+		Delete().
+		Where(token.FamilyID(familyID)).
+		ExecX(ctx)
+}
+
+// ----------------------------------
+// SESSION MANAGEMENT
+// --------------------------------
+func (obj UserManager) GetSessions(db *ent.Client, ctx context.Context, userID uuid.UUID) []*ent.Token {
+	sessions := db.Token.
+		Query().
+		Where(token.UserID(userID)).
+		Order(ent.Desc(token.FieldLastUsedAt)).
+		AllX(ctx)
+	return sessions
+}
+
+func (obj UserManager) GetSession(db *ent.Client, ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) *ent.Token {
+	session, _ := db.Token.
+		Query().
+		Where(token.ID(sessionID), token.UserID(userID)).
+		Only(ctx)
+	return session
+}
+
+func (obj UserManager) DeleteSession(db *ent.Client, ctx context.Context, session *ent.Token) {
+	db.Token.DeleteOne(session).ExecX(ctx)
+}
+
+// TouchSession records session activity, at most once per minute to avoid a write on every request
+func (obj UserManager) TouchSession(db *ent.Client, ctx context.Context, session *ent.Token) {
+	if time.Since(session.LastUsedAt) < time.Minute {
+		return
+	}
+	db.Token.UpdateOne(session).SetLastUsedAt(time.Now()).Exec(ctx)
 }
 
 func (obj UserManager) DeleteToken(db *ent.Client, ctx context.Context, access string) {
@@ -142,6 +223,402 @@ func (obj UserManager) ClearTokens(db *ent.Client, ctx context.Context, userID u
 		ExecX(ctx)
 }
 
+func (obj UserManager) SetTotpSecret(db *ent.Client, ctx context.Context, userObj *ent.User, secret string) *ent.User {
+	return userObj.Update().SetTotpSecret(secret).SetTotpLastStep(0).SaveX(ctx)
+}
+
+func (obj UserManager) EnableTwoFactor(db *ent.Client, ctx context.Context, userObj *ent.User) []string {
+	codes, hashedCodes := GenerateRecoveryCodes()
+	userObj.Update().SetTwoFactorEnabled(true).SetRecoveryCodes(hashedCodes).SaveX(ctx)
+	return codes
+}
+
+func (obj UserManager) RegenerateRecoveryCodes(db *ent.Client, ctx context.Context, userObj *ent.User) []string {
+	codes, hashedCodes := GenerateRecoveryCodes()
+	userObj.Update().SetRecoveryCodes(hashedCodes).SaveX(ctx)
+	return codes
+}
+
+func (obj UserManager) DisableTwoFactor(db *ent.Client, ctx context.Context, userObj *ent.User) {
+	userObj.Update().SetTwoFactorEnabled(false).ClearTotpSecret().ClearRecoveryCodes().SaveX(ctx)
+}
+
+// UseTotpCode accepts a valid TOTP code whose time step is later than the last accepted one, and records it.
+// The conditional update makes sure a code is accepted only once, even by concurrent requests.
+func (obj UserManager) UseTotpCode(db *ent.Client, ctx context.Context, userObj *ent.User, code string) bool {
+	if userObj.TotpSecret == nil {
+		return false
+	}
+	step, ok := ValidateTotpCode(*userObj.TotpSecret, code)
+	if !ok || step <= userObj.TotpLastStep {
+		return false
+	}
+	updated := db.User.Update().
+		Where(user.ID(userObj.ID), user.TotpLastStepLT(step)).
+		SetTotpLastStep(step).
+		SaveX(ctx)
+	return updated == 1
+}
+
+// VerifyTwoFactorCode accepts either a current TOTP code or an unused recovery code.
+// A matching recovery code is consumed so it cannot be used again. The codes are only updated if nobody changed them
+// since the user was loaded, so concurrent requests can't both use the same code.
+func (obj UserManager) VerifyTwoFactorCode(db *ent.Client, ctx context.Context, userObj *ent.User, code string) bool {
+	if userObj.TotpSecret == nil {
+		return false
+	}
+	if obj.UseTotpCode(db, ctx, userObj, code) {
+		return true
+	}
+	code = strings.ToLower(strings.TrimSpace(code))
+	for i, hashedCode := range userObj.RecoveryCodes {
+		if config.CheckPasswordHash(code, hashedCode) {
+			remainingCodes := append(append([]string{}, userObj.RecoveryCodes[:i]...), userObj.RecoveryCodes[i+1:]...)
+			// Codes are only removed or replaced by new random ones, so they are unchanged while the used
+			// one is still there and none is missing
+			updated := db.User.Update().
+				Where(user.ID(userObj.ID), func(s *sql.Selector) {
+					s.Where(sql.And(
+						sqljson.ValueContains(user.FieldRecoveryCodes, hashedCode),
+						sqljson.LenEQ(user.FieldRecoveryCodes, len(userObj.RecoveryCodes)),
+					))
+				}).
+				SetRecoveryCodes(remainingCodes).
+				SaveX(ctx)
+			return updated == 1
+		}
+	}
+	return false
+}
+
+// ----------------------------------
+// ROLES & PERMISSIONS
+// --------------------------------
+
+// GetRoles returns the role matching the user's base role along with any extra roles assigned to them
+func (obj UserManager) GetRoles(db *ent.Client, ctx context.Context, userObj *ent.User) []*ent.Role {
+	roles := db.Role.
+		Query().
+		Where(role.Or(role.Name(string(userObj.Role)), role.HasUsersWith(user.ID(userObj.ID)))).
+		AllX(ctx)
+	return roles
+}
+
+// GetPermissions returns the union of the permissions of all the user's roles
+func (obj UserManager) GetPermissions(db *ent.Client, ctx context.Context, userObj *ent.User) []string {
+	permissions := []string{}
+	seen := map[string]bool{}
+	for _, r := range obj.GetRoles(db, ctx, userObj) {
+		for _, p := range r.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return permissions
+}
+
+func (obj UserManager) HasAnyRole(db *ent.Client, ctx context.Context, userObj *ent.User, roleNames ...string) bool {
+	for _, name := range roleNames {
+		if string(userObj.Role) == name {
+			return true
+		}
+	}
+	return db.Role.
+		Query().
+		Where(role.NameIn(roleNames...), role.HasUsersWith(user.ID(userObj.ID))).
+		ExistX(ctx)
+}
+
+// ----------------------------------
+// BRUTE-FORCE PROTECTION
+// --------------------------------
+
+// RecordFailedLogin counts a failed login and locks the account once MaxFailedLogins is reached.
+// The unlock token is returned when the account just got locked so it can be emailed to the user.
+// The count is incremented in the database so concurrent failures are never lost, and only the request
+// whose conditional update resets the count gets to lock the account.
+func (obj UserManager) RecordFailedLogin(db *ent.Client, ctx context.Context, userObj *ent.User, ip string) *string {
+	now := time.Now()
+	updatedUser := userObj.Update().AddFailedLoginAttempts(1).SetLastFailedLoginAt(now).SaveX(ctx)
+	attempts := updatedUser.FailedLoginAttempts
+	if attempts < MaxFailedLogins {
+		return nil
+	}
+	lockedUntil := now.Add(LockoutDuration)
+	unlockToken := config.GenerateSecureToken(UnlockTokenLength)
+	locked := db.User.Update().
+		Where(user.ID(userObj.ID), user.FailedLoginAttemptsGTE(MaxFailedLogins)).
+		SetFailedLoginAttempts(0).
+		ClearLastFailedLoginAt().
+		SetLockedUntil(lockedUntil).
+		SetUnlockToken(config.HashToken(unlockToken)).
+		SaveX(ctx)
+	if locked == 0 {
+		return nil
+	}
+	db.AccountLockout.Create().
+		SetUserID(userObj.ID).
+		SetIP(ip).
+		SetReason(accountlockout.ReasonLoginFailures).
+		SetFailedAttempts(attempts).
+		SetLockedUntil(lockedUntil).
+		SaveX(ctx)
+	return &unlockToken
+}
+
+func (obj UserManager) ResetFailedLogins(db *ent.Client, ctx context.Context, userObj *ent.User) {
+	if userObj.FailedLoginAttempts == 0 && userObj.LockedUntil == nil {
+		return
+	}
+	userObj.Update().SetFailedLoginAttempts(0).ClearLastFailedLoginAt().ClearLockedUntil().ClearUnlockToken().SaveX(ctx)
+}
+
+// RecordIPFailure counts a failed login or otp attempt from an ip, recording a lockout when the ip gets blocked
+func (obj UserManager) RecordIPFailure(db *ent.Client, ctx context.Context, ip string) {
+	attempts, blocked := ipTracker.Record(ip)
+	if blocked {
+		db.AccountLockout.Create().
+			SetIP(ip).
+			SetReason(accountlockout.ReasonIPFailures).
+			SetFailedAttempts(attempts).
+			SetLockedUntil(time.Now().Add(IPFailureWindow)).
+			SaveX(ctx)
+	}
+}
+
+func (obj UserManager) GetByUnlockToken(db *ent.Client, ctx context.Context, unlockToken string) *ent.User {
+	u, _ := db.User.
+		Query().
+		Where(user.UnlockToken(config.HashToken(unlockToken))).
+		Only(ctx)
+	return u
+}
+
+func (obj UserManager) Unlock(db *ent.Client, ctx context.Context, userObj *ent.User) {
+	userObj.Update().SetFailedLoginAttempts(0).ClearLastFailedLoginAt().ClearLockedUntil().ClearUnlockToken().SaveX(ctx)
+	db.AccountLockout.Update().
+		Where(accountlockout.UserID(userObj.ID), accountlockout.UnlockedAtIsNil(), accountlockout.LockedUntilGT(time.Now())).
+		SetUnlockedAt(time.Now()).
+		ExecX(ctx)
+}
+
+// SetMagicLinkToken creates a single use login token for the user. Only its hash is stored.
+func (obj UserManager) SetMagicLinkToken(db *ent.Client, ctx context.Context, userObj *ent.User) string {
+	magicToken := config.GenerateSecureToken(MagicLinkTokenLength)
+	userObj.Update().
+		SetMagicLinkToken(config.HashToken(magicToken)).
+		SetMagicLinkExpiry(time.Now().Add(MagicLinkExpiry)).
+		ExecX(ctx)
+	return magicToken
+}
+
+// ConsumeMagicLinkToken clears the login token and returns its user, with the token's expiry still set.
+// The token is only cleared while it still matches, so when the same link is used concurrently just one request gets the user.
+func (obj UserManager) ConsumeMagicLinkToken(db *ent.Client, ctx context.Context, magicToken string) *ent.User {
+	tokenHash := config.HashToken(magicToken)
+	u, _ := db.User.
+		Query().
+		Where(user.MagicLinkToken(tokenHash)).
+		Only(ctx)
+	if u == nil {
+		return nil
+	}
+	consumed := db.User.Update().
+		Where(user.ID(u.ID), user.MagicLinkToken(tokenHash)).
+		ClearMagicLinkToken().
+		ClearMagicLinkExpiry().
+		SaveX(ctx)
+	if consumed != 1 {
+		return nil
+	}
+	return u
+}
+
+// RecordFailedOtp counts a wrong otp. Once MaxOtpAttempts is reached the otp is invalidated and true is returned
+func (obj UserManager) RecordFailedOtp(db *ent.Client, ctx context.Context, userObj *ent.User) bool {
+	// Incremented in the database so concurrent guesses all count
+	updatedUser := userObj.Update().AddOtpAttempts(1).SaveX(ctx)
+	if updatedUser.OtpAttempts < MaxOtpAttempts {
+		return false
+	}
+	db.User.Update().
+		Where(user.ID(userObj.ID), user.OtpAttemptsGTE(MaxOtpAttempts)).
+		SetOtpAttempts(0).
+		ClearOtp().
+		ClearOtpExpiry().
+		ExecX(ctx)
+	return true
+}
+
+// RecordFailedEmailChangeOtp is RecordFailedOtp for the otp sent to a pending new email
+func (obj UserManager) RecordFailedEmailChangeOtp(db *ent.Client, ctx context.Context, userObj *ent.User) bool {
+	updatedUser := userObj.Update().AddPendingEmailOtpAttempts(1).SaveX(ctx)
+	if updatedUser.PendingEmailOtpAttempts < MaxOtpAttempts {
+		return false
+	}
+	db.User.Update().
+		Where(user.ID(userObj.ID), user.PendingEmailOtpAttemptsGTE(MaxOtpAttempts)).
+		SetPendingEmailOtpAttempts(0).
+		ClearPendingEmailOtp().
+		ClearPendingEmailOtpExpiry().
+		ExecX(ctx)
+	return true
+}
+
+// ----------------------------------
+// LINKED IDENTITIES
+// --------------------------------
+func (obj UserManager) GetIdentity(db *ent.Client, ctx context.Context, provider string, providerUserID string) *ent.UserIdentity {
+	identity, _ := db.UserIdentity.
+		Query().
+		Where(useridentity.Provider(provider), useridentity.ProviderUserID(providerUserID)).
+		WithUser().
+		Only(ctx)
+	return identity
+}
+
+func (obj UserManager) GetUserIdentity(db *ent.Client, ctx context.Context, userID uuid.UUID, identityID uuid.UUID) *ent.UserIdentity {
+	identity, _ := db.UserIdentity.
+		Query().
+		Where(useridentity.ID(identityID), useridentity.UserID(userID)).
+		Only(ctx)
+	return identity
+}
+
+func (obj UserManager) GetIdentities(db *ent.Client, ctx context.Context, userID uuid.UUID) []*ent.UserIdentity {
+	identities := db.UserIdentity.
+		Query().
+		Where(useridentity.UserID(userID)).
+		Order(ent.Asc(useridentity.FieldCreatedAt)).
+		AllX(ctx)
+	return identities
+}
+
+func (obj UserManager) HasProvider(db *ent.Client, ctx context.Context, userID uuid.UUID, provider string) bool {
+	return db.UserIdentity.
+		Query().
+		Where(useridentity.UserID(userID), useridentity.Provider(provider)).
+		ExistX(ctx)
+}
+
+func (obj UserManager) LinkIdentity(db *ent.Client, ctx context.Context, userID uuid.UUID, provider string, info *SocialUserInfo) *ent.UserIdentity {
+	return db.UserIdentity.
+		Create().
+		SetUserID(userID).
+		SetProvider(provider).
+		SetProviderUserID(info.ProviderUserID).
+		SetEmail(info.Email).
+		SaveX(ctx)
+}
+
+func (obj UserManager) UnlinkIdentity(db *ent.Client, ctx context.Context, identity *ent.UserIdentity) {
+	db.UserIdentity.DeleteOne(identity).ExecX(ctx)
+}
+
+// ----------------------------------
+// PERSONAL ACCESS TOKENS
+// --------------------------------
+// CreatePersonalAccessToken stores a new token and returns it with the raw token, which can't be recovered afterwards
+func (obj UserManager) CreatePersonalAccessToken(db *ent.Client, ctx context.Context, userID uuid.UUID, data PersonalAccessTokenCreateSchema) (*ent.PersonalAccessToken, string) {
+	rawToken := GeneratePersonalAccessToken()
+	expiresInDays := PersonalAccessTokenDefaultDays
+	if data.ExpiresInDays != nil {
+		expiresInDays = *data.ExpiresInDays
+	}
+	scopes := data.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	accessToken := db.PersonalAccessToken.Create().
+		SetUserID(userID).
+		SetName(data.Name).
+		SetTokenHash(config.HashToken(rawToken)).
+		SetTokenPrefix(rawToken[:personalAccessTokenShownLength]).
+		SetScopes(scopes).
+		SetExpiresAt(time.Now().AddDate(0, 0, expiresInDays)).
+		SaveX(ctx)
+	return accessToken, rawToken
+}
+
+func (obj UserManager) GetPersonalAccessTokenByToken(db *ent.Client, ctx context.Context, rawToken string) *ent.PersonalAccessToken {
+	accessToken, _ := db.PersonalAccessToken.Query().
+		Where(personalaccesstoken.TokenHash(config.HashToken(rawToken))).
+		WithUser().
+		Only(ctx)
+	return accessToken
+}
+
+func (obj UserManager) GetPersonalAccessTokens(db *ent.Client, ctx context.Context, userID uuid.UUID) []*ent.PersonalAccessToken {
+	return db.PersonalAccessToken.Query().
+		Where(personalaccesstoken.UserID(userID)).
+		Order(ent.Desc(personalaccesstoken.FieldCreatedAt)).
+		AllX(ctx)
+}
+
+func (obj UserManager) GetPersonalAccessToken(db *ent.Client, ctx context.Context, userID uuid.UUID, id uuid.UUID) *ent.PersonalAccessToken {
+	accessToken, _ := db.PersonalAccessToken.Query().
+		Where(personalaccesstoken.UserID(userID), personalaccesstoken.ID(id)).
+		Only(ctx)
+	return accessToken
+}
+
+// TouchPersonalAccessToken records token usage, at most once per minute like TouchSession
+func (obj UserManager) TouchPersonalAccessToken(db *ent.Client, ctx context.Context, accessToken *ent.PersonalAccessToken) {
+	if accessToken.LastUsedAt != nil && time.Since(*accessToken.LastUsedAt) < time.Minute {
+		return
+	}
+	db.PersonalAccessToken.UpdateOne(accessToken).SetLastUsedAt(time.Now()).Exec(ctx)
+}
+
+func (obj UserManager) RevokePersonalAccessToken(db *ent.Client, ctx context.Context, accessToken *ent.PersonalAccessToken) {
+	db.PersonalAccessToken.DeleteOne(accessToken).ExecX(ctx)
+}
+
+// ----------------------------------
+// AUTH EVENTS
+// --------------------------------
+// CreateAuthEvent never fails the request, a lost audit entry is only logged
+func (obj UserManager) CreateAuthEvent(db *ent.Client, ctx context.Context, userID *uuid.UUID, eventType SecurityEventType, ip string, userAgent string, details map[string]string) {
+	err := db.AuthEvent.Create().
+		SetNillableUserID(userID).
+		SetEvent(string(eventType)).
+		SetIP(ip).
+		SetUserAgent(userAgent).
+		SetDetails(details).
+		Exec(ctx)
+	if err != nil {
+		log.Printf("Unable to record auth event %s: %v\n", eventType, err)
+	}
+}
+
+// IsNewLoginLocation reports whether a login comes from an ip or device never used by the user before.
+// The very first login is not considered new since there is nothing to compare it with.
+func (obj UserManager) IsNewLoginLocation(db *ent.Client, ctx context.Context, userID uuid.UUID, ip string, userAgent string) bool {
+	loginEvents := make([]string, len(LoginEventTypes))
+	for i, eventType := range LoginEventTypes {
+		loginEvents[i] = string(eventType)
+	}
+	query := db.AuthEvent.Query().Where(authevent.UserID(userID), authevent.EventIn(loginEvents...))
+	if !query.Clone().ExistX(ctx) {
+		return false
+	}
+	knownIP := query.Clone().Where(authevent.IP(ip)).ExistX(ctx)
+	knownDevice := query.Clone().Where(authevent.UserAgent(userAgent)).ExistX(ctx)
+	return !knownIP || !knownDevice
+}
+
+func (obj UserManager) GetAuthEventsPaginated(db *ent.Client, fibCtx *fiber.Ctx, userID uuid.UUID, event string) *config.PaginationResponse[*ent.AuthEvent] {
+	query := db.AuthEvent.Query().
+		Where(authevent.UserID(userID)).
+		Order(ent.Desc(authevent.FieldCreatedAt))
+	if event != "" {
+		query = query.Where(authevent.Event(event))
+	}
+	return config.PaginateModel(fibCtx, query)
+}
+
 func (obj UserManager) DropData(db *ent.Client, ctx context.Context) {
 	db.User.Delete().ExecX(ctx)
 }
\ No newline at end of file
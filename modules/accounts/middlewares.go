@@ -9,6 +9,7 @@ import (
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
 )
 
 func GetUser(db *ent.Client, ctx context.Context, token string) (*ent.User, *string) {
@@ -23,23 +24,116 @@ func GetUser(db *ent.Client, ctx context.Context, token string) (*ent.User, *str
 	return user, nil
 }
 
-func AuthMiddleware(db *ent.Client, allowedRoleParam ...user.Role) fiber.Handler {
+// getTokenUser authenticates a personal access token (Bearer pat_...) and returns its user and scopes
+func getTokenUser(db *ent.Client, ctx context.Context, token string) (*ent.User, []string, *string) {
+	accessToken, err := DecodePersonalAccessToken(db, ctx, token[7:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return accessToken.Edges.User, accessToken.Scopes, nil
+}
+
+// AuthMiddleware authenticates the request with a login session. When roles are given, the user must hold at least one of them.
+// Prefer RequirePermission for anything finer grained.
+// Personal access tokens are rejected, since the route declares no scope for them. Routes that take them use ScopedAuthMiddleware.
+func AuthMiddleware(db *ent.Client, allowedRoles ...user.Role) fiber.Handler {
+	return authMiddleware(db, false, allowedRoles)
+}
+
+// ScopedAuthMiddleware is AuthMiddleware that also accepts personal access tokens.
+// Every route behind it must declare the scope it needs with RequirePermission, which checks it against the token's scopes.
+func ScopedAuthMiddleware(db *ent.Client, allowedRoles ...user.Role) fiber.Handler {
+	return authMiddleware(db, true, allowedRoles)
+}
+
+func authMiddleware(db *ent.Client, allowAccessTokens bool, allowedRoles []user.Role) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		token := c.Get("Authorization")
 		if len(token) < 1 {
 			return config.APIError(c, 401, config.RequestErr(config.ERR_UNAUTHORIZED_USER, "Unauthorized User!"))
 		}
-		userObj, err := GetUser(db, c.Context(), token)
+		var userObj *ent.User
+		var err *string
+		if strings.HasPrefix(token, "Bearer "+PAT_PREFIX) {
+			if !allowAccessTokens {
+				return sessionRequiredErr(c)
+			}
+			var scopes []string
+			userObj, scopes, err = getTokenUser(db, c.Context(), token)
+			c.Locals("token_scopes", scopes)
+		} else {
+			userObj, err = GetUser(db, c.Context(), token)
+		}
 		if err != nil {
 			return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_TOKEN, *err))
 		}
-		if len(allowedRoleParam) > 0 {
-			allowedRole := &allowedRoleParam[0]
-			if userObj.Role != *allowedRole {
-				return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_USER, fmt.Sprintf("For %ss only", allowedRole)))
+		if !userObj.IsActive {
+			return AccountDeactivatedErr(c)
+		}
+		if len(allowedRoles) > 0 {
+			roleNames := make([]string, len(allowedRoles))
+			for i, r := range allowedRoles {
+				roleNames[i] = string(r)
+			}
+			if !userManager.HasAnyRole(db, c.Context(), userObj, roleNames...) {
+				return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_USER, fmt.Sprintf("For %ss only", strings.Join(roleNames, "s/"))))
 			}
 		}
 		c.Locals("user", userObj)
 		return c.Next()
 	}
 }
+
+// QueryTokenAuthMiddleware is AuthMiddleware for browser APIs that can't set headers, like websockets and EventSource.
+// The access token may also be passed in the token query param. It is kept in the token local for connections that check it again later.
+func QueryTokenAuthMiddleware(db *ent.Client) fiber.Handler {
+	auth := AuthMiddleware(db)
+	return func(c *fiber.Ctx) error {
+		token := c.Get("Authorization")
+		if token == "" && c.Query("token") != "" {
+			token = "Bearer " + c.Query("token")
+			c.Request().Header.Set("Authorization", token)
+		}
+		c.Locals("token", token)
+		return auth(c)
+	}
+}
+
+// OptionalAuthMiddleware authenticates the request if it carries a token and lets anonymous requests through.
+// Handlers behind it get a nil request user for anonymous requests.
+func OptionalAuthMiddleware(db *ent.Client) fiber.Handler {
+	auth := AuthMiddleware(db)
+	return func(c *fiber.Ctx) error {
+		if c.Get("Authorization") == "" {
+			return c.Next()
+		}
+		return auth(c)
+	}
+}
+
+// RequirePermission lets the request through only if the user holds every given permission through any of their roles.
+// Requests made with a personal access token also need the permission among the token's scopes.
+// It must come after AuthMiddleware.
+func RequirePermission(db *ent.Client, permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj := base.RequestUser(c)
+		if userObj == nil {
+			return config.APIError(c, 401, config.RequestErr(config.ERR_UNAUTHORIZED_USER, "Unauthorized User!"))
+		}
+		granted, ok := c.Locals("permissions").([]string)
+		if !ok {
+			granted = userManager.GetPermissions(db, c.Context(), userObj)
+			c.Locals("permissions", granted)
+		}
+		scopes, isAccessToken := RequestTokenScopes(c)
+		for _, permission := range permissions {
+			if !HasPermission(granted, permission) {
+				return config.APIError(c, 403, config.ForbiddenErr(fmt.Sprintf("You do not have the %s permission", permission)))
+			}
+			if isAccessToken && !HasPermission(scopes, permission) {
+				return config.APIError(c, 403, config.ForbiddenErr(fmt.Sprintf("This access token is missing the %s scope", permission)))
+			}
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,85 @@
+package accounts
+
+import (
+	"strings"
+
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+)
+
+// Permissions are named "<resource>.<action>".
+// A role holding "<resource>.*" is granted every action on that resource, and "*" grants everything.
+const (
+	PERM_ALL = "*"
+
+	PERM_COURSE_READ    = "course.read" // instructor views of courses, lessons and quizzes
+	PERM_COURSE_CREATE  = "course.create"
+	PERM_COURSE_UPDATE  = "course.update"
+	PERM_COURSE_DELETE  = "course.delete"
+	PERM_COURSE_PUBLISH = "course.publish"
+	PERM_REVIEW_REPLY   = "course.review_reply" // Replying to reviews on one's own courses
+	PERM_LESSON_CREATE  = "lesson.create"
+	PERM_LESSON_UPDATE  = "lesson.update"
+	PERM_LESSON_DELETE  = "lesson.delete"
+	PERM_QUIZ_CREATE    = "quiz.create"
+	PERM_QUIZ_UPDATE    = "quiz.update"
+	PERM_QUIZ_DELETE    = "quiz.delete"
+
+	PERM_USER_VIEW                     = "user.view"
+	PERM_USER_MANAGE                   = "user.manage" // deactivate, force logout, password reset
+	PERM_COURSE_SUBMISSION_REVIEW      = "course_submission.review"
+	PERM_CATEGORY_MANAGE               = "category.manage"
+	PERM_TAG_MANAGE                    = "tag.manage"
+	PERM_LOCKOUT_VIEW                  = "lockout.view"
+	PERM_AUTH_EVENT_VIEW               = "auth_event.view"
+	PERM_ROLE_MANAGE                   = "role.manage"
+	PERM_INSTRUCTOR_APPLICATION_REVIEW = "instructor_application.review"
+	PERM_SITE_DETAIL_MANAGE            = "site_detail.manage"
+	PERM_ANALYTICS_VIEW                = "analytics.view"
+	PERM_REVIEW_MODERATE               = "review.moderate"
+	PERM_CHAT_MODERATE                 = "chat.moderate" // Instructors moderate their own course rooms without it
+)
+
+// Permissions lists every permission checked somewhere in the api
+var Permissions = []string{
+	PERM_COURSE_READ, PERM_COURSE_CREATE, PERM_COURSE_UPDATE, PERM_COURSE_DELETE, PERM_COURSE_PUBLISH, PERM_REVIEW_REPLY,
+	PERM_LESSON_CREATE, PERM_LESSON_UPDATE, PERM_LESSON_DELETE,
+	PERM_QUIZ_CREATE, PERM_QUIZ_UPDATE, PERM_QUIZ_DELETE,
+	PERM_USER_VIEW, PERM_USER_MANAGE, PERM_COURSE_SUBMISSION_REVIEW, PERM_CATEGORY_MANAGE, PERM_TAG_MANAGE, PERM_LOCKOUT_VIEW, PERM_AUTH_EVENT_VIEW, PERM_ROLE_MANAGE, PERM_INSTRUCTOR_APPLICATION_REVIEW, PERM_SITE_DETAIL_MANAGE, PERM_ANALYTICS_VIEW, PERM_REVIEW_MODERATE, PERM_CHAT_MODERATE,
+}
+
+type RoleDefinition struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// SystemRoles mirror the values of the user role field and are created on startup
+var SystemRoles = []RoleDefinition{
+	{Name: string(user.RoleStudent), Description: "Default role of every learner", Permissions: []string{}},
+	{Name: string(user.RoleInstructor), Description: "Creates and manages their own courses", Permissions: []string{"course.*", "lesson.*", "quiz.*"}},
+	{Name: string(user.RoleAdmin), Description: "Full access", Permissions: []string{PERM_ALL}},
+}
+
+// HasPermission reports whether the granted permissions (which may contain wildcards) cover the permission
+func HasPermission(granted []string, permission string) bool {
+	resource := strings.Split(permission, ".")[0]
+	for _, p := range granted {
+		if p == permission || p == PERM_ALL || p == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidPermission accepts known permissions and wildcards over known resources
+func IsValidPermission(permission string) bool {
+	if permission == PERM_ALL {
+		return true
+	}
+	for _, p := range Permissions {
+		if p == permission || strings.Split(p, ".")[0]+".*" == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,348 @@
+package accounts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+)
+
+// SocialUserInfo is what every identity provider must tell us about the user
+type SocialUserInfo struct {
+	ProviderUserID string
+	Email          string
+	EmailVerified  bool
+	Name           string
+	Avatar         *string
+}
+
+// SocialProvider is implemented by each external identity provider that users can login with
+type SocialProvider interface {
+	Name() string
+	// FetchUser exchanges the credential posted by the client for the user's details.
+	// Google expects an id token in data.Token, the others expect an authorization code in data.Code.
+	FetchUser(ctx context.Context, data SocialLoginSchema) (*SocialUserInfo, *config.ErrorResponse)
+}
+
+var providerHttpClient = &http.Client{Timeout: 10 * time.Second}
+
+func providerErr(message string) *config.ErrorResponse {
+	errData := config.RequestErr(config.ERR_INVALID_TOKEN, message)
+	return &errData
+}
+
+var (
+	socialProviders     map[string]SocialProvider
+	socialProvidersOnce sync.Once
+)
+
+// GetSocialProviders returns the providers that have been configured, keyed by name.
+// They are built once so that OIDC discovery documents stay cached between requests.
+func GetSocialProviders(cfg config.Config) map[string]SocialProvider {
+	socialProvidersOnce.Do(func() {
+		socialProviders = buildSocialProviders(cfg)
+	})
+	return socialProviders
+}
+
+func buildSocialProviders(cfg config.Config) map[string]SocialProvider {
+	providers := map[string]SocialProvider{}
+	if cfg.GoogleClientID != "" {
+		providers["google"] = GoogleProvider{}
+	}
+	if cfg.GithubClientID != "" {
+		providers["github"] = GithubProvider{ClientID: cfg.GithubClientID, ClientSecret: cfg.GithubClientSecret}
+	}
+	if cfg.MicrosoftClientID != "" {
+		tenant := cfg.MicrosoftTenant
+		if tenant == "" {
+			tenant = "common"
+		}
+		providers["microsoft"] = &OIDCProvider{
+			ProviderName: "microsoft",
+			Issuer:       fmt.Sprintf("https://login.microsoftonline.com/%s/v2.0", tenant),
+			ClientID:     cfg.MicrosoftClientID,
+			ClientSecret: cfg.MicrosoftClientSecret,
+		}
+	}
+	if cfg.OIDCIssuer != "" && cfg.OIDCProviderName != "" {
+		providers[cfg.OIDCProviderName] = &OIDCProvider{
+			ProviderName: cfg.OIDCProviderName,
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+		}
+	}
+	return providers
+}
+
+func GetSocialProvider(cfg config.Config, name string) SocialProvider {
+	provider, ok := GetSocialProviders(cfg)[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	return provider
+}
+
+// ----------------------------------
+// GOOGLE
+// --------------------------------
+// GoogleProvider validates google id tokens against GOOGLE_CLIENT_ID (see ConvertGoogleToken)
+type GoogleProvider struct{}
+
+func (p GoogleProvider) Name() string {
+	return "google"
+}
+
+func (p GoogleProvider) FetchUser(ctx context.Context, data SocialLoginSchema) (*SocialUserInfo, *config.ErrorResponse) {
+	if data.Token == "" {
+		errData := config.ValidationErr("token", "This field is required.")
+		return nil, &errData
+	}
+	payload, errData := ConvertGoogleToken(ctx, data.Token)
+	if errData != nil {
+		return nil, errData
+	}
+	info := SocialUserInfo{
+		ProviderUserID: payload.SUB,
+		Email:          payload.Email,
+		EmailVerified:  payload.EmailVerified,
+		Name:           payload.Name,
+	}
+	if payload.Picture != "" {
+		info.Avatar = &payload.Picture
+	}
+	return &info, nil
+}
+
+// ----------------------------------
+// GITHUB
+// --------------------------------
+type GithubProvider struct {
+	ClientID     string
+	ClientSecret string
+}
+
+func (p GithubProvider) Name() string {
+	return "github"
+}
+
+func (p GithubProvider) FetchUser(ctx context.Context, data SocialLoginSchema) (*SocialUserInfo, *config.ErrorResponse) {
+	if data.Code == "" {
+		errData := config.ValidationErr("code", "This field is required.")
+		return nil, &errData
+	}
+	tokenData := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	form := url.Values{
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code":          {data.Code},
+		"redirect_uri":  {data.RedirectUri},
+	}
+	if err := postForm(ctx, "https://github.com/login/oauth/access_token", form, &tokenData); err != nil || tokenData.AccessToken == "" {
+		return nil, providerErr("Invalid authorization code")
+	}
+
+	userData := struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}{}
+	if err := getJSON(ctx, "https://api.github.com/user", tokenData.AccessToken, &userData); err != nil {
+		return nil, providerErr("Unable to fetch github user")
+	}
+
+	// The profile email may be private or unverified, so use the primary verified one
+	emails := []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}{}
+	if err := getJSON(ctx, "https://api.github.com/user/emails", tokenData.AccessToken, &emails); err != nil {
+		return nil, providerErr("Unable to fetch github user emails")
+	}
+	info := SocialUserInfo{ProviderUserID: fmt.Sprint(userData.ID), Name: userData.Name}
+	for _, email := range emails {
+		if email.Primary {
+			info.Email = email.Email
+			info.EmailVerified = email.Verified
+		}
+	}
+	if info.Name == "" {
+		info.Name = userData.Login
+	}
+	if userData.AvatarURL != "" {
+		info.Avatar = &userData.AvatarURL
+	}
+	return &info, nil
+}
+
+// ----------------------------------
+// GENERIC OPENID CONNECT (also used for microsoft)
+// --------------------------------
+type OIDCProvider struct {
+	ProviderName string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	discovery     *oidcDiscovery
+	discoveryLock sync.Mutex
+}
+
+type oidcDiscovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.ProviderName
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.discoveryLock.Lock()
+	defer p.discoveryLock.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	discovery := oidcDiscovery{}
+	discoveryUrl := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, discoveryUrl, "", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("incomplete openid configuration at %s", discoveryUrl)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+func (p *OIDCProvider) FetchUser(ctx context.Context, data SocialLoginSchema) (*SocialUserInfo, *config.ErrorResponse) {
+	if data.Code == "" {
+		errData := config.ValidationErr("code", "This field is required.")
+		return nil, &errData
+	}
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		log.Printf("OIDC discovery failed for %s: %v", p.ProviderName, err)
+		errData := config.RequestErr(config.ERR_NETWORK_FAILURE, "Unable to reach identity provider")
+		return nil, &errData
+	}
+
+	tokenData := struct {
+		AccessToken string `json:"access_token"`
+		IdToken     string `json:"id_token"`
+	}{}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code":          {data.Code},
+		"redirect_uri":  {data.RedirectUri},
+	}
+	if err := postForm(ctx, discovery.TokenEndpoint, form, &tokenData); err != nil || tokenData.AccessToken == "" {
+		return nil, providerErr("Invalid authorization code")
+	}
+
+	// The userinfo response comes straight from the provider over TLS, so it is trusted as is
+	userData := struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}{}
+	if err := getJSON(ctx, discovery.UserinfoEndpoint, tokenData.AccessToken, &userData); err != nil || userData.Sub == "" {
+		return nil, providerErr("Unable to fetch user info")
+	}
+	emailVerified := userData.EmailVerified
+	if emailVerified == nil {
+		// Some providers (like microsoft) leave it out of userinfo, so fall back to the id token
+		emailVerified = idTokenEmailVerified(tokenData.IdToken, userData.Sub)
+	}
+	info := SocialUserInfo{
+		ProviderUserID: userData.Sub,
+		Email:          userData.Email,
+		EmailVerified:  emailVerified != nil && *emailVerified,
+		Name:           userData.Name,
+	}
+	if userData.Picture != "" {
+		info.Avatar = &userData.Picture
+	}
+	return &info, nil
+}
+
+// idTokenEmailVerified reads the email verification status from the claims of an id token issued to the given subject.
+// The token came straight from the token endpoint over TLS, so like the userinfo response its signature isn't checked.
+// Microsoft sends xms_edov instead of email_verified, once it is added to the app registration's optional claims
+func idTokenEmailVerified(idToken string, sub string) *bool {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	claims := struct {
+		Sub                      string `json:"sub"`
+		EmailVerified            *bool  `json:"email_verified"`
+		EmailDomainOwnerVerified *bool  `json:"xms_edov"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Sub != sub {
+		return nil
+	}
+	if claims.EmailVerified != nil {
+		return claims.EmailVerified
+	}
+	return claims.EmailDomainOwnerVerified
+}
+
+// ----------------------------------
+// HTTP HELPERS
+// --------------------------------
+func postForm(ctx context.Context, endpoint string, form url.Values, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	return doJSON(req, dst)
+}
+
+func getJSON(ctx context.Context, endpoint string, bearer string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return doJSON(req, dst)
+}
+
+func doJSON(req *http.Request, dst interface{}) error {
+	resp, err := providerHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned status %d", req.Method, req.URL, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
@@ -1,6 +1,10 @@
 package accounts
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
@@ -37,6 +41,7 @@ func Register(db *ent.Client) fiber.Handler {
 
 		// Create User
 		newUser := userManager.Create(db, ctx, data, user.RoleStudent, false)
+		EmitSecurityEvent(db, c, &newUser.ID, SE_REGISTER, nil)
 
 		// Send Email
 		go config.SendEmail(newUser, config.ET_ACTIVATE, newUser.Otp)
@@ -51,12 +56,14 @@ func Register(db *ent.Client) fiber.Handler {
 
 // @Summary Verify a user's email
 // @Description `This endpoint verifies a user's email.`
+// @Description `The otp is invalidated after 5 wrong attempts`
 // @Tags Auth
 // @Param email_data body VerifyEmailRequestSchema true "Email object"
 // @Success 200 {object} base.ResponseSchema
 // @Failure 422 {object} base.ValidationErrorExample
 // @Failure 404 {object} base.NotFoundErrorExample
 // @Failure 400 {object} base.InvalidErrorExample
+// @Failure 429 {object} base.TooManyRequestsErrorExample
 // @Router /auth/verify-email [post]
 func VerifyEmail(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -66,6 +73,9 @@ func VerifyEmail(db *ent.Client) fiber.Handler {
 		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
 			return config.APIError(c, *errCode, *errData)
 		}
+		if wait := ipTracker.RetryAfter(c.IP()); wait > 0 {
+			return TooManyAttemptsErr(c, wait, "Too many failed attempts. Try again later")
+		}
 		user := userManager.GetByEmail(db, ctx, data.Email)
 		if user == nil {
 			return config.APIError(c, 404, config.RequestErr(config.ERR_INCORRECT_EMAIL, "Incorrect Email"))
@@ -74,16 +84,12 @@ func VerifyEmail(db *ent.Client) fiber.Handler {
 		if user.IsVerified {
 			return c.Status(200).JSON(base.ResponseMessage("Email already verified"))
 		}
-		if user.Otp == nil || *user.Otp != data.Otp {
-			return config.APIError(c, 404, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect Otp"))
-		}
-
-		if userManager.IsOtpExpired(user) {
-			return config.APIError(c, 400, config.RequestErr(config.ERR_EXPIRED_OTP, "Expired Otp"))
+		if errData := CheckOtp(db, c, user, data.Otp); errData != nil {
+			return errData
 		}
 
 		// Update User
-		user.Update().SetIsVerified(true).Save(ctx)
+		user.Update().SetIsVerified(true).SetOtpAttempts(0).ClearOtp().ClearOtpExpiry().Save(ctx)
 
 		// Send Welcome Email
 		go config.SendEmail(user, config.ET_WELCOME, nil)
@@ -120,7 +126,7 @@ func ResendVerificationEmail(db *ent.Client) fiber.Handler {
 
 		// Send Email
 		otp, otpExp := userManager.GetOtp()
-		user.Update().SetOtp(otp).SetOtpExpiry(otpExp).Save(ctx)
+		user.Update().SetOtp(otp).SetOtpExpiry(otpExp).SetOtpAttempts(0).Save(ctx)
 		go config.SendEmail(user, config.ET_ACTIVATE, &otp)
 		return c.Status(200).JSON(base.ResponseMessage("Verification email sent"))
 	}
@@ -150,21 +156,21 @@ func SendPasswordResetOtp(db *ent.Client) fiber.Handler {
 		}
 
 		// Send Email
-		otp, otpExp := userManager.GetOtp()
-		user.Update().SetOtp(otp).SetOtpExpiry(otpExp).Save(ctx)
-		go config.SendEmail(user, config.ET_RESET, &otp)
+		StartPasswordReset(db, c, user, nil)
 		return c.Status(200).JSON(base.ResponseMessage("Password otp sent"))
 	}
 }
 
 // @Summary Set New Password
 // @Description `This endpoint verifies the password reset otp.`
+// @Description `The otp is invalidated after 5 wrong attempts. A successful reset also unlocks a locked account`
 // @Tags Auth
 // @Param email body SetNewPasswordSchema true "Password reset object"
 // @Success 200 {object} base.ResponseSchema
 // @Failure 422 {object} base.ValidationErrorExample
 // @Failure 404 {object} base.NotFoundErrorExample
 // @Failure 400 {object} base.InvalidErrorExample
+// @Failure 429 {object} base.TooManyRequestsErrorExample
 // @Router /auth/set-new-password [post]
 func SetNewPassword(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -175,35 +181,71 @@ func SetNewPassword(db *ent.Client) fiber.Handler {
 		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
 			return config.APIError(c, *errCode, *errData)
 		}
+		if wait := ipTracker.RetryAfter(c.IP()); wait > 0 {
+			return TooManyAttemptsErr(c, wait, "Too many failed attempts. Try again later")
+		}
 		user := userManager.GetByEmail(db, ctx, data.Email)
 		if user == nil {
 			return config.APIError(c, 404, config.RequestErr(config.ERR_INCORRECT_EMAIL, "Incorrect Email"))
 		}
 
-		if user.Otp == nil || *user.Otp != data.Otp {
-			return config.APIError(c, 404, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect Otp"))
+		if errData := CheckOtp(db, c, user, data.Otp); errData != nil {
+			return errData
 		}
 
-		if userManager.IsOtpExpired(user) {
-			return config.APIError(c, 400, config.RequestErr(config.ERR_EXPIRED_OTP, "Expired Otp"))
+		// Set Password. Proving ownership of the email also lifts any lockout
+		user.Update().SetPassword(config.HashPassword(data.Password)).SetOtpAttempts(0).ClearOtp().ClearOtpExpiry().Save(ctx)
+		if user.LockedUntil != nil {
+			userManager.Unlock(db, ctx, user)
 		}
 
-		// Set Password
-		user.Update().SetPassword(config.HashPassword(data.Password)).Save(ctx)
-
 		// Send Email
 		go config.SendEmail(user, config.ET_RESET_SUCC, nil)
+		EmitSecurityEvent(db, c, &user.ID, SE_PASSWORD_RESET, nil)
 		return c.Status(200).JSON(base.ResponseMessage("Password reset successful"))
 	}
 }
 
+// @Summary Unlock account
+// @Description `This endpoint unlocks an account that was locked after too many failed logins, using the token sent to the user's email`
+// @Tags Auth
+// @Param data body TokenSchema true "Unlock token"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 400 {object} base.InvalidErrorExample
+// @Router /auth/unlock-account [post]
+func UnlockAccount(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := TokenSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		user := userManager.GetByUnlockToken(db, ctx, data.Token)
+		if user == nil {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_INVALID_TOKEN, "Invalid or used unlock token"))
+		}
+		userManager.Unlock(db, ctx, user)
+		EmitSecurityEvent(db, c, &user.ID, SE_ACCOUNT_UNLOCKED, nil)
+		return c.Status(200).JSON(base.ResponseMessage("Account unlocked successfully"))
+	}
+}
+
 // @Summary Login a user
 // @Description `This endpoint generates new access and refresh tokens for authentication`
+// @Description `If the user has two-factor authentication enabled, a short-lived challenge token is returned instead. Exchange it at /auth/login/2fa`
+// @Description `Repeated failures slow further attempts down (429 with a Retry-After header) and eventually lock the account (403). An unlock token is then emailed to the user`
 // @Tags Auth
 // @Param user body LoginSchema true "User login"
 // @Success 201 {object} LoginResponseSchema
+// @Success 200 {object} TwoFactorChallengeResponseSchema
 // @Failure 422 {object} base.ValidationErrorExample
 // @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 429 {object} base.TooManyRequestsErrorExample
 // @Router /auth/login [post]
 func Login(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -215,22 +257,169 @@ func Login(db *ent.Client) fiber.Handler {
 			return config.APIError(c, *errCode, *errData)
 		}
 
+		ip := c.IP()
+		if wait := ipTracker.RetryAfter(ip); wait > 0 {
+			return TooManyAttemptsErr(c, wait, "Too many failed login attempts from your network. Try again later")
+		}
 		user := userManager.GetByEmailOrUsername(db, ctx, data.EmailOrUsername)
 		if user == nil {
+			userManager.RecordIPFailure(db, ctx, ip)
+			EmitSecurityEvent(db, c, nil, SE_LOGIN_FAILED, map[string]string{"identifier": data.EmailOrUsername, "reason": "unknown_user"})
+			return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_CREDENTIALS, "Invalid Credentials"))
+		}
+		if errData := checkLoginAllowed(c, user); errData != nil {
+			return errData
+		}
+		if !config.CheckPasswordHash(data.Password, user.Password) {
+			EmitSecurityEvent(db, c, &user.ID, SE_LOGIN_FAILED, map[string]string{"reason": "invalid_password"})
+			if recordFailedLogin(db, c, user) {
+				return AccountLockedErr(c, LockoutDuration)
+			}
 			return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_CREDENTIALS, "Invalid Credentials"))
 		}
 		if !user.IsVerified {
 			return config.APIError(c, 401, config.RequestErr(config.ERR_UNVERIFIED_USER, "Verify your email first"))
 		}
 
+		// Require the second factor before issuing tokens.
+		// Failed attempts are only cleared once it passes, otherwise a known password would allow unlimited code guesses
+		if user.TwoFactorEnabled {
+			return c.Status(200).JSON(TwoFactorChallengeResponse(user))
+		}
+		userManager.ResetFailedLogins(db, ctx, user)
+
 		// Create Auth Tokens
-		access := GenerateAccessToken(user.ID, user.Username)
-		refresh := GenerateRefreshToken()
-		userManager.AddTokens(db, ctx, user, access, refresh)
+		response := LoginResponseSchema{
+			ResponseSchema: base.ResponseMessage("Login successful"),
+			Data:           IssueLoginTokens(db, c, user, SE_LOGIN, nil),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Complete a two-factor login
+// @Description `This endpoint exchanges the challenge token returned by login and a 2FA code for access and refresh tokens`
+// @Description `The code can either be the current code from an authenticator app or one of the unused recovery codes`
+// @Tags Auth
+// @Param data body TwoFactorLoginSchema true "Two-factor login"
+// @Success 201 {object} LoginResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 429 {object} base.TooManyRequestsErrorExample
+// @Router /auth/login/2fa [post]
+func TwoFactorLogin(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := TwoFactorLoginSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		user := DecodeTwoFactorChallengeToken(db, ctx, data.ChallengeToken)
+		if user == nil {
+			return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_TOKEN, "Challenge token is invalid or expired"))
+		}
+		if errData := checkLoginAllowed(c, user); errData != nil {
+			return errData
+		}
+		if !userManager.VerifyTwoFactorCode(db, ctx, user, data.Code) {
+			EmitSecurityEvent(db, c, &user.ID, SE_LOGIN_FAILED, map[string]string{"reason": "invalid_2fa_code"})
+			if recordFailedLogin(db, c, user) {
+				return AccountLockedErr(c, LockoutDuration)
+			}
+			return config.APIError(c, 401, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect 2FA code"))
+		}
+		userManager.ResetFailedLogins(db, ctx, user)
 
 		response := LoginResponseSchema{
 			ResponseSchema: base.ResponseMessage("Login successful"),
-			Data:           TokensResponseSchema{Access: access, Refresh: refresh},
+			Data:           IssueLoginTokens(db, c, user, SE_LOGIN, map[string]string{"two_factor": "true"}),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Send a magic login link
+// @Description `This endpoint emails a single use login link (and code) to the user, for logging in without a password`
+// @Description `It expires after 15 minutes. The response is the same whether or not the email is registered`
+// @Tags Auth
+// @Param email body EmailRequestSchema true "Email object"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /auth/magic-link [post]
+func SendMagicLink(db *ent.Client, cfg config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := EmailRequestSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		// Don't reveal whether the email is registered
+		if user := userManager.GetByEmail(db, ctx, data.Email); user != nil {
+			magicToken := userManager.SetMagicLinkToken(db, ctx, user)
+			go config.SendLinkEmail(user, config.ET_MAGIC_LINK, magicToken, MagicLinkUrl(cfg, magicToken))
+		}
+		return c.Status(200).JSON(base.ResponseMessage("If the email is registered, a login link has been sent to it"))
+	}
+}
+
+// @Summary Login with a magic link
+// @Description `This endpoint exchanges the token from a magic login link for access and refresh tokens. Each link works once`
+// @Description `Following the link proves ownership of the email, so unverified accounts get verified`
+// @Description `If the user has two-factor authentication enabled, a challenge token is returned instead. Exchange it at /auth/login/2fa`
+// @Tags Auth
+// @Param data body TokenSchema true "Magic link token"
+// @Success 201 {object} LoginResponseSchema
+// @Success 200 {object} TwoFactorChallengeResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 429 {object} base.TooManyRequestsErrorExample
+// @Router /auth/magic-link/verify [post]
+func MagicLinkLogin(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := TokenSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		ip := c.IP()
+		if wait := ipTracker.RetryAfter(ip); wait > 0 {
+			return TooManyAttemptsErr(c, wait, "Too many failed login attempts from your network. Try again later")
+		}
+		user := userManager.ConsumeMagicLinkToken(db, ctx, data.Token)
+		if user == nil {
+			userManager.RecordIPFailure(db, ctx, ip)
+			EmitSecurityEvent(db, c, nil, SE_LOGIN_FAILED, map[string]string{"reason": "invalid_magic_link"})
+			return config.APIError(c, 400, config.RequestErr(config.ERR_INVALID_TOKEN, "Invalid or used login link"))
+		}
+		if time.Now().After(*user.MagicLinkExpiry) {
+			EmitSecurityEvent(db, c, &user.ID, SE_LOGIN_FAILED, map[string]string{"reason": "expired_magic_link"})
+			return config.APIError(c, 400, config.RequestErr(config.ERR_EXPIRED_TOKEN, "Expired login link"))
+		}
+		if errData := checkLoginAllowed(c, user); errData != nil {
+			return errData
+		}
+		if !user.IsVerified {
+			user = user.Update().SetIsVerified(true).SaveX(ctx)
+		}
+
+		if user.TwoFactorEnabled {
+			return c.Status(200).JSON(TwoFactorChallengeResponse(user))
+		}
+		userManager.ResetFailedLogins(db, ctx, user)
+		response := LoginResponseSchema{
+			ResponseSchema: base.ResponseMessage("Login successful"),
+			Data:           IssueLoginTokens(db, c, user, SE_MAGIC_LINK_LOGIN, nil),
 		}
 		return c.Status(201).JSON(response)
 	}
@@ -242,12 +431,12 @@ func Login(db *ent.Client) fiber.Handler {
 // @Tags Auth
 // @Param user body TokenSchema true "Google auth"
 // @Success 201 {object} LoginResponseSchema
+// @Success 200 {object} TwoFactorChallengeResponseSchema
 // @Failure 422 {object} base.ValidationErrorExample
 // @Failure 401 {object} base.UnauthorizedErrorExample
 // @Router /auth/google [post]
 func GoogleLogin(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
 		data := TokenSchema{}
 
 		// Validate request
@@ -255,29 +444,197 @@ func GoogleLogin(db *ent.Client) fiber.Handler {
 			return c.Status(*errCode).JSON(errData)
 		}
 
-		userGoogleData, errData := ConvertGoogleToken(ctx, data.Token)
+		info, errData := GoogleProvider{}.FetchUser(c.Context(), SocialLoginSchema{Token: data.Token})
 		if errData != nil {
+			EmitSecurityEvent(db, c, nil, SE_LOGIN_FAILED, map[string]string{"provider": "google", "reason": errData.Message})
 			return config.APIError(c, 401, *errData)
 		}
+		return socialLoginResponse(db, c, "google", info)
+	}
+}
 
-		email := userGoogleData.Email
-		name := userGoogleData.Name
-		avatar := userGoogleData.Picture
+func socialLoginResponse(db *ent.Client, c *fiber.Ctx, provider string, info *SocialUserInfo) error {
+	socialUser, errData := SocialLoginUser(db, c.Context(), provider, info)
+	if errData != nil {
+		EmitSecurityEvent(db, c, nil, SE_LOGIN_FAILED, map[string]string{"provider": provider, "reason": errData.Message})
+		return config.APIError(c, 401, *errData)
+	}
+	if !socialUser.IsActive {
+		return AccountDeactivatedErr(c)
+	}
+	eventType := SE_SOCIAL_LOGIN
+	if provider == "google" {
+		eventType = SE_GOOGLE_LOGIN
+	}
+	if socialUser.TwoFactorEnabled {
+		return c.Status(200).JSON(TwoFactorChallengeResponse(socialUser))
+	}
+	response := LoginResponseSchema{
+		ResponseSchema: base.ResponseMessage("Login successful"),
+		Data:           IssueLoginTokens(db, c, socialUser, eventType, map[string]string{"provider": provider}),
+	}
+	return c.Status(201).JSON(response)
+}
 
-		access, refresh, err := RegisterSocialUser(db, ctx, email, name, &avatar)
-		if err != nil {
-			return c.Status(401).JSON(err)
+// @Summary List social login providers
+// @Description `This endpoint returns the names of the social login providers enabled on this server`
+// @Tags Auth
+// @Success 200 {object} SocialProvidersResponseSchema
+// @Router /auth/social/providers [get]
+func ListSocialProviders(cfg config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		names := make([]string, 0)
+		for name := range GetSocialProviders(cfg) {
+			names = append(names, name)
 		}
-		response := LoginResponseSchema{
-			ResponseSchema: base.ResponseMessage("Login successful"),
-			Data:           TokensResponseSchema{Access: *access, Refresh: *refresh},
+		sort.Strings(names)
+		response := SocialProvidersResponseSchema{
+			ResponseSchema: base.ResponseMessage("Providers fetched"),
+			Data:           names,
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Login with a social provider
+// @Description `This endpoint logs a user in through an external identity provider (google, github, microsoft or the configured OIDC provider)`
+// @Description `Send an id token as "token" for google, and an authorization code (with the redirect_uri used to get it) as "code" for the others`
+// @Description `New users are registered automatically. If the user has 2FA enabled, a challenge token is returned instead of tokens`
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Param data body SocialLoginSchema true "Provider credential"
+// @Success 201 {object} LoginResponseSchema
+// @Success 200 {object} TwoFactorChallengeResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/social/{provider} [post]
+func SocialLogin(db *ent.Client, cfg config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provider := GetSocialProvider(cfg, c.Params("provider"))
+		if provider == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Provider Not Found"))
+		}
+		data := SocialLoginSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		info, errData := provider.FetchUser(c.Context(), data)
+		if errData != nil {
+			EmitSecurityEvent(db, c, nil, SE_LOGIN_FAILED, map[string]string{"provider": provider.Name(), "reason": errData.Message})
+			return config.APIError(c, 401, *errData)
+		}
+		return socialLoginResponse(db, c, provider.Name(), info)
+	}
+}
+
+// @Summary Link a social provider
+// @Description `This endpoint links an external identity to the authenticated user so they can login with it too`
+// @Description `The credential is sent the same way as when logging in with the provider`
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Param data body SocialLoginSchema true "Provider credential"
+// @Success 201 {object} IdentityResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/social/{provider}/link [post]
+// @Security BearerAuth
+func LinkSocialProvider(db *ent.Client, cfg config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		provider := GetSocialProvider(cfg, c.Params("provider"))
+		if provider == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Provider Not Found"))
+		}
+		data := SocialLoginSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		info, errData := provider.FetchUser(ctx, data)
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		if identity := userManager.GetIdentity(db, ctx, provider.Name(), info.ProviderUserID); identity != nil {
+			errMsg := "This account is already linked to another user"
+			if identity.UserID == user.ID {
+				errMsg = "This account is already linked"
+			}
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, errMsg))
+		}
+		if userManager.HasProvider(db, ctx, user.ID, provider.Name()) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, fmt.Sprintf("You already have a linked %s account", provider.Name())))
+		}
+
+		identity := userManager.LinkIdentity(db, ctx, user.ID, provider.Name(), info)
+		response := IdentityResponseSchema{
+			ResponseSchema: base.ResponseMessage("Account linked successfully"),
+			Data:           IdentitySchema{}.Assign(identity),
 		}
 		return c.Status(201).JSON(response)
 	}
 }
 
+// @Summary List linked identities
+// @Description `This endpoint lists the external identities linked to the authenticated user`
+// @Tags Auth
+// @Success 200 {object} IdentitiesResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/identities [get]
+// @Security BearerAuth
+func GetIdentities(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		identities := userManager.GetIdentities(db, c.Context(), user.ID)
+		response := IdentitiesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Identities fetched"),
+		}.Assign(identities)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Unlink an identity
+// @Description `This endpoint removes a linked external identity from the authenticated user`
+// @Description `Users without a password (registered through social login) cannot remove their last identity`
+// @Tags Auth
+// @Param id path string true "Identity ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/identities/{id} [delete]
+// @Security BearerAuth
+func UnlinkIdentity(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		identityID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		identity := userManager.GetUserIdentity(db, ctx, user.ID, *identityID)
+		if identity == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Identity Not Found"))
+		}
+		if user.SocialLogin && len(userManager.GetIdentities(db, ctx, user.ID)) == 1 {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "You cannot unlink your only login method"))
+		}
+		userManager.UnlinkIdentity(db, ctx, identity)
+		return c.Status(200).JSON(base.ResponseMessage("Identity unlinked successfully"))
+	}
+}
+
 // @Summary Refresh tokens
 // @Description `This endpoint refresh tokens by generating new access and refresh tokens for a user`
+// @Description `Refresh tokens are single use. Presenting one that has already been rotated revokes every session of that login`
 // @Tags Auth
 // @Param refresh body TokenSchema true "Refresh token"
 // @Success 201 {object} LoginResponseSchema
@@ -295,15 +652,32 @@ func Refresh(db *ent.Client) fiber.Handler {
 		}
 
 		token := data.Token
-		user := DecodeRefreshToken(db, ctx, token)
-		if user == nil {
+		session := DecodeRefreshToken(db, ctx, token)
+		if session == nil {
+			// A validly signed token that was already rotated means it leaked. Kill its whole family
+			if ValidateRefreshToken(token) {
+				if usedToken := userManager.GetUsedRefreshToken(db, ctx, token); usedToken != nil {
+					userManager.RevokeTokenFamily(db, ctx, usedToken.FamilyID)
+					EmitSecurityEvent(db, c, &usedToken.UserID, SE_REFRESH_TOKEN_REUSE, map[string]string{
+						"family_id": usedToken.FamilyID.String(),
+					})
+					return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_TOKEN, "Refresh token reuse detected. Please login again"))
+				}
+			}
 			return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_TOKEN, "Refresh token is invalid or expired"))
 		}
 
-		// Create and Update Auth Tokens
+		// Create and Rotate Auth Tokens
+		user := session.Edges.User
+		if !user.IsActive {
+			return AccountDeactivatedErr(c)
+		}
 		access := GenerateAccessToken(user.ID, user.Username)
 		refresh := GenerateRefreshToken()
-		userManager.UpdateTokens(db, ctx, access, refresh, token)
+		if !userManager.RotateTokens(db, ctx, session, access, refresh, GetSessionInfo(c)) {
+			return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_TOKEN, "Refresh token is invalid or expired"))
+		}
+		EmitSecurityEvent(db, c, &user.ID, SE_REFRESH, nil)
 
 		response := LoginResponseSchema{
 			ResponseSchema: base.ResponseMessage("Tokens refresh successful"),
@@ -323,6 +697,7 @@ func Refresh(db *ent.Client) fiber.Handler {
 func Logout(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userManager.DeleteToken(db, c.Context(), c.Get("Authorization")[7:])
+		EmitSecurityEvent(db, c, &base.RequestUser(c).ID, SE_LOGOUT, nil)
 		return c.Status(200).JSON(base.ResponseMessage("Logout successful"))
 	}
 }
@@ -338,7 +713,307 @@ func LogoutAll(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		user := base.RequestUser(c)
 		userManager.ClearTokens(db, c.Context(), user.ID)
+		EmitSecurityEvent(db, c, &user.ID, SE_LOGOUT_ALL, nil)
 		return c.Status(200).JSON(base.ResponseMessage("Logout successful"))
 	}
 }
 
+// @Summary List active sessions
+// @Description `This endpoint lists the devices the authenticated user is currently logged in on`
+// @Tags Auth
+// @Success 200 {object} SessionsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/sessions [get]
+// @Security BearerAuth
+func GetSessions(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		sessions := userManager.GetSessions(db, c.Context(), user.ID)
+		response := SessionsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Sessions fetched"),
+		}.Assign(sessions, c.Get("Authorization")[7:])
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Revoke a session
+// @Description `This endpoint logs the authenticated user out of a single device, e.g a lost phone`
+// @Tags Auth
+// @Param id path string true "Session ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/sessions/{id} [delete]
+// @Security BearerAuth
+func RevokeSession(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		sessionID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		session := userManager.GetSession(db, ctx, user.ID, *sessionID)
+		if session == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Session Not Found"))
+		}
+		userManager.DeleteSession(db, ctx, session)
+		return c.Status(200).JSON(base.ResponseMessage("Session revoked successfully"))
+	}
+}
+
+// @Summary List your security events
+// @Description `This endpoint lists the authenticated user's recent authentication events (logins, failed attempts, password resets...), newest first`
+// @Tags Auth
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param event query string false "Filter By Event (e.g login, login_failed, password_reset)"
+// @Success 200 {object} AuthEventsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/events [get]
+// @Security BearerAuth
+func GetAuthEvents(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		authEvents := userManager.GetAuthEventsPaginated(db, c, user.ID, c.Query("event"))
+		response := AuthEventsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Security events fetched"),
+		}.Assign(authEvents)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Get the token verification keys
+// @Description `This endpoint publishes the public keys that verify EDNET jwts, as a JSON Web Key Set. It lives at the root: /.well-known/jwks.json`
+// @Description `Pick the key matching the kid header of a token. Keys rotate regularly, so refetch the set when a kid is unknown`
+// @Tags Auth
+// @Success 200 {object} JWKSResponseSchema
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(200).JSON(JWKSResponseSchema{Keys: PublicJWKs()})
+}
+
+// @Summary List personal access tokens
+// @Description `This endpoint lists the authenticated user's personal access tokens. The tokens themselves are never shown again after creation`
+// @Tags Auth
+// @Success 200 {object} PersonalAccessTokensResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /auth/tokens [get]
+// @Security BearerAuth
+func GetPersonalAccessTokens(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		accessTokens := userManager.GetPersonalAccessTokens(db, c.Context(), user.ID)
+		response := PersonalAccessTokensResponseSchema{
+			ResponseSchema: base.ResponseMessage("Access tokens fetched"),
+		}.Assign(accessTokens)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Create a personal access token
+// @Description `This endpoint creates a token for scripts and other programmatic access. Send it as 'Authorization: Bearer pat_...'`
+// @Description `The token is only shown in this response, so store it safely. It expires after expires_in_days (30 by default, 365 at most).`
+// @Description `Scopes are permission names (e.g course.read or course.*) and limit what the token can do on permission protected endpoints. They must be held by the user. Every other endpoint needs a login session.`
+// @Description `Tokens can't be used to manage the account's credentials (tokens, sessions, 2FA, email, deletion).`
+// @Tags Auth
+// @Param data body PersonalAccessTokenCreateSchema true "Token object"
+// @Success 201 {object} PersonalAccessTokenCreatedResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /auth/tokens [post]
+// @Security BearerAuth
+func CreatePersonalAccessToken(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		data := PersonalAccessTokenCreateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		granted := userManager.GetPermissions(db, ctx, user)
+		for _, scope := range data.Scopes {
+			if !IsValidPermission(scope) {
+				return config.APIError(c, 422, config.ValidationErr("scopes", fmt.Sprintf("%s is not a valid permission", scope)))
+			}
+			if !HasPermission(granted, scope) {
+				return config.APIError(c, 422, config.ValidationErr("scopes", fmt.Sprintf("You do not have the %s permission", scope)))
+			}
+		}
+
+		accessToken, rawToken := userManager.CreatePersonalAccessToken(db, ctx, user.ID, data)
+		response := PersonalAccessTokenCreatedResponseSchema{
+			ResponseSchema: base.ResponseMessage("Access token created. Copy it now, it won't be shown again"),
+			Data: PersonalAccessTokenCreatedSchema{
+				PersonalAccessTokenSchema: PersonalAccessTokenSchema{}.Assign(accessToken),
+				Token:                     rawToken,
+			},
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Revoke a personal access token
+// @Description `This endpoint revokes one of the authenticated user's personal access tokens`
+// @Tags Auth
+// @Param id path string true "Token ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /auth/tokens/{id} [delete]
+// @Security BearerAuth
+func RevokePersonalAccessToken(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		tokenID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		accessToken := userManager.GetPersonalAccessToken(db, ctx, user.ID, *tokenID)
+		if accessToken == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Access Token Not Found"))
+		}
+		userManager.RevokePersonalAccessToken(db, ctx, accessToken)
+		return c.Status(200).JSON(base.ResponseMessage("Access token revoked successfully"))
+	}
+}
+
+// @Summary Start two-factor authentication setup
+// @Description `This endpoint generates a new TOTP secret and otpauth uri for the authenticated user`
+// @Description `Add it to an authenticator app, then confirm with a code at /auth/2fa/enable. 2FA stays off until confirmed`
+// @Tags Auth
+// @Success 200 {object} TwoFactorSetupResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/2fa/setup [post]
+// @Security BearerAuth
+func SetupTwoFactor(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		if user.TwoFactorEnabled {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Two-factor authentication is already enabled"))
+		}
+		secret := GenerateTotpSecret()
+		userManager.SetTotpSecret(db, c.Context(), user, secret)
+		response := TwoFactorSetupResponseSchema{
+			ResponseSchema: base.ResponseMessage("Two-factor setup started"),
+			Data:           TwoFactorSetupSchema{Secret: secret, OtpauthUri: TotpUri(secret, user.Email)},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Enable two-factor authentication
+// @Description `This endpoint confirms the TOTP setup with a code from the authenticator app and enables 2FA`
+// @Description `The recovery codes in the response are shown only once. Store them safely`
+// @Tags Auth
+// @Param data body TwoFactorCodeSchema true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/2fa/enable [post]
+// @Security BearerAuth
+func EnableTwoFactor(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		data := TwoFactorCodeSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if user.TwoFactorEnabled {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Two-factor authentication is already enabled"))
+		}
+		if user.TotpSecret == nil {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Start two-factor setup first"))
+		}
+		if !userManager.UseTotpCode(db, ctx, user, data.Code) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect 2FA code"))
+		}
+
+		codes := userManager.EnableTwoFactor(db, ctx, user)
+		response := RecoveryCodesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Two-factor authentication enabled"),
+			Data:           RecoveryCodesSchema{RecoveryCodes: codes},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Disable two-factor authentication
+// @Description `This endpoint disables 2FA for the authenticated user. A TOTP code or recovery code is required`
+// @Tags Auth
+// @Param data body TwoFactorCodeSchema true "TOTP or recovery code"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/2fa/disable [post]
+// @Security BearerAuth
+func DisableTwoFactor(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		data := TwoFactorCodeSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if !user.TwoFactorEnabled {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Two-factor authentication is not enabled"))
+		}
+		if !userManager.VerifyTwoFactorCode(db, ctx, user, data.Code) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect 2FA code"))
+		}
+
+		userManager.DisableTwoFactor(db, ctx, user)
+		return c.Status(200).JSON(base.ResponseMessage("Two-factor authentication disabled"))
+	}
+}
+
+// @Summary Regenerate recovery codes
+// @Description `This endpoint replaces all existing recovery codes with new ones. A current TOTP code is required`
+// @Tags Auth
+// @Param data body TwoFactorCodeSchema true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /auth/2fa/recovery-codes [post]
+// @Security BearerAuth
+func RegenerateRecoveryCodes(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		data := TwoFactorCodeSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if !user.TwoFactorEnabled || user.TotpSecret == nil {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Two-factor authentication is not enabled"))
+		}
+		if !userManager.UseTotpCode(db, ctx, user, data.Code) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_INCORRECT_OTP, "Incorrect 2FA code"))
+		}
+
+		codes := userManager.RegenerateRecoveryCodes(db, ctx, user)
+		response := RecoveryCodesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Recovery codes regenerated"),
+			Data:           RecoveryCodesSchema{RecoveryCodes: codes},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
@@ -0,0 +1,213 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enttest"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/useridentity"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const fakeProviderName = "fakeidp"
+
+var (
+	fakeIdp       *FakeIdentityProvider
+	socialTestCfg config.Config
+)
+
+func TestMain(m *testing.M) {
+	// Keeps emails from being sent
+	os.Setenv("ENVIRONMENT", "test")
+	os.Setenv("JWT_ALGORITHM", "EdDSA")
+	os.Setenv("ACCESS_TOKEN_EXPIRE_MINUTES", "30")
+	os.Setenv("REFRESH_TOKEN_EXPIRE_MINUTES", "60")
+
+	// Social providers are built once per process, so every test shares the same fake provider
+	fakeIdp = NewFakeIdentityProvider("")
+	server := httptest.NewServer(fakeIdp)
+	socialTestCfg = config.Config{OIDCIssuer: server.URL, OIDCProviderName: fakeProviderName, OIDCClientID: "ednet"}
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+func setupSocialLogin(t *testing.T) (*ent.Client, *fiber.App) {
+	db := enttest.Open(t, "sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name()))
+	t.Cleanup(func() { db.Close() })
+	InitSigningKeys(db, context.Background())
+
+	app := fiber.New()
+	app.Post("/auth/social/:provider", SocialLogin(db, socialTestCfg))
+	return db, app
+}
+
+func postSocialLogin(t *testing.T, app *fiber.App, code string) (int, map[string]interface{}) {
+	body := strings.NewReader(fmt.Sprintf(`{"code": %q, "redirect_uri": "http://localhost:3000/auth/callback"}`, code))
+	req := httptest.NewRequest(http.MethodPost, "/auth/social/"+fakeProviderName, body)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	data := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&data)
+	return resp.StatusCode, data
+}
+
+func TestSocialLoginRegistersNewUser(t *testing.T) {
+	db, app := setupSocialLogin(t)
+	ctx := context.Background()
+	fakeIdp.AddUser("new-user-code", SocialUserInfo{
+		ProviderUserID: "sub-new", Email: "new@example.com", EmailVerified: true, Name: "New User",
+	})
+
+	status, data := postSocialLogin(t, app, "new-user-code")
+	if status != 201 {
+		t.Fatalf("expected 201, got %d: %v", status, data)
+	}
+	tokens, _ := data["data"].(map[string]interface{})
+	if tokens["access"] == "" || tokens["refresh"] == "" {
+		t.Fatalf("expected tokens in response, got %v", data)
+	}
+
+	userObj, err := db.User.Query().Where(user.Email("new@example.com")).Only(ctx)
+	if err != nil {
+		t.Fatalf("expected the user to be registered: %v", err)
+	}
+	if !userObj.SocialLogin || !userObj.IsVerified {
+		t.Errorf("expected a verified social login user, got social_login=%v is_verified=%v", userObj.SocialLogin, userObj.IsVerified)
+	}
+	if !db.UserIdentity.Query().Where(useridentity.UserID(userObj.ID), useridentity.Provider(fakeProviderName), useridentity.ProviderUserID("sub-new")).ExistX(ctx) {
+		t.Error("expected the provider identity to be linked")
+	}
+}
+
+func TestSocialLoginRejectsBadCode(t *testing.T) {
+	db, app := setupSocialLogin(t)
+
+	status, data := postSocialLogin(t, app, "unknown-code")
+	if status != 401 {
+		t.Fatalf("expected 401, got %d: %v", status, data)
+	}
+	if count := db.User.Query().CountX(context.Background()); count != 0 {
+		t.Errorf("expected no user to be created, got %d", count)
+	}
+}
+
+func TestSocialLoginRejectsUnverifiedEmail(t *testing.T) {
+	db, app := setupSocialLogin(t)
+	fakeIdp.AddUser("unverified-code", SocialUserInfo{
+		ProviderUserID: "sub-unverified", Email: "unverified@example.com", EmailVerified: false, Name: "Unverified",
+	})
+
+	status, data := postSocialLogin(t, app, "unverified-code")
+	if status != 401 {
+		t.Fatalf("expected 401, got %d: %v", status, data)
+	}
+	if db.User.Query().Where(user.Email("unverified@example.com")).ExistX(context.Background()) {
+		t.Error("expected no user to be created for an unverified email")
+	}
+}
+
+func TestSocialLoginReadsVerificationFromIdToken(t *testing.T) {
+	db, app := setupSocialLogin(t)
+	ctx := context.Background()
+
+	t.Run("registers a user verified in the id token", func(t *testing.T) {
+		fakeIdp.AddIdTokenVerifiedUser("id-token-verified-code", SocialUserInfo{
+			ProviderUserID: "sub-id-token-verified", Email: "idtoken@example.com", EmailVerified: true, Name: "Id Token",
+		})
+		if status, data := postSocialLogin(t, app, "id-token-verified-code"); status != 201 {
+			t.Fatalf("expected 201, got %d: %v", status, data)
+		}
+		if !db.User.Query().Where(user.Email("idtoken@example.com"), user.IsVerified(true)).ExistX(ctx) {
+			t.Error("expected a verified user to be registered")
+		}
+	})
+
+	t.Run("rejects a user unverified in the id token", func(t *testing.T) {
+		fakeIdp.AddIdTokenVerifiedUser("id-token-unverified-code", SocialUserInfo{
+			ProviderUserID: "sub-id-token-unverified", Email: "idtoken-unverified@example.com", EmailVerified: false, Name: "Id Token",
+		})
+		if status, data := postSocialLogin(t, app, "id-token-unverified-code"); status != 401 {
+			t.Fatalf("expected 401, got %d: %v", status, data)
+		}
+		if db.User.Query().Where(user.Email("idtoken-unverified@example.com")).ExistX(ctx) {
+			t.Error("expected no user to be created for an unverified email")
+		}
+	})
+}
+
+func TestSocialLoginLinksAccounts(t *testing.T) {
+	db, app := setupSocialLogin(t)
+	ctx := context.Background()
+	createUser := func(email string, socialLogin bool) *ent.User {
+		return db.User.Create().
+			SetName("Existing User").
+			SetEmail(email).
+			SetUsername(strings.Split(email, "@")[0]).
+			SetPassword(config.HashPassword("password")).
+			SetSocialLogin(socialLogin).
+			SetIsVerified(true).
+			SaveX(ctx)
+	}
+
+	t.Run("links an existing social user by email", func(t *testing.T) {
+		existing := createUser("social@example.com", true)
+		fakeIdp.AddUser("link-code", SocialUserInfo{
+			ProviderUserID: "sub-social", Email: "social@example.com", EmailVerified: true, Name: "Social",
+		})
+		if status, data := postSocialLogin(t, app, "link-code"); status != 201 {
+			t.Fatalf("expected 201, got %d: %v", status, data)
+		}
+		identity, err := db.UserIdentity.Query().Where(useridentity.ProviderUserID("sub-social")).Only(ctx)
+		if err != nil || identity.UserID != existing.ID {
+			t.Fatalf("expected the identity to be linked to the existing user, got %v (%v)", identity, err)
+		}
+
+		// The linked identity logs in to the same user even after the email changes at the provider
+		fakeIdp.AddUser("link-code-again", SocialUserInfo{
+			ProviderUserID: "sub-social", Email: "renamed@example.com", EmailVerified: true, Name: "Social",
+		})
+		if status, data := postSocialLogin(t, app, "link-code-again"); status != 201 {
+			t.Fatalf("expected 201, got %d: %v", status, data)
+		}
+		if count := db.User.Query().Where(user.Email("renamed@example.com")).CountX(ctx); count != 0 {
+			t.Errorf("expected no new user, got %d", count)
+		}
+	})
+
+	t.Run("rejects a different account at the same provider", func(t *testing.T) {
+		fakeIdp.AddUser("other-sub-code", SocialUserInfo{
+			ProviderUserID: "sub-other", Email: "social@example.com", EmailVerified: true, Name: "Other",
+		})
+		if status, data := postSocialLogin(t, app, "other-sub-code"); status != 401 {
+			t.Fatalf("expected 401, got %d: %v", status, data)
+		}
+	})
+
+	t.Run("requires a password for password users", func(t *testing.T) {
+		createUser("password@example.com", false)
+		fakeIdp.AddUser("password-user-code", SocialUserInfo{
+			ProviderUserID: "sub-password", Email: "password@example.com", EmailVerified: true, Name: "Password",
+		})
+		if status, data := postSocialLogin(t, app, "password-user-code"); status != 401 {
+			t.Fatalf("expected 401, got %d: %v", status, data)
+		}
+		if db.UserIdentity.Query().Where(useridentity.ProviderUserID("sub-password")).ExistX(ctx) {
+			t.Error("expected no identity to be linked to a password user")
+		}
+	})
+}
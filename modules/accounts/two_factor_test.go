@@ -0,0 +1,44 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enttest"
+)
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	db := enttest.Open(t, "sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared&_fk=1")
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	userObj := db.User.Create().
+		SetName("Two Factor User").
+		SetEmail("twofactor@example.com").
+		SetUsername("twofactor").
+		SetPassword(config.HashPassword("password")).
+		SaveX(ctx)
+	userObj = userManager.SetTotpSecret(db, ctx, userObj, GenerateTotpSecret())
+	codes := userManager.EnableTwoFactor(db, ctx, userObj)
+
+	// Two logins that loaded the user before either of them used the code
+	first, second := db.User.GetX(ctx, userObj.ID), db.User.GetX(ctx, userObj.ID)
+	if !userManager.VerifyTwoFactorCode(db, ctx, first, codes[0]) {
+		t.Fatal("expected the recovery code to be accepted")
+	}
+	if userManager.VerifyTwoFactorCode(db, ctx, second, codes[0]) {
+		t.Error("expected the recovery code to be rejected once used by a concurrent login")
+	}
+	if remaining := db.User.GetX(ctx, userObj.ID).RecoveryCodes; len(remaining) != len(codes)-1 {
+		t.Errorf("expected %d recovery codes left, got %d", len(codes)-1, len(remaining))
+	}
+
+	// A fresh load sees the used code is gone, while the others still work
+	reloaded := db.User.GetX(ctx, userObj.ID)
+	if userManager.VerifyTwoFactorCode(db, ctx, reloaded, codes[0]) {
+		t.Error("expected a used recovery code to be rejected")
+	}
+	if !userManager.VerifyTwoFactorCode(db, ctx, reloaded, codes[1]) {
+		t.Error("expected an unused recovery code to be accepted")
+	}
+}
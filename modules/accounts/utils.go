@@ -2,13 +2,16 @@ package accounts
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/gosimple/slug"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
-	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
 	"github.com/mitchellh/mapstructure"
 	"google.golang.org/api/idtoken"
 )
@@ -43,32 +46,145 @@ func ConvertGoogleToken(ctx context.Context, accessToken string) (*GooglePayload
 	return &data, nil
 }
 
-func RegisterSocialUser(db *ent.Client, ctx context.Context, email string, name string, avatar *string) (*string, *string, *config.ErrorResponse) {
+// SocialLoginUser resolves the account for a provider identity, creating or linking it when necessary.
+// Existing accounts are only linked automatically when the provider has verified the email and the account
+// was itself created through social login. Password accounts must link providers from /auth/social/{provider}/link.
+func SocialLoginUser(db *ent.Client, ctx context.Context, provider string, info *SocialUserInfo) (*ent.User, *config.ErrorResponse) {
 	cfg := config.GetConfig()
 
-	socialUser, _ := db.User.Query().Where(user.Email(email)).Only(ctx)
-	if (socialUser == nil) {
+	if identity := userManager.GetIdentity(db, ctx, provider, info.ProviderUserID); identity != nil {
+		return identity.Edges.User, nil
+	}
+	if info.Email == "" || !info.EmailVerified {
+		errData := config.RequestErr(config.ERR_UNVERIFIED_USER, fmt.Sprintf("Your %s account has no verified email", provider))
+		return nil, &errData
+	}
+
+	socialUser := userManager.GetByEmail(db, ctx, info.Email)
+	if socialUser == nil {
 		password := config.HashPassword(cfg.SocialsPassword)
-		username := GenerateUsernameFromEmail(db, ctx, email, nil)
+		username := GenerateUsernameFromEmail(db, ctx, info.Email, nil)
+		name := info.Name
+		if len(name) < 2 {
+			name = username
+		}
 		socialUser = db.User.Create().
 			SetName(name).
-			SetEmail(email).
+			SetEmail(config.NormalizeEmail(info.Email)).
 			SetPassword(password).
-			SetUsername(username).	
-			SetSocialLogin(true).	
+			SetUsername(username).
+			SetNillableAvatar(info.Avatar).
+			SetSocialLogin(true).
 			SetIsVerified(true).
 			SaveX(ctx)
-	} else {
-		if !socialUser.SocialLogin {
-			errData := config.RequestErr(config.ERR_INVALID_AUTH, "Requires password to login")
-			return nil, nil, &errData
-		}
+	} else if !socialUser.SocialLogin {
+		errData := config.RequestErr(config.ERR_INVALID_AUTH, "Requires password to login")
+		return nil, &errData
+	} else if userManager.HasProvider(db, ctx, socialUser.ID, provider) {
+		// Another account at this provider is already linked and uses the same email
+		errData := config.RequestErr(config.ERR_INVALID_AUTH, fmt.Sprintf("A different %s account is linked to this user", provider))
+		return nil, &errData
 	}
-	// Generate tokens
-	access := GenerateAccessToken(socialUser.ID, socialUser.Username)
+	userManager.LinkIdentity(db, ctx, socialUser.ID, provider, info)
+	return socialUser, nil
+}
+
+// IssueLoginTokens starts a new session for a successful login and records it in the audit log under eventType.
+// The user is alerted by email when the login comes from a new ip or device.
+func IssueLoginTokens(db *ent.Client, fibCtx *fiber.Ctx, userObj *ent.User, eventType SecurityEventType, details map[string]string) TokensResponseSchema {
+	ctx := fibCtx.Context()
+	access := GenerateAccessToken(userObj.ID, userObj.Username)
 	refresh := GenerateRefreshToken()
-	userManager.AddTokens(db, ctx, socialUser, access, refresh)
-	return &access, &refresh, nil
+	sessionInfo := GetSessionInfo(fibCtx)
+	userManager.AddTokens(db, ctx, userObj, access, refresh, sessionInfo)
+
+	if userManager.IsNewLoginLocation(db, ctx, userObj.ID, sessionInfo.IP, sessionInfo.UserAgent) {
+		if details == nil {
+			details = map[string]string{}
+		}
+		details["new_location"] = "true"
+		message := fmt.Sprintf("%s, ip address %s, on %s", sessionInfo.DeviceName, sessionInfo.IP, time.Now().Format("January 2, 2006 15:04 MST"))
+		go config.SendMessageEmail(userObj, config.ET_NEW_LOGIN, message)
+	}
+	EmitSecurityEvent(db, fibCtx, &userObj.ID, eventType, details)
+	return TokensResponseSchema{Access: access, Refresh: refresh}
+}
+
+// StartPasswordReset emails the user a fresh password reset otp.
+// details is recorded with the audit event, e.g. to tell which admin triggered the reset.
+func StartPasswordReset(db *ent.Client, fibCtx *fiber.Ctx, userObj *ent.User, details map[string]string) {
+	otp, otpExp := userManager.GetOtp()
+	userObj.Update().SetOtp(otp).SetOtpExpiry(otpExp).SetOtpAttempts(0).Save(fibCtx.Context())
+	go config.SendEmail(userObj, config.ET_RESET, &otp)
+	EmitSecurityEvent(db, fibCtx, &userObj.ID, SE_PASSWORD_RESET_REQUESTED, details)
+}
+
+type SessionInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
+
+// GetSessionInfo describes the device making the request.
+// Clients can name themselves with the X-Device-Name header, otherwise a name is derived from the user agent.
+func GetSessionInfo(c *fiber.Ctx) SessionInfo {
+	userAgent := c.Get("User-Agent")
+	deviceName := strings.TrimSpace(c.Get("X-Device-Name"))
+	if deviceName == "" {
+		deviceName = DeviceNameFromUserAgent(userAgent)
+	}
+	if len(deviceName) > 100 {
+		deviceName = deviceName[:100]
+	}
+	return SessionInfo{DeviceName: deviceName, UserAgent: userAgent, IP: c.IP()}
+}
+
+func DeviceNameFromUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	platforms := []struct{ match, name string }{
+		{"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"},
+		{"Windows", "Windows"}, {"Macintosh", "Mac"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+	browsers := []struct{ match, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+		{"okhttp", "Android app"}, {"CFNetwork", "iOS app"}, {"PostmanRuntime", "Postman"}, {"curl/", "curl"},
+	}
+	platform, browser := "", ""
+	for _, p := range platforms {
+		if strings.Contains(userAgent, p.match) {
+			platform = p.name
+			break
+		}
+	}
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.match) {
+			browser = b.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && platform != "":
+		return fmt.Sprintf("%s on %s", browser, platform)
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
+
+func TwoFactorChallengeResponse(userObj *ent.User) TwoFactorChallengeResponseSchema {
+	challengeToken, expiresAt := GenerateTwoFactorChallengeToken(userObj.ID)
+	return TwoFactorChallengeResponseSchema{
+		ResponseSchema: base.ResponseMessage("Two-factor authentication required"),
+		Data: TwoFactorChallengeSchema{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+			ExpiresAt:         expiresAt,
+		},
+	}
 }
 
 func GenerateUsernameFromEmail(db *ent.Client, ctx context.Context, email string, username *string) string {
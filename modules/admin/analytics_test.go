@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enttest"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/payment"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("ENVIRONMENT", "test")
+	os.Exit(m.Run())
+}
+
+func TestGetTopCourses(t *testing.T) {
+	db := enttest.Open(t, "sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name()))
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	createUser := func(username string) *ent.User {
+		return db.User.Create().
+			SetName("Analytics User").
+			SetEmail(username + "@example.com").
+			SetUsername(username).
+			SetPassword(config.HashPassword("password")).
+			SaveX(ctx)
+	}
+	instructor := createUser("instructor")
+	students := []*ent.User{createUser("student1"), createUser("student2")}
+	category := db.Category.Create().SetName("Programming").SetSlug("programming").SaveX(ctx)
+	createCourse := func(slug string) *ent.Course {
+		return db.Course.Create().
+			SetTitle("Course " + slug).
+			SetSlug(slug).
+			SetDesc("A course for the analytics tests").
+			SetThumbnailURL("https://example.com/thumbnail.png").
+			SetCategory(category).
+			SetInstructor(instructor).
+			SaveX(ctx)
+	}
+
+	// popular has the most enrollments while rated has the best reviews
+	popular, rated := createCourse("popular"), createCourse("rated")
+	for i, student := range students {
+		db.Enrollment.Create().SetUser(student).SetCourse(popular).SetPaymentStatus(enrollment.PaymentStatusSuccessful).SaveX(ctx)
+		db.Payment.Create().
+			SetUser(student).SetCourse(popular).SetAmount(19.99).
+			SetStatus(payment.StatusSuccessful).SetPaymentMethod("card").SetTransactionID(uuid.NewString()).
+			SaveX(ctx)
+		db.Review.Create().SetUser(student).SetCourse(popular).SetRating(float64(3 + i)).SaveX(ctx)
+	}
+	db.Enrollment.Create().SetUser(students[0]).SetCourse(rated).SetPaymentStatus(enrollment.PaymentStatusSuccessful).SaveX(ctx)
+	db.Review.Create().SetUser(students[0]).SetCourse(rated).SetRating(5).SaveX(ctx)
+
+	app := fiber.New()
+	app.Get("/admin/analytics/top-courses", GetTopCourses(db))
+	end := time.Now().UTC().Format("2006-01-02")
+
+	tests := []struct {
+		sortBy   string
+		expected []TopCourseSchema
+	}{
+		{
+			sortBy: TOP_BY_ENROLLMENTS,
+			expected: []TopCourseSchema{
+				{Course: UserCourseSchema{ID: popular.ID}, Enrollments: 2, Revenue: 39.98, ReviewsCount: 2, Rating: 3.5},
+				{Course: UserCourseSchema{ID: rated.ID}, Enrollments: 1, ReviewsCount: 1, Rating: 5},
+			},
+		},
+		{
+			sortBy: TOP_BY_RATING,
+			expected: []TopCourseSchema{
+				{Course: UserCourseSchema{ID: rated.ID}, Enrollments: 1, ReviewsCount: 1, Rating: 5},
+				{Course: UserCourseSchema{ID: popular.ID}, Enrollments: 2, Revenue: 39.98, ReviewsCount: 2, Rating: 3.5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/analytics/top-courses?sort_by="+tt.sortBy+"&end="+end, nil)
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Fatalf("expected 200, got %d", resp.StatusCode)
+			}
+			data := TopCoursesResponseSchema{}
+			json.NewDecoder(resp.Body).Decode(&data)
+			if len(data.Data) != len(tt.expected) {
+				t.Fatalf("expected %d courses, got %+v", len(tt.expected), data.Data)
+			}
+			for i, expected := range tt.expected {
+				got := data.Data[i]
+				if got.Course.ID != expected.Course.ID || got.Enrollments != expected.Enrollments || got.Revenue != expected.Revenue ||
+					got.ReviewsCount != expected.ReviewsCount || got.Rating != expected.Rating {
+					t.Errorf("expected %+v at position %d, got %+v", expected, i, got)
+				}
+			}
+		})
+	}
+}
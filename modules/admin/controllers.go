@@ -1 +1,1410 @@
-package admin
\ No newline at end of file
+package admin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/instructorapplication"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/general"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/instructors"
+)
+
+var adminManager = AdminManager{}
+var userManager = accounts.UserManager{}
+var courseManager = courses.CourseManager{}
+
+// @Summary Retrieve Lockouts
+// @Description `This endpoint retrieves paginated account and ip lockouts caused by too many failed login or otp attempts, newest first`
+// @Tags Admin
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param active query bool false "Only lockouts still in effect"
+// @Success 200 {object} LockoutsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/lockouts [get]
+// @Security BearerAuth
+func GetLockouts(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lockouts := adminManager.GetLockoutsPaginated(db, c, c.QueryBool("active"))
+		response := LockoutsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Lockouts Fetched Successfully"),
+		}.Assign(lockouts)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Security Events
+// @Description `This endpoint retrieves paginated authentication events of every user (logins, failed attempts, password resets...), newest first`
+// @Tags Admin
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param user_id query string false "Filter By User ID"
+// @Param event query string false "Filter By Event (e.g login, login_failed, password_reset)"
+// @Param ip query string false "Filter By IP"
+// @Success 200 {object} AuthEventsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/auth-events [get]
+// @Security BearerAuth
+func GetAuthEvents(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var userID *uuid.UUID
+		if userIDStr := c.Query("user_id"); userIDStr != "" {
+			parsedID, errData := config.ParseUUID(userIDStr)
+			if errData != nil {
+				return config.APIError(c, 400, *errData)
+			}
+			userID = parsedID
+		}
+		authEvents := adminManager.GetAuthEventsPaginated(db, c, userID, c.Query("event"), c.Query("ip"))
+		response := AuthEventsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Security Events Fetched Successfully"),
+		}.Assign(authEvents)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Permissions
+// @Description `This endpoint lists every permission that can be granted to a role. "<resource>.*" and "*" wildcards are also accepted`
+// @Tags Admin
+// @Success 200 {object} PermissionsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/permissions [get]
+// @Security BearerAuth
+func GetPermissions(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		response := PermissionsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Permissions Fetched Successfully"),
+			Data:           accounts.Permissions,
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Roles
+// @Description `This endpoint lists all roles with their permissions, system roles first`
+// @Tags Admin
+// @Success 200 {object} RolesResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/roles [get]
+// @Security BearerAuth
+func GetRoles(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		roles := adminManager.GetRoles(db, c.Context())
+		response := RolesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Roles Fetched Successfully"),
+		}.Assign(roles)
+		return c.Status(200).JSON(response)
+	}
+}
+
+func validatePermissions(permissions []string) *config.ErrorResponse {
+	for _, permission := range permissions {
+		if !accounts.IsValidPermission(permission) {
+			errData := config.ValidationErr("permissions", fmt.Sprintf("%s is not a valid permission", permission))
+			return &errData
+		}
+	}
+	return nil
+}
+
+// @Summary Create A Role
+// @Description `This endpoint creates a custom role (e.g teaching_assistant or support) which can then be assigned to users`
+// @Tags Admin
+// @Param role body RoleCreateSchema true "Role object"
+// @Success 201 {object} RoleResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/roles [post]
+// @Security BearerAuth
+func CreateRole(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := RoleCreateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		data.Name = strings.ReplaceAll(config.Slugify(data.Name), "-", "_")
+		if data.Name == "" {
+			return config.APIError(c, 422, config.ValidationErr("name", "Invalid role name"))
+		}
+		if adminManager.GetRoleByName(db, ctx, data.Name) != nil {
+			return config.APIError(c, 422, config.ValidationErr("name", "Role already exists"))
+		}
+		if errData := validatePermissions(data.Permissions); errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
+
+		role := adminManager.CreateRole(db, ctx, data)
+		response := RoleResponseSchema{
+			ResponseSchema: base.ResponseMessage("Role Created Successfully"),
+			Data:           RoleSchema{}.Assign(role),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Update A Role
+// @Description `This endpoint updates a role's description or permissions. The admin role cannot be changed`
+// @Tags Admin
+// @Param id path string true "Role ID"
+// @Param role body RoleUpdateSchema true "Role object"
+// @Success 200 {object} RoleResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/roles/{id} [put]
+// @Security BearerAuth
+func UpdateRole(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		roleID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		role := adminManager.GetRoleByID(db, ctx, *roleID)
+		if role == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Role Not Found"))
+		}
+		if role.Name == string(user.RoleAdmin) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "The admin role cannot be changed"))
+		}
+		data := RoleUpdateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if errData := validatePermissions(data.Permissions); errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
+
+		role = adminManager.UpdateRole(db, ctx, role, data)
+		response := RoleResponseSchema{
+			ResponseSchema: base.ResponseMessage("Role Updated Successfully"),
+			Data:           RoleSchema{}.Assign(role),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete A Role
+// @Description `This endpoint deletes a custom role, removing it from every user that held it. System roles cannot be deleted`
+// @Tags Admin
+// @Param id path string true "Role ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/roles/{id} [delete]
+// @Security BearerAuth
+func DeleteRole(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		roleID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		role := adminManager.GetRoleByID(db, ctx, *roleID)
+		if role == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Role Not Found"))
+		}
+		if role.IsSystem {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "System roles cannot be deleted"))
+		}
+		db.Role.DeleteOne(role).ExecX(ctx)
+		return c.Status(200).JSON(base.ResponseMessage("Role Deleted Successfully"))
+	}
+}
+
+func userRoleNames(db *ent.Client, c *fiber.Ctx, userObj *ent.User) []string {
+	names := make([]string, 0)
+	for _, role := range userManager.GetRoles(db, c.Context(), userObj) {
+		names = append(names, role.Name)
+	}
+	return names
+}
+
+// @Summary Assign A Role To A User
+// @Description `This endpoint gives a user an extra role on top of their base role`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Param data body UserRoleSchema true "Role name"
+// @Success 200 {object} UserRolesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/roles [post]
+// @Security BearerAuth
+func AssignUserRole(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		userObj := adminManager.GetUserByID(db, ctx, *userID)
+		if userObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("User Not Found"))
+		}
+		data := UserRoleSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		role := adminManager.GetRoleByName(db, ctx, data.Role)
+		if role == nil {
+			return config.APIError(c, 422, config.ValidationErr("role", "Role does not exist"))
+		}
+
+		adminManager.AssignRole(db, ctx, userObj, role)
+		response := UserRolesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Role Assigned Successfully"),
+			Data:           userRoleNames(db, c, userObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Remove A Role From A User
+// @Description `This endpoint removes an extra role from a user. A user's base role cannot be removed here`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} UserRolesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/roles/{role} [delete]
+// @Security BearerAuth
+func RemoveUserRole(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		userObj := adminManager.GetUserByID(db, ctx, *userID)
+		if userObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("User Not Found"))
+		}
+		role := adminManager.GetRoleByName(db, ctx, c.Params("role"))
+		if role == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Role Not Found"))
+		}
+		if role.Name == string(userObj.Role) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "This is the user's base role"))
+		}
+
+		adminManager.RemoveRole(db, ctx, userObj, role)
+		response := UserRolesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Role Removed Successfully"),
+			Data:           userRoleNames(db, c, userObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// getTargetUser fetches the user in the id path param. Anonymised (deleted) accounts are treated as missing
+func getTargetUser(db *ent.Client, c *fiber.Ctx) (*ent.User, error) {
+	userID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	userObj := adminManager.GetUserByID(db, c.Context(), *userID)
+	if userObj == nil || userObj.DeletedAt != nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("User Not Found"))
+	}
+	return userObj, nil
+}
+
+// adminEventDetails tells who performed an admin action, for the target user's audit log
+func adminEventDetails(c *fiber.Ctx) map[string]string {
+	return map[string]string{"by": base.RequestUser(c).Username}
+}
+
+// @Summary Retrieve Users
+// @Description `This endpoint retrieves paginated users, newest first. The search matches name, username or email`
+// @Tags Admin
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param search query string false "Search By Name, Username Or Email"
+// @Param role query string false "Filter By Base Role (student, instructor or admin)"
+// @Param is_active query bool false "Filter By Active Status"
+// @Success 200 {object} AdminUsersResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users [get]
+// @Security BearerAuth
+func GetUsers(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var role *user.Role
+		if roleStr := c.Query("role"); roleStr != "" {
+			roleVal := user.Role(roleStr)
+			if user.RoleValidator(roleVal) != nil {
+				return config.APIError(c, 400, config.InvalidParamErr("Invalid role"))
+			}
+			role = &roleVal
+		}
+		var isActive *bool
+		if c.Query("is_active") != "" {
+			isActiveVal := c.QueryBool("is_active")
+			isActive = &isActiveVal
+		}
+		users := adminManager.GetUsersPaginated(db, c, strings.TrimSpace(c.Query("search")), role, isActive)
+		response := AdminUsersResponseSchema{
+			ResponseSchema: base.ResponseMessage("Users Fetched Successfully"),
+		}.Assign(users)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve A User
+// @Description `This endpoint retrieves the account details of a user, including all their roles`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Success 200 {object} AdminUserResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id} [get]
+// @Security BearerAuth
+func GetUser(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		response := AdminUserResponseSchema{
+			ResponseSchema: base.ResponseMessage("User Fetched Successfully"),
+			Data: AdminUserDetailSchema{
+				AdminUserSchema: AdminUserSchema{}.Assign(userObj),
+				Roles:           userRoleNames(db, c, userObj),
+			},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve A User's Enrollments
+// @Description `This endpoint retrieves the paginated course enrollments of a user, newest first`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} UserEnrollmentsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/enrollments [get]
+// @Security BearerAuth
+func GetUserEnrollments(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		enrollments := adminManager.GetUserEnrollmentsPaginated(db, c, userObj.ID)
+		response := UserEnrollmentsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Enrollments Fetched Successfully"),
+		}.Assign(enrollments)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve A User's Payments
+// @Description `This endpoint retrieves the paginated payments of a user, newest first`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} UserPaymentsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/payments [get]
+// @Security BearerAuth
+func GetUserPayments(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		payments := adminManager.GetUserPaymentsPaginated(db, c, userObj.ID)
+		response := UserPaymentsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Payments Fetched Successfully"),
+		}.Assign(payments)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Deactivate A User
+// @Description `This endpoint deactivates an account. The user is logged out everywhere and can no longer login or use their access tokens`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Success 200 {object} AdminUserResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/deactivate [post]
+// @Security BearerAuth
+func DeactivateUser(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		if userObj.ID == base.RequestUser(c).ID {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "You can't deactivate your own account"))
+		}
+		if !userObj.IsActive {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "User is already deactivated"))
+		}
+		userObj = adminManager.SetUserActive(db, c.Context(), userObj, false)
+		accounts.EmitSecurityEvent(db, c, &userObj.ID, accounts.SE_ACCOUNT_DEACTIVATED, adminEventDetails(c))
+		response := AdminUserResponseSchema{
+			ResponseSchema: base.ResponseMessage("User Deactivated Successfully"),
+			Data: AdminUserDetailSchema{
+				AdminUserSchema: AdminUserSchema{}.Assign(userObj),
+				Roles:           userRoleNames(db, c, userObj),
+			},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Reactivate A User
+// @Description `This endpoint reactivates a deactivated account`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Success 200 {object} AdminUserResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/reactivate [post]
+// @Security BearerAuth
+func ReactivateUser(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		if userObj.IsActive {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "User is already active"))
+		}
+		userObj = adminManager.SetUserActive(db, c.Context(), userObj, true)
+		accounts.EmitSecurityEvent(db, c, &userObj.ID, accounts.SE_ACCOUNT_REACTIVATED, adminEventDetails(c))
+		response := AdminUserResponseSchema{
+			ResponseSchema: base.ResponseMessage("User Reactivated Successfully"),
+			Data: AdminUserDetailSchema{
+				AdminUserSchema: AdminUserSchema{}.Assign(userObj),
+				Roles:           userRoleNames(db, c, userObj),
+			},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Change A User's Base Role
+// @Description `This endpoint changes the base role (student, instructor or admin) of a user. Extra roles are managed with /admin/users/{id}/roles`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Param data body UserBaseRoleSchema true "Base role"
+// @Success 200 {object} AdminUserResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/role [put]
+// @Security BearerAuth
+func ChangeUserRole(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		if userObj.ID == base.RequestUser(c).ID {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "You can't change your own role"))
+		}
+		data := UserBaseRoleSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		previousRole := userObj.Role
+		userObj = adminManager.SetUserRole(db, c.Context(), userObj, data.Role)
+		details := adminEventDetails(c)
+		details["from"] = string(previousRole)
+		details["to"] = string(data.Role)
+		accounts.EmitSecurityEvent(db, c, &userObj.ID, accounts.SE_ROLE_CHANGED, details)
+		response := AdminUserResponseSchema{
+			ResponseSchema: base.ResponseMessage("Role Changed Successfully"),
+			Data: AdminUserDetailSchema{
+				AdminUserSchema: AdminUserSchema{}.Assign(userObj),
+				Roles:           userRoleNames(db, c, userObj),
+			},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Force Logout A User
+// @Description `This endpoint ends every session of a user. Personal access tokens are left untouched`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/logout [post]
+// @Security BearerAuth
+func ForceLogoutUser(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		userManager.ClearTokens(db, c.Context(), userObj.ID)
+		accounts.EmitSecurityEvent(db, c, &userObj.ID, accounts.SE_FORCED_LOGOUT, adminEventDetails(c))
+		return c.Status(200).JSON(base.ResponseMessage("User Logged Out Successfully"))
+	}
+}
+
+// @Summary Trigger A Password Reset
+// @Description `This endpoint emails the user a password reset otp, as if they had requested it themselves`
+// @Tags Admin
+// @Param id path string true "User ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/users/{id}/password-reset [post]
+// @Security BearerAuth
+func TriggerPasswordReset(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userObj, err := getTargetUser(db, c)
+		if err != nil {
+			return err
+		}
+		accounts.StartPasswordReset(db, c, userObj, adminEventDetails(c))
+		return c.Status(200).JSON(base.ResponseMessage("Password Reset Otp Sent"))
+	}
+}
+
+// categoryOrTagSlug validates the name of a new or renamed category/tag and returns its slug
+func categoryOrTagSlug(name string) (string, *config.ErrorResponse) {
+	slug := config.Slugify(name)
+	if slug == "" {
+		errData := config.ValidationErr("name", "Name must contain letters or numbers")
+		return "", &errData
+	}
+	return slug, nil
+}
+
+func getCategory(db *ent.Client, c *fiber.Ctx) (*ent.Category, error) {
+	categoryObj := courseManager.GetCategoryBySlug(db, c.Context(), c.Params("slug"))
+	if categoryObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Category Not Found"))
+	}
+	return categoryObj, nil
+}
+
+func getTag(db *ent.Client, c *fiber.Ctx) (*ent.Tag, error) {
+	tagObj := courseManager.GetTagBySlug(db, c.Context(), c.Params("slug"))
+	if tagObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Tag Not Found"))
+	}
+	return tagObj, nil
+}
+
+// @Summary Create A Category
+// @Description `This endpoint creates a course category. Its slug is generated from the name`
+// @Tags Admin
+// @Param data body CategoryOrTagCreateSchema true "Category"
+// @Success 201 {object} CategoryOrTagResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/categories [post]
+// @Security BearerAuth
+func CreateCategory(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := CategoryOrTagCreateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		slug, errData := categoryOrTagSlug(data.Name)
+		if errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
+		if courseManager.GetCategoryBySlug(db, ctx, slug) != nil {
+			return config.APIError(c, 422, config.ValidationErr("name", "A category with this name already exists"))
+		}
+
+		categoryObj := adminManager.CreateCategory(db, ctx, data.Name)
+		response := CategoryOrTagResponseSchema{
+			ResponseSchema: base.ResponseMessage("Category Created Successfully"),
+			Data:           courses.CategoryOrTagSchema{}.Assign(categoryObj, nil),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Rename A Category
+// @Description `This endpoint renames a category. Its slug is regenerated from the new name`
+// @Tags Admin
+// @Param slug path string true "Category Slug"
+// @Param data body CategoryOrTagCreateSchema true "Category"
+// @Success 200 {object} CategoryOrTagResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/categories/{slug} [put]
+// @Security BearerAuth
+func UpdateCategory(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		categoryObj, err := getCategory(db, c)
+		if err != nil {
+			return err
+		}
+		data := CategoryOrTagCreateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		slug, errData := categoryOrTagSlug(data.Name)
+		if errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
+		if existing := courseManager.GetCategoryBySlug(db, ctx, slug); existing != nil && existing.ID != categoryObj.ID {
+			return config.APIError(c, 422, config.ValidationErr("name", "A category with this name already exists"))
+		}
+
+		categoryObj = adminManager.RenameCategory(db, ctx, categoryObj, data.Name)
+		response := CategoryOrTagResponseSchema{
+			ResponseSchema: base.ResponseMessage("Category Updated Successfully"),
+			Data:           courses.CategoryOrTagSchema{}.Assign(categoryObj, nil),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Merge A Category Into Another
+// @Description `This endpoint moves every course of a category into the target category, then deletes it`
+// @Tags Admin
+// @Param slug path string true "Category Slug"
+// @Param data body CategoryOrTagMergeSchema true "Target category"
+// @Success 200 {object} CategoryOrTagResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/categories/{slug}/merge [post]
+// @Security BearerAuth
+func MergeCategory(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		categoryObj, err := getCategory(db, c)
+		if err != nil {
+			return err
+		}
+		data := CategoryOrTagMergeSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		target := courseManager.GetCategoryBySlug(db, ctx, data.TargetSlug)
+		if target == nil {
+			return config.APIError(c, 422, config.ValidationErr("target_slug", "Invalid category slug"))
+		}
+		if target.ID == categoryObj.ID {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "A category can't be merged into itself"))
+		}
+
+		adminManager.MergeCategories(db, ctx, categoryObj, target)
+		response := CategoryOrTagResponseSchema{
+			ResponseSchema: base.ResponseMessage("Categories Merged Successfully"),
+			Data:           courses.CategoryOrTagSchema{}.Assign(target, nil),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete A Category
+// @Description `This endpoint deletes a category. Categories that still have courses must be merged instead`
+// @Tags Admin
+// @Param slug path string true "Category Slug"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/categories/{slug} [delete]
+// @Security BearerAuth
+func DeleteCategory(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		categoryObj, err := getCategory(db, c)
+		if err != nil {
+			return err
+		}
+		if adminManager.CategoryHasCourses(db, ctx, categoryObj) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "This category still has courses. Merge it into another category instead"))
+		}
+		adminManager.DeleteCategory(db, ctx, categoryObj)
+		return c.Status(200).JSON(base.ResponseMessage("Category Deleted Successfully"))
+	}
+}
+
+// @Summary Create A Tag
+// @Description `This endpoint creates a course tag. Its slug is generated from the name`
+// @Tags Admin
+// @Param data body CategoryOrTagCreateSchema true "Tag"
+// @Success 201 {object} CategoryOrTagResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/tags [post]
+// @Security BearerAuth
+func CreateTag(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := CategoryOrTagCreateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		slug, errData := categoryOrTagSlug(data.Name)
+		if errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
+		if courseManager.GetTagBySlug(db, ctx, slug) != nil {
+			return config.APIError(c, 422, config.ValidationErr("name", "A tag with this name already exists"))
+		}
+
+		tagObj := adminManager.CreateTag(db, ctx, data.Name)
+		response := CategoryOrTagResponseSchema{
+			ResponseSchema: base.ResponseMessage("Tag Created Successfully"),
+			Data:           courses.CategoryOrTagSchema{}.Assign(nil, tagObj),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Rename A Tag
+// @Description `This endpoint renames a tag. Its slug is regenerated from the new name`
+// @Tags Admin
+// @Param slug path string true "Tag Slug"
+// @Param data body CategoryOrTagCreateSchema true "Tag"
+// @Success 200 {object} CategoryOrTagResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/tags/{slug} [put]
+// @Security BearerAuth
+func UpdateTag(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		tagObj, err := getTag(db, c)
+		if err != nil {
+			return err
+		}
+		data := CategoryOrTagCreateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		slug, errData := categoryOrTagSlug(data.Name)
+		if errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
+		if existing := courseManager.GetTagBySlug(db, ctx, slug); existing != nil && existing.ID != tagObj.ID {
+			return config.APIError(c, 422, config.ValidationErr("name", "A tag with this name already exists"))
+		}
+
+		tagObj = adminManager.RenameTag(db, ctx, tagObj, data.Name)
+		response := CategoryOrTagResponseSchema{
+			ResponseSchema: base.ResponseMessage("Tag Updated Successfully"),
+			Data:           courses.CategoryOrTagSchema{}.Assign(nil, tagObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Merge A Tag Into Another
+// @Description `This endpoint tags every course of a tag with the target tag, then deletes it`
+// @Tags Admin
+// @Param slug path string true "Tag Slug"
+// @Param data body CategoryOrTagMergeSchema true "Target tag"
+// @Success 200 {object} CategoryOrTagResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/tags/{slug}/merge [post]
+// @Security BearerAuth
+func MergeTag(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		tagObj, err := getTag(db, c)
+		if err != nil {
+			return err
+		}
+		data := CategoryOrTagMergeSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		target := courseManager.GetTagBySlug(db, ctx, data.TargetSlug)
+		if target == nil {
+			return config.APIError(c, 422, config.ValidationErr("target_slug", "Invalid tag slug"))
+		}
+		if target.ID == tagObj.ID {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "A tag can't be merged into itself"))
+		}
+
+		adminManager.MergeTags(db, ctx, tagObj, target)
+		response := CategoryOrTagResponseSchema{
+			ResponseSchema: base.ResponseMessage("Tags Merged Successfully"),
+			Data:           courses.CategoryOrTagSchema{}.Assign(nil, target),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete A Tag
+// @Description `This endpoint deletes a tag and removes it from every course`
+// @Tags Admin
+// @Param slug path string true "Tag Slug"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/tags/{slug} [delete]
+// @Security BearerAuth
+func DeleteTag(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tagObj, err := getTag(db, c)
+		if err != nil {
+			return err
+		}
+		adminManager.DeleteTag(db, c.Context(), tagObj)
+		return c.Status(200).JSON(base.ResponseMessage("Tag Deleted Successfully"))
+	}
+}
+
+// @Summary Retrieve Course Submissions
+// @Description `This endpoint retrieves the course review queue, oldest submission first so courses are reviewed in order`
+// @Tags Admin
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param status query string false "Filter By Status (submitted, approved or rejected)" default(submitted)
+// @Success 200 {object} courses.CoursesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/courses/submissions [get]
+// @Security BearerAuth
+func GetCourseSubmissions(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		status := course.Status(c.Query("status", string(course.StatusSubmitted)))
+		if status != course.StatusSubmitted && status != course.StatusApproved && status != course.StatusRejected {
+			return config.APIError(c, 400, config.InvalidParamErr("Invalid status"))
+		}
+		submissions := adminManager.GetCourseSubmissionsPaginated(db, c, status)
+		response := courses.CoursesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Course Submissions Fetched Successfully"),
+		}.Assign(submissions)
+		return c.Status(200).JSON(response)
+	}
+}
+
+func getSubmittedCourse(db *ent.Client, c *fiber.Ctx) (*ent.Course, error) {
+	courseObj := courseManager.GetCourseBySlug(db, c.Context(), c.Params("slug"), nil, true)
+	if courseObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
+	}
+	if courseObj.Status != course.StatusSubmitted {
+		return nil, config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Course is not awaiting review"))
+	}
+	return courseObj, nil
+}
+
+// @Summary Approve A Course
+// @Description `This endpoint approves a submitted course and notifies the instructor, who can then publish it. Courses that were live before going back to review are published again directly`
+// @Tags Admin
+// @Param slug path string true "Course Slug"
+// @Success 200 {object} courses.CourseResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/courses/{slug}/approve [post]
+// @Security BearerAuth
+func ApproveCourse(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		courseObj, err := getSubmittedCourse(db, c)
+		if err != nil {
+			return err
+		}
+		courseObj = adminManager.ApproveCourse(db, c.Context(), courseObj, base.RequestUser(c))
+		go config.SendMessageEmail(courseObj.Edges.Instructor, config.ET_COURSE_APPROVED, courseObj.Title)
+		response := courses.CourseResponseSchema{
+			ResponseSchema: base.ResponseMessage("Course Approved Successfully"),
+			Data:           courses.CourseDetailSchema{}.Assign(courseObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Reject A Course
+// @Description `This endpoint rejects a submitted course. The instructor is emailed the reason and can resubmit after addressing it`
+// @Tags Admin
+// @Param slug path string true "Course Slug"
+// @Param data body CourseRejectSchema true "Rejection reason"
+// @Success 200 {object} courses.CourseResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/courses/{slug}/reject [post]
+// @Security BearerAuth
+func RejectCourse(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		courseObj, err := getSubmittedCourse(db, c)
+		if err != nil {
+			return err
+		}
+		data := CourseRejectSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		courseObj = adminManager.RejectCourse(db, c.Context(), courseObj, base.RequestUser(c), data.Reason)
+		go config.SendMessageEmail(courseObj.Edges.Instructor, config.ET_COURSE_REJECTED, fmt.Sprintf("%s: %s", courseObj.Title, data.Reason))
+		response := courses.CourseResponseSchema{
+			ResponseSchema: base.ResponseMessage("Course Rejected Successfully"),
+			Data:           courses.CourseDetailSchema{}.Assign(courseObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Instructor Applications
+// @Description `This endpoint retrieves paginated instructor applications, oldest first so they are reviewed in order`
+// @Tags Admin
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param status query string false "Filter By Status (pending, approved or rejected)"
+// @Success 200 {object} instructors.InstructorApplicationsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/instructor-applications [get]
+// @Security BearerAuth
+func GetInstructorApplications(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		status := c.Query("status")
+		if status != "" && instructorapplication.StatusValidator(instructorapplication.Status(status)) != nil {
+			return config.APIError(c, 400, config.InvalidParamErr("Invalid status"))
+		}
+		applications := adminManager.GetInstructorApplicationsPaginated(db, c, status)
+		response := instructors.InstructorApplicationsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Applications Fetched Successfully"),
+		}.Assign(applications)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve An Instructor Application
+// @Description `This endpoint retrieves the details of an instructor application`
+// @Tags Admin
+// @Param id path string true "Application ID"
+// @Success 200 {object} instructors.InstructorApplicationResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/instructor-applications/{id} [get]
+// @Security BearerAuth
+func GetInstructorApplication(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		applicationID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		application := adminManager.GetInstructorApplication(db, c.Context(), *applicationID)
+		if application == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Application Not Found"))
+		}
+		response := instructors.InstructorApplicationResponseSchema{
+			ResponseSchema: base.ResponseMessage("Application Fetched Successfully"),
+			Data:           instructors.InstructorApplicationSchema{}.Assign(application),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+func getPendingApplication(db *ent.Client, c *fiber.Ctx) (*ent.InstructorApplication, error) {
+	applicationID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	application := adminManager.GetInstructorApplication(db, c.Context(), *applicationID)
+	if application == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Application Not Found"))
+	}
+	if application.Status != instructorapplication.StatusPending {
+		return nil, config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Application has already been reviewed"))
+	}
+	return application, nil
+}
+
+// @Summary Approve An Instructor Application
+// @Description `This endpoint approves an instructor application. The applicant becomes an instructor and is notified by email`
+// @Tags Admin
+// @Param id path string true "Application ID"
+// @Success 200 {object} instructors.InstructorApplicationResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/instructor-applications/{id}/approve [post]
+// @Security BearerAuth
+func ApproveInstructorApplication(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		application, err := getPendingApplication(db, c)
+		if application == nil {
+			return err
+		}
+		application = adminManager.ApproveInstructorApplication(db, c.Context(), application, base.RequestUser(c))
+		go config.SendEmail(application.Edges.User, config.ET_INSTRUCTOR_APP_APPR, nil)
+		response := instructors.InstructorApplicationResponseSchema{
+			ResponseSchema: base.ResponseMessage("Application Approved Successfully"),
+			Data:           instructors.InstructorApplicationSchema{}.Assign(application),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Reject An Instructor Application
+// @Description `This endpoint rejects an instructor application with a reason, which is emailed to the applicant`
+// @Tags Admin
+// @Param id path string true "Application ID"
+// @Param data body ApplicationRejectSchema true "Rejection reason"
+// @Success 200 {object} instructors.InstructorApplicationResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/instructor-applications/{id}/reject [post]
+// @Security BearerAuth
+func RejectInstructorApplication(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		application, err := getPendingApplication(db, c)
+		if application == nil {
+			return err
+		}
+		data := ApplicationRejectSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		application = adminManager.RejectInstructorApplication(db, c.Context(), application, base.RequestUser(c), data.Reason)
+		go config.SendMessageEmail(application.Edges.User, config.ET_INSTRUCTOR_APP_REJ, data.Reason)
+		response := instructors.InstructorApplicationResponseSchema{
+			ResponseSchema: base.ResponseMessage("Application Rejected Successfully"),
+			Data:           instructors.InstructorApplicationSchema{}.Assign(application),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Update Site Detail
+// @Description `This endpoint updates the site details and platform settings (support email, logo, currency, limits)`
+// @Description `Changes apply immediately on this instance and within a minute on the others`
+// @Tags Admin
+// @Param data formData SiteDetailUpdateSchema true "Site detail object"
+// @Param logo formData file false "Site logo to upload"
+// @Success 200 {object} general.SiteDetailResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/site-detail [put]
+// @Security BearerAuth
+func UpdateSiteDetail(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		data := SiteDetailUpdateSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateFormRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		// Check and validate logo
+		file, err := config.ValidateImage(c, "logo", false)
+		if err != nil {
+			return c.Status(422).JSON(err)
+		}
+		var logo *string
+		if file != nil {
+			logoStr := config.UploadFile(file, string(config.FF_LOGOS))
+			logo = &logoStr
+		}
+
+		sitedetail := general.SiteDetailService{}.GetOrCreate(db, ctx)
+		adminManager.UpdateSiteDetail(db, ctx, sitedetail, data, logo)
+		sitedetail = general.LoadSettings(db, ctx)
+		response := general.SiteDetailResponseSchema{
+			ResponseSchema: base.ResponseMessage("Site Details Updated Successfully"),
+			Data:           general.SiteDetailSchema{}.Init(sitedetail),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Platform Analytics
+// @Description `This endpoint aggregates signups, enrollments, completions and revenue into day, week or month buckets`
+// @Description `Only successful enrollments and payments are counted. Weeks start on Monday and all dates are in UTC`
+// @Tags Admin
+// @Param start query string false "Start Date (YYYY-MM-DD). Defaults to 29 days before the end date"
+// @Param end query string false "End Date, inclusive (YYYY-MM-DD). Defaults to today"
+// @Param interval query string false "Bucket Size (day, week or month)" default(day)
+// @Success 200 {object} AnalyticsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/analytics [get]
+// @Security BearerAuth
+func GetAnalytics(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		analytics, errData := parseAnalyticsRange(c)
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		buckets := adminManager.GetAnalyticsBuckets(db, ctx, *analytics)
+		response := AnalyticsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Analytics Fetched Successfully"),
+			Data:           AnalyticsSchema{}.Assign(*analytics, general.Settings(db, ctx).DefaultCurrency, buckets),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Top Courses
+// @Description `This endpoint ranks courses by successful enrollments or by the average rating of reviews left within the date range`
+// @Tags Admin
+// @Param start query string false "Start Date (YYYY-MM-DD). Defaults to 29 days before the end date"
+// @Param end query string false "End Date, inclusive (YYYY-MM-DD). Defaults to today"
+// @Param sort_by query string false "Rank By (enrollments or rating)" default(enrollments)
+// @Param limit query int false "Number Of Courses (max 50)" default(10)
+// @Success 200 {object} TopCoursesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/analytics/top-courses [get]
+// @Security BearerAuth
+func GetTopCourses(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		analytics, errData := parseAnalyticsRange(c)
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		sortBy := c.Query("sort_by", TOP_BY_ENROLLMENTS)
+		if sortBy != TOP_BY_ENROLLMENTS && sortBy != TOP_BY_RATING {
+			return config.APIError(c, 400, config.InvalidParamErr("Invalid sort_by. Use enrollments or rating"))
+		}
+		limit := c.QueryInt("limit", 10)
+		if limit < 1 || limit > 50 {
+			return config.APIError(c, 400, config.InvalidParamErr("Limit must be between 1 and 50"))
+		}
+		topCourses := adminManager.GetTopCourses(db, c.Context(), *analytics, sortBy, limit)
+		response := TopCoursesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Top Courses Fetched Successfully"),
+			Data:           topCourses,
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Reported Reviews
+// @Description `This endpoint retrieves the review moderation queue: visible reviews with unresolved reports, most reported first`
+// @Description `Use hidden=true to list the reviews hidden by moderators instead`
+// @Tags Admin
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param hidden query bool false "List Hidden Reviews" default(false)
+// @Success 200 {object} AdminReviewsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/reviews [get]
+// @Security BearerAuth
+func GetModeratedReviews(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviews := adminManager.GetModeratedReviewsPaginated(db, c, c.QueryBool("hidden"))
+		response := AdminReviewsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Reviews Fetched Successfully"),
+		}.Assign(reviews)
+		return c.Status(200).JSON(response)
+	}
+}
+
+func getModeratedReview(db *ent.Client, c *fiber.Ctx) (*ent.Review, error) {
+	reviewID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	reviewObj := adminManager.GetReview(db, c.Context(), *reviewID)
+	if reviewObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
+	}
+	return reviewObj, nil
+}
+
+// @Summary Retrieve Review Reports
+// @Description `This endpoint retrieves every report made on a review, resolved ones included`
+// @Tags Admin
+// @Param id path string true "Review ID"
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} ReviewReportsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/reviews/{id}/reports [get]
+// @Security BearerAuth
+func GetReviewReports(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviewObj, err := getModeratedReview(db, c)
+		if err != nil {
+			return err
+		}
+		reports := adminManager.GetReviewReportsPaginated(db, c, reviewObj)
+		response := ReviewReportsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Review Reports Fetched Successfully"),
+		}.Assign(reports)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Hide A Review
+// @Description `This endpoint hides a review from listings and course ratings, and resolves its open reports`
+// @Tags Admin
+// @Param id path string true "Review ID"
+// @Param data body ReviewHideSchema true "Hide reason"
+// @Success 200 {object} AdminReviewResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/reviews/{id}/hide [post]
+// @Security BearerAuth
+func HideReview(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviewObj, err := getModeratedReview(db, c)
+		if err != nil {
+			return err
+		}
+		if reviewObj.IsHidden {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Review is already hidden"))
+		}
+		data := ReviewHideSchema{}
+
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		reviewObj = adminManager.HideReview(db, c.Context(), reviewObj, data.Reason)
+		response := AdminReviewResponseSchema{
+			ResponseSchema: base.ResponseMessage("Review Hidden Successfully"),
+			Data:           AdminReviewSchema{}.Assign(reviewObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Restore A Review
+// @Description `This endpoint makes a hidden review visible again. On a visible review, it dismisses the open reports`
+// @Tags Admin
+// @Param id path string true "Review ID"
+// @Success 200 {object} AdminReviewResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /admin/reviews/{id}/restore [post]
+// @Security BearerAuth
+func RestoreReview(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		reviewObj, err := getModeratedReview(db, c)
+		if err != nil {
+			return err
+		}
+		if !reviewObj.IsHidden && reviewObj.ReportsCount == 0 {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Review is neither hidden nor reported"))
+		}
+		reviewObj = adminManager.RestoreReview(db, c.Context(), reviewObj)
+		response := AdminReviewResponseSchema{
+			ResponseSchema: base.ResponseMessage("Review Restored Successfully"),
+			Data:           AdminReviewSchema{}.Assign(reviewObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
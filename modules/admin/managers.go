@@ -2,14 +2,593 @@ package admin
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"entgo.io/ent/dialect/sql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/accountlockout"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/authevent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/instructorapplication"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/payment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/review"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewreport"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/role"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/tag"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
 )
 
 type AdminManager struct{}
 
+// ----------------------------------
+// CATEGORIES & TAGS
+// --------------------------------
 func (a AdminManager) CreateCategory (db *ent.Client, ctx context.Context, name string) *ent.Category {
 	category := db.Category.Create().SetName(name).SetSlug(config.Slugify(name)).SaveX(ctx)
 	return category
-}
\ No newline at end of file
+}
+
+func (a AdminManager) RenameCategory(db *ent.Client, ctx context.Context, categoryObj *ent.Category, name string) *ent.Category {
+	return categoryObj.Update().SetName(name).SetSlug(config.Slugify(name)).SaveX(ctx)
+}
+
+func (a AdminManager) CategoryHasCourses(db *ent.Client, ctx context.Context, categoryObj *ent.Category) bool {
+	return db.Course.Query().Where(course.CategoryID(categoryObj.ID)).ExistX(ctx)
+}
+
+// MergeCategories moves every course of source into target, then deletes source
+func (a AdminManager) MergeCategories(db *ent.Client, ctx context.Context, source *ent.Category, target *ent.Category) {
+	db.Course.Update().Where(course.CategoryID(source.ID)).SetCategoryID(target.ID).ExecX(ctx)
+	db.Category.DeleteOne(source).ExecX(ctx)
+}
+
+func (a AdminManager) DeleteCategory(db *ent.Client, ctx context.Context, categoryObj *ent.Category) {
+	db.Category.DeleteOne(categoryObj).ExecX(ctx)
+}
+
+func (a AdminManager) CreateTag(db *ent.Client, ctx context.Context, name string) *ent.Tag {
+	return db.Tag.Create().SetName(name).SetSlug(config.Slugify(name)).SaveX(ctx)
+}
+
+func (a AdminManager) RenameTag(db *ent.Client, ctx context.Context, tagObj *ent.Tag, name string) *ent.Tag {
+	return tagObj.Update().SetName(name).SetSlug(config.Slugify(name)).SaveX(ctx)
+}
+
+// MergeTags tags every course of source with target, then deletes source
+func (a AdminManager) MergeTags(db *ent.Client, ctx context.Context, source *ent.Tag, target *ent.Tag) {
+	courseIDs := db.Course.Query().
+		Where(course.HasTagsWith(tag.ID(source.ID)), course.Not(course.HasTagsWith(tag.ID(target.ID)))).
+		IDsX(ctx)
+	if len(courseIDs) > 0 {
+		target.Update().AddCourseIDs(courseIDs...).ExecX(ctx)
+	}
+	db.Tag.DeleteOne(source).ExecX(ctx)
+}
+
+// DeleteTag deletes a tag. Courses simply lose it
+func (a AdminManager) DeleteTag(db *ent.Client, ctx context.Context, tagObj *ent.Tag) {
+	db.Tag.DeleteOne(tagObj).ExecX(ctx)
+}
+
+func (a AdminManager) GetLockoutsPaginated(db *ent.Client, fibCtx *fiber.Ctx, activeOnly bool) *config.PaginationResponse[*ent.AccountLockout] {
+	query := db.AccountLockout.Query().
+		WithUser().
+		Order(ent.Desc(accountlockout.FieldCreatedAt))
+	if activeOnly {
+		query = query.Where(accountlockout.UnlockedAtIsNil(), accountlockout.LockedUntilGT(time.Now()))
+	}
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (a AdminManager) GetAuthEventsPaginated(db *ent.Client, fibCtx *fiber.Ctx, userID *uuid.UUID, event string, ip string) *config.PaginationResponse[*ent.AuthEvent] {
+	query := db.AuthEvent.Query().
+		WithUser().
+		Order(ent.Desc(authevent.FieldCreatedAt))
+	if userID != nil {
+		query = query.Where(authevent.UserID(*userID))
+	}
+	if event != "" {
+		query = query.Where(authevent.Event(event))
+	}
+	if ip != "" {
+		query = query.Where(authevent.IP(ip))
+	}
+	return config.PaginateModel(fibCtx, query)
+}
+
+// ----------------------------------
+// COURSE MODERATION
+// --------------------------------
+// EnsureCourseStatuses marks courses published before moderation existed as published, so they stay consistent with is_published
+func (a AdminManager) EnsureCourseStatuses(db *ent.Client, ctx context.Context) {
+	db.Course.Update().
+		Where(course.IsPublishedEQ(true), course.StatusNEQ(course.StatusPublished)).
+		SetStatus(course.StatusPublished).
+		SetPublishedAt(time.Now()).
+		ExecX(ctx)
+}
+
+// GetCourseSubmissionsPaginated returns courses in a moderation status, oldest submission first so they are reviewed in order
+func (a AdminManager) GetCourseSubmissionsPaginated(db *ent.Client, fibCtx *fiber.Ctx, status course.Status) *config.PaginationResponse[*ent.Course] {
+	query := db.Course.Query().
+		Where(course.StatusEQ(status)).
+		WithInstructor().
+		WithCategory().
+		WithTags().
+		WithReviews(courses.VisibleReviews).
+		WithEnrollments().
+		WithLessons().
+		Order(ent.Asc(course.FieldSubmittedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+// ApproveCourse approves a submitted course. A course that was live when major edits sent it back for review goes live again
+func (a AdminManager) ApproveCourse(db *ent.Client, ctx context.Context, courseObj *ent.Course, reviewer *ent.User) *ent.Course {
+	updateQuery := courseObj.Update().
+		SetStatus(course.StatusApproved).
+		ClearRejectionReason().
+		SetReviewedBy(reviewer.ID).
+		SetReviewedAt(time.Now()).
+		SetRepublishOnApproval(false)
+	if courseObj.RepublishOnApproval {
+		updateQuery = updateQuery.SetStatus(course.StatusPublished).SetIsPublished(true)
+	}
+	updatedCourse := updateQuery.SaveX(ctx)
+	updatedCourse.Edges = courseObj.Edges
+	return updatedCourse
+}
+
+func (a AdminManager) RejectCourse(db *ent.Client, ctx context.Context, courseObj *ent.Course, reviewer *ent.User, reason string) *ent.Course {
+	updatedCourse := courseObj.Update().
+		SetStatus(course.StatusRejected).
+		SetIsPublished(false).
+		SetRejectionReason(reason).
+		SetReviewedBy(reviewer.ID).
+		SetReviewedAt(time.Now()).
+		SaveX(ctx)
+	updatedCourse.Edges = courseObj.Edges
+	return updatedCourse
+}
+
+// ----------------------------------
+// ROLES
+// --------------------------------
+func (a AdminManager) GetRoles(db *ent.Client, ctx context.Context) []*ent.Role {
+	return db.Role.Query().Order(ent.Desc(role.FieldIsSystem), ent.Asc(role.FieldName)).AllX(ctx)
+}
+
+func (a AdminManager) GetRoleByID(db *ent.Client, ctx context.Context, id uuid.UUID) *ent.Role {
+	r, _ := db.Role.Get(ctx, id)
+	return r
+}
+
+func (a AdminManager) GetRoleByName(db *ent.Client, ctx context.Context, name string) *ent.Role {
+	r, _ := db.Role.Query().Where(role.Name(name)).Only(ctx)
+	return r
+}
+
+func (a AdminManager) CreateRole(db *ent.Client, ctx context.Context, data RoleCreateSchema) *ent.Role {
+	return db.Role.Create().
+		SetName(data.Name).
+		SetDescription(data.Description).
+		SetPermissions(data.Permissions).
+		SaveX(ctx)
+}
+
+func (a AdminManager) UpdateRole(db *ent.Client, ctx context.Context, roleObj *ent.Role, data RoleUpdateSchema) *ent.Role {
+	update := roleObj.Update()
+	if data.Description != nil {
+		update = update.SetDescription(*data.Description)
+	}
+	if data.Permissions != nil {
+		update = update.SetPermissions(data.Permissions)
+	}
+	return update.SaveX(ctx)
+}
+
+// EnsureSystemRoles creates the roles backing the user role field if they do not exist yet.
+// Existing ones get any of their default permissions they're missing, so permissions added in later releases
+// reach deployed databases. Permissions granted on top of the defaults are kept.
+func (a AdminManager) EnsureSystemRoles(db *ent.Client, ctx context.Context) {
+	for _, def := range accounts.SystemRoles {
+		roleObj := a.GetRoleByName(db, ctx, def.Name)
+		if roleObj == nil {
+			db.Role.Create().
+				SetName(def.Name).
+				SetDescription(def.Description).
+				SetPermissions(def.Permissions).
+				SetIsSystem(true).
+				SaveX(ctx)
+			continue
+		}
+		missing := make([]string, 0)
+		for _, permission := range def.Permissions {
+			if !slices.Contains(roleObj.Permissions, permission) {
+				missing = append(missing, permission)
+			}
+		}
+		if len(missing) > 0 {
+			roleObj.Update().AppendPermissions(missing).ExecX(ctx)
+		}
+	}
+}
+
+func (a AdminManager) AssignRole(db *ent.Client, ctx context.Context, userObj *ent.User, roleObj *ent.Role) {
+	userObj.Update().AddRoles(roleObj).ExecX(ctx)
+}
+
+func (a AdminManager) RemoveRole(db *ent.Client, ctx context.Context, userObj *ent.User, roleObj *ent.Role) {
+	userObj.Update().RemoveRoles(roleObj).ExecX(ctx)
+}
+
+func (a AdminManager) GetUserByID(db *ent.Client, ctx context.Context, id uuid.UUID) *ent.User {
+	u, _ := db.User.Get(ctx, id)
+	return u
+}
+
+// ----------------------------------
+// USERS
+// --------------------------------
+func (a AdminManager) GetUsersPaginated(db *ent.Client, fibCtx *fiber.Ctx, search string, role *user.Role, isActive *bool) *config.PaginationResponse[*ent.User] {
+	query := db.User.Query().
+		Where(user.DeletedAtIsNil()).
+		Order(ent.Desc(user.FieldCreatedAt))
+	if search != "" {
+		query = query.Where(user.Or(user.NameContainsFold(search), user.UsernameContainsFold(search), user.EmailContainsFold(search)))
+	}
+	if role != nil {
+		query = query.Where(user.RoleEQ(*role))
+	}
+	if isActive != nil {
+		query = query.Where(user.IsActive(*isActive))
+	}
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (a AdminManager) GetUserEnrollmentsPaginated(db *ent.Client, fibCtx *fiber.Ctx, userID uuid.UUID) *config.PaginationResponse[*ent.Enrollment] {
+	query := db.Enrollment.Query().
+		Where(enrollment.UserID(userID)).
+		WithCourse().
+		Order(ent.Desc(enrollment.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (a AdminManager) GetUserPaymentsPaginated(db *ent.Client, fibCtx *fiber.Ctx, userID uuid.UUID) *config.PaginationResponse[*ent.Payment] {
+	query := db.Payment.Query().
+		Where(payment.UserID(userID)).
+		WithCourse().
+		Order(ent.Desc(payment.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+// SetUserActive deactivates or reactivates an account. Deactivation also ends every session of the user
+func (a AdminManager) SetUserActive(db *ent.Client, ctx context.Context, userObj *ent.User, isActive bool) *ent.User {
+	userObj = userObj.Update().SetIsActive(isActive).SaveX(ctx)
+	if !isActive {
+		userManager.ClearTokens(db, ctx, userObj.ID)
+	}
+	return userObj
+}
+
+func (a AdminManager) SetUserRole(db *ent.Client, ctx context.Context, userObj *ent.User, role user.Role) *ent.User {
+	return userObj.Update().SetRole(role).SaveX(ctx)
+}
+
+// ----------------------------------
+// INSTRUCTOR APPLICATIONS
+// --------------------------------
+func (a AdminManager) GetInstructorApplicationsPaginated(db *ent.Client, fibCtx *fiber.Ctx, status string) *config.PaginationResponse[*ent.InstructorApplication] {
+	query := db.InstructorApplication.Query().
+		WithUser().
+		Order(ent.Asc(instructorapplication.FieldCreatedAt))
+	if status != "" {
+		query = query.Where(instructorapplication.StatusEQ(instructorapplication.Status(status)))
+	}
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (a AdminManager) GetInstructorApplication(db *ent.Client, ctx context.Context, id uuid.UUID) *ent.InstructorApplication {
+	application, _ := db.InstructorApplication.Query().
+		Where(instructorapplication.ID(id)).
+		WithUser().
+		Only(ctx)
+	return application
+}
+
+// ApproveInstructorApplication marks the application approved and makes the applicant an instructor
+func (a AdminManager) ApproveInstructorApplication(db *ent.Client, ctx context.Context, application *ent.InstructorApplication, reviewer *ent.User) *ent.InstructorApplication {
+	applicant := application.Edges.User
+	updatedApplication := application.Update().
+		SetStatus(instructorapplication.StatusApproved).
+		SetReviewedBy(reviewer.ID).
+		SetReviewedAt(time.Now()).
+		SaveX(ctx)
+	if applicant.Role == user.RoleStudent {
+		applicant = applicant.Update().SetRole(user.RoleInstructor).SaveX(ctx)
+	}
+	updatedApplication.Edges.User = applicant
+	return updatedApplication
+}
+
+func (a AdminManager) RejectInstructorApplication(db *ent.Client, ctx context.Context, application *ent.InstructorApplication, reviewer *ent.User, reason string) *ent.InstructorApplication {
+	updatedApplication := application.Update().
+		SetStatus(instructorapplication.StatusRejected).
+		SetRejectionReason(reason).
+		SetReviewedBy(reviewer.ID).
+		SetReviewedAt(time.Now()).
+		SaveX(ctx)
+	updatedApplication.Edges.User = application.Edges.User
+	return updatedApplication
+}
+
+// ----------------------------------
+// SITE DETAIL
+// --------------------------------
+func (a AdminManager) UpdateSiteDetail(db *ent.Client, ctx context.Context, sitedetail *ent.SiteDetail, data SiteDetailUpdateSchema, logo *string) *ent.SiteDetail {
+	update := sitedetail.Update().
+		SetName(data.Name).
+		SetEmail(data.Email).
+		SetPhone(data.Phone).
+		SetAddress(data.Address).
+		SetFb(data.Fb).
+		SetTw(data.Tw).
+		SetWh(data.Wh).
+		SetIg(data.Ig).
+		SetSupportEmail(data.SupportEmail).
+		SetDefaultCurrency(strings.ToLower(data.DefaultCurrency)).
+		SetAiSummaryDailyLimit(data.AiSummaryDailyLimit).
+		SetMaxUploadSizeMB(data.MaxUploadSizeMB)
+	if logo != nil {
+		update = update.SetLogo(*logo)
+	}
+	return update.SaveX(ctx)
+}
+
+// ----------------------------------
+// ANALYTICS
+// --------------------------------
+
+// A count or sum per date_trunc bucket of the range, grouped in the database with bucketBy
+type analyticsBucketRow struct {
+	Bucket time.Time `json:"bucket"`
+	Count  int       `json:"count"`
+	Sum    float64   `json:"sum"`
+}
+
+// bucketBy groups a query by the (UTC) analytics bucket of a timestamp column and selects it as bucket.
+// Buckets follow bucketStart, since postgres weeks start on Monday too
+func bucketBy(field string, interval string) ent.AggregateFunc {
+	return func(s *sql.Selector) string {
+		bucket := fmt.Sprintf("date_trunc('%s', %s AT TIME ZONE 'UTC')", interval, s.C(field))
+		s.GroupBy(bucket)
+		return sql.As(bucket, "bucket")
+	}
+}
+
+type courseStatRow struct {
+	CourseID uuid.UUID `json:"course_id"`
+	Count    int       `json:"count"`
+	Sum      float64   `json:"sum"`
+	Mean     float64   `json:"mean"`
+}
+
+// GetAnalyticsBuckets counts signups, paid/free enrollments, completions and successful payments per bucket of the range
+func (a AdminManager) GetAnalyticsBuckets(db *ent.Client, ctx context.Context, analytics analyticsRange) []AnalyticsBucketSchema {
+	buckets := []AnalyticsBucketSchema{}
+	indexes := map[int64]int{}
+	for t := bucketStart(analytics.Start, analytics.Interval); t.Before(analytics.End); t = nextBucket(t, analytics.Interval) {
+		indexes[t.Unix()] = len(buckets)
+		buckets = append(buckets, AnalyticsBucketSchema{Start: t})
+	}
+	// Only the per bucket counts and sums leave the database
+	eachBucket := func(rows []analyticsBucketRow, add func(bucket *AnalyticsBucketSchema, row analyticsBucketRow)) {
+		for _, row := range rows {
+			if i, ok := indexes[row.Bucket.Unix()]; ok {
+				add(&buckets[i], row)
+			}
+		}
+	}
+
+	signups := []analyticsBucketRow{}
+	db.User.Query().
+		Where(user.CreatedAtGTE(analytics.Start), user.CreatedAtLT(analytics.End)).
+		Aggregate(bucketBy(user.FieldCreatedAt, analytics.Interval), ent.Count()).
+		ScanX(ctx, &signups)
+	eachBucket(signups, func(bucket *AnalyticsBucketSchema, row analyticsBucketRow) { bucket.Signups = row.Count })
+
+	enrollments := []analyticsBucketRow{}
+	db.Enrollment.Query().
+		Where(
+			enrollment.PaymentStatusEQ(enrollment.PaymentStatusSuccessful),
+			enrollment.CreatedAtGTE(analytics.Start), enrollment.CreatedAtLT(analytics.End),
+		).
+		Aggregate(bucketBy(enrollment.FieldCreatedAt, analytics.Interval), ent.Count()).
+		ScanX(ctx, &enrollments)
+	eachBucket(enrollments, func(bucket *AnalyticsBucketSchema, row analyticsBucketRow) { bucket.Enrollments = row.Count })
+
+	completions := []analyticsBucketRow{}
+	db.Enrollment.Query().
+		Where(enrollment.CompletedAtGTE(analytics.Start), enrollment.CompletedAtLT(analytics.End)).
+		Aggregate(bucketBy(enrollment.FieldCompletedAt, analytics.Interval), ent.Count()).
+		ScanX(ctx, &completions)
+	eachBucket(completions, func(bucket *AnalyticsBucketSchema, row analyticsBucketRow) { bucket.Completions = row.Count })
+
+	payments := []analyticsBucketRow{}
+	db.Payment.Query().
+		Where(
+			payment.StatusEQ(payment.StatusSuccessful),
+			payment.CreatedAtGTE(analytics.Start), payment.CreatedAtLT(analytics.End),
+		).
+		Aggregate(bucketBy(payment.FieldCreatedAt, analytics.Interval), ent.Sum(payment.FieldAmount)).
+		ScanX(ctx, &payments)
+	eachBucket(payments, func(bucket *AnalyticsBucketSchema, row analyticsBucketRow) { bucket.Revenue = row.Sum })
+
+	for i := range buckets {
+		buckets[i].Revenue = math.Round(buckets[i].Revenue*100) / 100
+	}
+	return buckets
+}
+
+// GetTopCourses ranks courses by their enrollments or by the rating of the reviews they got within the range
+func (a AdminManager) GetTopCourses(db *ent.Client, ctx context.Context, analytics analyticsRange, sortBy string, limit int) []TopCourseSchema {
+	stats := map[uuid.UUID]*TopCourseSchema{}
+	statsFor := func(courseID uuid.UUID) *TopCourseSchema {
+		if _, ok := stats[courseID]; !ok {
+			stats[courseID] = &TopCourseSchema{Course: UserCourseSchema{ID: courseID}}
+		}
+		return stats[courseID]
+	}
+
+	enrollmentCounts := []courseStatRow{}
+	db.Enrollment.Query().
+		Where(
+			enrollment.PaymentStatusEQ(enrollment.PaymentStatusSuccessful),
+			enrollment.CreatedAtGTE(analytics.Start), enrollment.CreatedAtLT(analytics.End),
+		).
+		GroupBy(enrollment.FieldCourseID).
+		Aggregate(ent.Count()).
+		ScanX(ctx, &enrollmentCounts)
+	for _, row := range enrollmentCounts {
+		statsFor(row.CourseID).Enrollments = row.Count
+	}
+
+	revenues := []courseStatRow{}
+	db.Payment.Query().
+		Where(
+			payment.StatusEQ(payment.StatusSuccessful),
+			payment.CreatedAtGTE(analytics.Start), payment.CreatedAtLT(analytics.End),
+		).
+		GroupBy(payment.FieldCourseID).
+		Aggregate(ent.Sum(payment.FieldAmount)).
+		ScanX(ctx, &revenues)
+	for _, row := range revenues {
+		statsFor(row.CourseID).Revenue = math.Round(row.Sum*100) / 100
+	}
+
+	ratings := []courseStatRow{}
+	db.Review.Query().
+		Where(review.CreatedAtGTE(analytics.Start), review.CreatedAtLT(analytics.End)).
+		GroupBy(review.FieldCourseID).
+		Aggregate(ent.Count(), ent.As(ent.Mean(review.FieldRating), "mean")).
+		ScanX(ctx, &ratings)
+	for _, row := range ratings {
+		courseStats := statsFor(row.CourseID)
+		courseStats.ReviewsCount = row.Count
+		courseStats.Rating = math.Round(row.Mean*100) / 100
+	}
+
+	topCourses := []*TopCourseSchema{}
+	for _, courseStats := range stats {
+		if (sortBy == TOP_BY_RATING && courseStats.ReviewsCount > 0) || (sortBy == TOP_BY_ENROLLMENTS && courseStats.Enrollments > 0) {
+			topCourses = append(topCourses, courseStats)
+		}
+	}
+	sort.Slice(topCourses, func(i, j int) bool {
+		x, y := topCourses[i], topCourses[j]
+		if sortBy == TOP_BY_RATING {
+			if x.Rating != y.Rating {
+				return x.Rating > y.Rating
+			}
+			return x.ReviewsCount > y.ReviewsCount
+		}
+		if x.Enrollments != y.Enrollments {
+			return x.Enrollments > y.Enrollments
+		}
+		return x.Revenue > y.Revenue
+	})
+	if len(topCourses) > limit {
+		topCourses = topCourses[:limit]
+	}
+
+	courseIDs := make([]uuid.UUID, 0, len(topCourses))
+	for _, courseStats := range topCourses {
+		courseIDs = append(courseIDs, courseStats.Course.ID)
+	}
+	coursesByID := map[uuid.UUID]*ent.Course{}
+	for _, courseObj := range db.Course.Query().Where(course.IDIn(courseIDs...)).AllX(ctx) {
+		coursesByID[courseObj.ID] = courseObj
+	}
+	result := make([]TopCourseSchema, 0, len(topCourses))
+	for _, courseStats := range topCourses {
+		courseObj, ok := coursesByID[courseStats.Course.ID]
+		if !ok {
+			continue
+		}
+		courseStats.Course = courseStats.Course.Assign(courseObj)
+		result = append(result, *courseStats)
+	}
+	return result
+}
+
+// ----------------------------------
+// REVIEW MODERATION
+// --------------------------------
+
+// GetModeratedReviewsPaginated lists hidden reviews, or visible ones with unresolved reports (most reported first)
+func (a AdminManager) GetModeratedReviewsPaginated(db *ent.Client, fibCtx *fiber.Ctx, hidden bool) *config.PaginationResponse[*ent.Review] {
+	query := db.Review.Query().WithUser().WithCourse()
+	if hidden {
+		query = query.Where(review.IsHidden(true)).Order(ent.Desc(review.FieldHiddenAt))
+	} else {
+		query = query.Where(review.IsHidden(false), review.ReportsCountGT(0)).
+			Order(ent.Desc(review.FieldReportsCount), ent.Asc(review.FieldCreatedAt))
+	}
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (a AdminManager) GetReview(db *ent.Client, ctx context.Context, reviewID uuid.UUID) *ent.Review {
+	reviewObj, _ := db.Review.Query().Where(review.ID(reviewID)).WithUser().WithCourse().Only(ctx)
+	return reviewObj
+}
+
+func (a AdminManager) GetReviewReportsPaginated(db *ent.Client, fibCtx *fiber.Ctx, reviewObj *ent.Review) *config.PaginationResponse[*ent.ReviewReport] {
+	query := db.ReviewReport.Query().
+		Where(reviewreport.ReviewID(reviewObj.ID)).
+		WithUser().
+		Order(ent.Desc(reviewreport.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+// resolveReviewReports closes the open reports of a review once a moderator has acted on it
+func (a AdminManager) resolveReviewReports(db *ent.Client, ctx context.Context, reviewObj *ent.Review) {
+	db.ReviewReport.Update().
+		Where(reviewreport.ReviewID(reviewObj.ID), reviewreport.ResolvedAtIsNil()).
+		SetResolvedAt(time.Now()).
+		ExecX(ctx)
+}
+
+func (a AdminManager) HideReview(db *ent.Client, ctx context.Context, reviewObj *ent.Review, reason string) *ent.Review {
+	a.resolveReviewReports(db, ctx, reviewObj)
+	updatedReview := reviewObj.Update().
+		SetIsHidden(true).
+		SetHiddenReason(reason).
+		SetHiddenAt(time.Now()).
+		SetReportsCount(0).
+		SaveX(ctx)
+	updatedReview.Edges = reviewObj.Edges
+	return updatedReview
+}
+
+// RestoreReview makes a hidden review visible again. On a visible review it dismisses the open reports
+func (a AdminManager) RestoreReview(db *ent.Client, ctx context.Context, reviewObj *ent.Review) *ent.Review {
+	a.resolveReviewReports(db, ctx, reviewObj)
+	updatedReview := reviewObj.Update().
+		SetIsHidden(false).
+		ClearHiddenReason().
+		ClearHiddenAt().
+		SetReportsCount(0).
+		SaveX(ctx)
+	updatedReview.Edges = reviewObj.Edges
+	return updatedReview
+}
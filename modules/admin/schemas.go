@@ -0,0 +1,485 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/payment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewreport"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
+)
+
+// REQUEST BODY SCHEMAS
+type RoleCreateSchema struct {
+	Name        string   `json:"name" validate:"required,max=50" example:"teaching_assistant"`
+	Description string   `json:"description" validate:"max=200" example:"Helps instructors with lessons and quizzes"`
+	Permissions []string `json:"permissions" validate:"required" example:"course.read,lesson.update"`
+}
+
+type RoleUpdateSchema struct {
+	Description *string  `json:"description" validate:"omitempty,max=200" example:"Helps instructors with lessons and quizzes"`
+	Permissions []string `json:"permissions" example:"course.read,lesson.update,quiz.*"`
+}
+
+type UserRoleSchema struct {
+	Role string `json:"role" validate:"required" example:"support"`
+}
+
+type UserBaseRoleSchema struct {
+	Role user.Role `json:"role" validate:"required,user_role_type_validator" example:"instructor"`
+}
+
+type CategoryOrTagCreateSchema struct {
+	Name string `json:"name" validate:"required,max=100" example:"Web Development"`
+}
+
+type CategoryOrTagMergeSchema struct {
+	TargetSlug string `json:"target_slug" validate:"required" example:"web-development"`
+}
+
+type SiteDetailUpdateSchema struct {
+	Name                string `form:"name" validate:"required,max=100" example:"EDNET"`
+	Email               string `form:"email" validate:"required,email" example:"johndoe@email.com"`
+	Phone               string `form:"phone" validate:"required,max=20" example:"+2348133831036"`
+	Address             string `form:"address" validate:"required,max=300" example:"234, Lagos, Nigeria"`
+	Fb                  string `form:"fb" validate:"required,url" example:"https://facebook.com"`
+	Tw                  string `form:"tw" validate:"required,url" example:"https://twitter.com"`
+	Wh                  string `form:"wh" validate:"required,url" example:"https://wa.me/2348133831036"`
+	Ig                  string `form:"ig" validate:"required,url" example:"https://instagram.com"`
+	SupportEmail        string `form:"support_email" validate:"required,email" example:"support@email.com"`
+	DefaultCurrency     string `form:"default_currency" validate:"required,len=3,alpha" example:"usd"`
+	AiSummaryDailyLimit int    `form:"ai_summary_daily_limit" validate:"min=0,max=1000" example:"10"`
+	MaxUploadSizeMB     int    `form:"max_upload_size_mb" validate:"required,min=1,max=100" example:"10"` // Can't exceed config.MaxRequestBodySizeMB
+}
+
+type ReviewHideSchema struct {
+	Reason string `json:"reason" validate:"required,max=500" example:"Contains spam links"`
+}
+
+type CourseRejectSchema struct {
+	Reason string `json:"reason" validate:"required,min=10,max=1000" example:"The intro video has no audio"`
+}
+
+type ApplicationRejectSchema struct {
+	Reason string `json:"reason" validate:"required,min=10,max=1000" example:"Please include a sample lesson in the language you want to teach"`
+}
+
+// RESPONSE BODY SCHEMAS
+type LockoutSchema struct {
+	ID             uuid.UUID            `json:"id"`
+	User           *base.UserDataSchema `json:"user"` // null for ip lockouts
+	IP             string               `json:"ip" example:"102.89.34.1"`
+	Reason         string               `json:"reason" example:"login_failures"`
+	FailedAttempts int                  `json:"failed_attempts" example:"10"`
+	LockedUntil    time.Time            `json:"locked_until"`
+	UnlockedAt     *time.Time           `json:"unlocked_at"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+func (l LockoutSchema) Assign(lockout *ent.AccountLockout) LockoutSchema {
+	l.ID = lockout.ID
+	if lockout.Edges.User != nil {
+		user := base.UserDataSchema{}.Assign(lockout.Edges.User)
+		l.User = &user
+	}
+	l.IP = lockout.IP
+	l.Reason = string(lockout.Reason)
+	l.FailedAttempts = lockout.FailedAttempts
+	l.LockedUntil = lockout.LockedUntil
+	l.UnlockedAt = lockout.UnlockedAt
+	l.CreatedAt = lockout.CreatedAt
+	return l
+}
+
+type LockoutsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[LockoutSchema] `json:"data"`
+}
+
+func (l LockoutsResponseSchema) Assign(lockoutsData *config.PaginationResponse[*ent.AccountLockout]) LockoutsResponseSchema {
+	items := make([]LockoutSchema, 0)
+	for _, lockout := range lockoutsData.Items {
+		items = append(items, LockoutSchema{}.Assign(lockout))
+	}
+	l.Data.Items = items
+	l.Data.ItemsCount = lockoutsData.ItemsCount
+	l.Data.Page = lockoutsData.Page
+	l.Data.TotalPages = lockoutsData.TotalPages
+	l.Data.Limit = lockoutsData.Limit
+	return l
+}
+
+type AuthEventSchema struct {
+	User *base.UserDataSchema `json:"user"` // null for failed logins with an unknown email
+	accounts.AuthEventSchema
+}
+
+func (a AuthEventSchema) Assign(authEvent *ent.AuthEvent) AuthEventSchema {
+	if authEvent.Edges.User != nil {
+		user := base.UserDataSchema{}.Assign(authEvent.Edges.User)
+		a.User = &user
+	}
+	a.AuthEventSchema = accounts.AuthEventSchema{}.Assign(authEvent)
+	return a
+}
+
+type AuthEventsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[AuthEventSchema] `json:"data"`
+}
+
+func (a AuthEventsResponseSchema) Assign(authEventsData *config.PaginationResponse[*ent.AuthEvent]) AuthEventsResponseSchema {
+	items := make([]AuthEventSchema, 0)
+	for _, authEvent := range authEventsData.Items {
+		items = append(items, AuthEventSchema{}.Assign(authEvent))
+	}
+	a.Data.Items = items
+	a.Data.ItemsCount = authEventsData.ItemsCount
+	a.Data.Page = authEventsData.Page
+	a.Data.TotalPages = authEventsData.TotalPages
+	a.Data.Limit = authEventsData.Limit
+	return a
+}
+
+type AdminUserSchema struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name" example:"John Doe"`
+	Username            string     `json:"username" example:"john-doe"`
+	Email               string     `json:"email" example:"johndoe@email.com"`
+	Avatar              *string    `json:"avatar" example:"https://img.url"`
+	Role                user.Role  `json:"role" example:"student"`
+	IsVerified          bool       `json:"is_verified"`
+	IsActive            bool       `json:"is_active"`
+	SocialLogin         bool       `json:"social_login"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	LockedUntil         *time.Time `json:"locked_until"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+func (u AdminUserSchema) Assign(userObj *ent.User) AdminUserSchema {
+	u.ID = userObj.ID
+	u.Name = userObj.Name
+	u.Username = userObj.Username
+	u.Email = userObj.Email
+	u.Avatar = userObj.Avatar
+	u.Role = userObj.Role
+	u.IsVerified = userObj.IsVerified
+	u.IsActive = userObj.IsActive
+	u.SocialLogin = userObj.SocialLogin
+	u.TwoFactorEnabled = userObj.TwoFactorEnabled
+	u.LockedUntil = userObj.LockedUntil
+	u.DeletionScheduledAt = userObj.DeletionScheduledAt
+	u.CreatedAt = userObj.CreatedAt
+	return u
+}
+
+type AdminUsersResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[AdminUserSchema] `json:"data"`
+}
+
+func (a AdminUsersResponseSchema) Assign(usersData *config.PaginationResponse[*ent.User]) AdminUsersResponseSchema {
+	items := make([]AdminUserSchema, 0)
+	for _, userObj := range usersData.Items {
+		items = append(items, AdminUserSchema{}.Assign(userObj))
+	}
+	a.Data.Items = items
+	a.Data.ItemsCount = usersData.ItemsCount
+	a.Data.Page = usersData.Page
+	a.Data.TotalPages = usersData.TotalPages
+	a.Data.Limit = usersData.Limit
+	return a
+}
+
+type AdminUserDetailSchema struct {
+	AdminUserSchema
+	Roles []string `json:"roles" example:"student,support"` // base role plus any extra role
+}
+
+type AdminUserResponseSchema struct {
+	base.ResponseSchema
+	Data AdminUserDetailSchema `json:"data"`
+}
+
+type UserCourseSchema struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title" example:"Go Programming for Beginners"`
+	Slug  string    `json:"slug" example:"go-programming-for-beginners"`
+}
+
+func (u UserCourseSchema) Assign(course *ent.Course) UserCourseSchema {
+	u.ID = course.ID
+	u.Title = course.Title
+	u.Slug = course.Slug
+	return u
+}
+
+type UserEnrollmentSchema struct {
+	ID            uuid.UUID                `json:"id"`
+	Course        UserCourseSchema         `json:"course"`
+	Status        enrollment.Status        `json:"status" example:"active"`
+	PaymentStatus enrollment.PaymentStatus `json:"payment_status" example:"successful"`
+	Progress      int                      `json:"progress" example:"40"`
+	CreatedAt     time.Time                `json:"created_at"`
+}
+
+func (e UserEnrollmentSchema) Assign(enrollmentObj *ent.Enrollment) UserEnrollmentSchema {
+	e.ID = enrollmentObj.ID
+	e.Course = e.Course.Assign(enrollmentObj.Edges.Course)
+	e.Status = enrollmentObj.Status
+	e.PaymentStatus = enrollmentObj.PaymentStatus
+	e.Progress = enrollmentObj.Progress
+	e.CreatedAt = enrollmentObj.CreatedAt
+	return e
+}
+
+type UserEnrollmentsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[UserEnrollmentSchema] `json:"data"`
+}
+
+func (u UserEnrollmentsResponseSchema) Assign(enrollmentsData *config.PaginationResponse[*ent.Enrollment]) UserEnrollmentsResponseSchema {
+	items := make([]UserEnrollmentSchema, 0)
+	for _, enrollmentObj := range enrollmentsData.Items {
+		items = append(items, UserEnrollmentSchema{}.Assign(enrollmentObj))
+	}
+	u.Data.Items = items
+	u.Data.ItemsCount = enrollmentsData.ItemsCount
+	u.Data.Page = enrollmentsData.Page
+	u.Data.TotalPages = enrollmentsData.TotalPages
+	u.Data.Limit = enrollmentsData.Limit
+	return u
+}
+
+type UserPaymentSchema struct {
+	ID            uuid.UUID        `json:"id"`
+	Course        UserCourseSchema `json:"course"`
+	Amount        float64          `json:"amount" example:"50"`
+	Status        payment.Status   `json:"status" example:"successful"`
+	PaymentMethod string           `json:"payment_method" example:"stripe"`
+	TransactionID string           `json:"transaction_id" example:"cs_test_a1b2c3"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+func (p UserPaymentSchema) Assign(paymentObj *ent.Payment) UserPaymentSchema {
+	p.ID = paymentObj.ID
+	p.Course = p.Course.Assign(paymentObj.Edges.Course)
+	p.Amount = paymentObj.Amount
+	p.Status = paymentObj.Status
+	p.PaymentMethod = paymentObj.PaymentMethod
+	p.TransactionID = paymentObj.TransactionID
+	p.CreatedAt = paymentObj.CreatedAt
+	return p
+}
+
+type UserPaymentsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[UserPaymentSchema] `json:"data"`
+}
+
+func (u UserPaymentsResponseSchema) Assign(paymentsData *config.PaginationResponse[*ent.Payment]) UserPaymentsResponseSchema {
+	items := make([]UserPaymentSchema, 0)
+	for _, paymentObj := range paymentsData.Items {
+		items = append(items, UserPaymentSchema{}.Assign(paymentObj))
+	}
+	u.Data.Items = items
+	u.Data.ItemsCount = paymentsData.ItemsCount
+	u.Data.Page = paymentsData.Page
+	u.Data.TotalPages = paymentsData.TotalPages
+	u.Data.Limit = paymentsData.Limit
+	return u
+}
+
+type CategoryOrTagResponseSchema struct {
+	base.ResponseSchema
+	Data courses.CategoryOrTagSchema `json:"data"`
+}
+
+type RoleSchema struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name" example:"teaching_assistant"`
+	Description string    `json:"description" example:"Helps instructors with lessons and quizzes"`
+	Permissions []string  `json:"permissions" example:"course.read,lesson.update"`
+	IsSystem    bool      `json:"is_system"`
+}
+
+func (r RoleSchema) Assign(role *ent.Role) RoleSchema {
+	r.ID = role.ID
+	r.Name = role.Name
+	r.Description = role.Description
+	r.Permissions = role.Permissions
+	if r.Permissions == nil {
+		r.Permissions = []string{}
+	}
+	r.IsSystem = role.IsSystem
+	return r
+}
+
+type RoleResponseSchema struct {
+	base.ResponseSchema
+	Data RoleSchema `json:"data"`
+}
+
+type RolesResponseSchema struct {
+	base.ResponseSchema
+	Data []RoleSchema `json:"data"`
+}
+
+func (r RolesResponseSchema) Assign(roles []*ent.Role) RolesResponseSchema {
+	items := make([]RoleSchema, 0)
+	for _, role := range roles {
+		items = append(items, RoleSchema{}.Assign(role))
+	}
+	r.Data = items
+	return r
+}
+
+type PermissionsResponseSchema struct {
+	base.ResponseSchema
+	Data []string `json:"data" example:"course.read,course.update"`
+}
+
+type UserRolesResponseSchema struct {
+	base.ResponseSchema
+	Data []string `json:"data" example:"student,support"`
+}
+
+type AnalyticsTotalsSchema struct {
+	Signups     int     `json:"signups" example:"25"`
+	Enrollments int     `json:"enrollments" example:"40"`
+	Completions int     `json:"completions" example:"6"`
+	Revenue     float64 `json:"revenue" example:"799.6"`
+}
+
+type AnalyticsBucketSchema struct {
+	Start time.Time `json:"start"`
+	AnalyticsTotalsSchema
+}
+
+type AnalyticsSchema struct {
+	Start    time.Time               `json:"start"`
+	End      time.Time               `json:"end"`
+	Interval string                  `json:"interval" example:"day"`
+	Currency string                  `json:"currency" example:"usd"`
+	Totals   AnalyticsTotalsSchema   `json:"totals"`
+	Buckets  []AnalyticsBucketSchema `json:"buckets"`
+}
+
+func (a AnalyticsSchema) Assign(analytics analyticsRange, currency string, buckets []AnalyticsBucketSchema) AnalyticsSchema {
+	a.Start = analytics.Start
+	a.End = analytics.End
+	a.Interval = analytics.Interval
+	a.Currency = currency
+	a.Buckets = buckets
+	for _, bucket := range buckets {
+		a.Totals.Signups += bucket.Signups
+		a.Totals.Enrollments += bucket.Enrollments
+		a.Totals.Completions += bucket.Completions
+		a.Totals.Revenue += bucket.Revenue
+	}
+	return a
+}
+
+type AnalyticsResponseSchema struct {
+	base.ResponseSchema
+	Data AnalyticsSchema `json:"data"`
+}
+
+type TopCourseSchema struct {
+	Course       UserCourseSchema `json:"course"`
+	Enrollments  int              `json:"enrollments" example:"120"`
+	Revenue      float64          `json:"revenue" example:"2398.8"`
+	ReviewsCount int              `json:"reviews_count" example:"30"`
+	Rating       float64          `json:"rating" example:"4.6"`
+}
+
+type TopCoursesResponseSchema struct {
+	base.ResponseSchema
+	Data []TopCourseSchema `json:"data"`
+}
+
+type AdminReviewSchema struct {
+	courses.ReviewResponseData
+	Course       UserCourseSchema `json:"course"`
+	IsHidden     bool             `json:"is_hidden" example:"false"`
+	HiddenReason *string          `json:"hidden_reason" example:"Contains spam links"`
+	HiddenAt     *time.Time       `json:"hidden_at"`
+	ReportsCount int              `json:"reports_count" example:"3"`
+}
+
+func (a AdminReviewSchema) Assign(reviewObj *ent.Review) AdminReviewSchema {
+	a.ReviewResponseData = a.ReviewResponseData.Assign(reviewObj)
+	a.Course = a.Course.Assign(reviewObj.Edges.Course)
+	a.IsHidden = reviewObj.IsHidden
+	a.HiddenReason = reviewObj.HiddenReason
+	a.HiddenAt = reviewObj.HiddenAt
+	a.ReportsCount = reviewObj.ReportsCount
+	return a
+}
+
+type AdminReviewResponseSchema struct {
+	base.ResponseSchema
+	Data AdminReviewSchema `json:"data"`
+}
+
+type AdminReviewsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[AdminReviewSchema] `json:"data"`
+}
+
+func (a AdminReviewsResponseSchema) Assign(reviewsData *config.PaginationResponse[*ent.Review]) AdminReviewsResponseSchema {
+	items := make([]AdminReviewSchema, 0)
+	for _, reviewObj := range reviewsData.Items {
+		items = append(items, AdminReviewSchema{}.Assign(reviewObj))
+	}
+	a.Data.Items = items
+	a.Data.ItemsCount = reviewsData.ItemsCount
+	a.Data.Page = reviewsData.Page
+	a.Data.TotalPages = reviewsData.TotalPages
+	a.Data.Limit = reviewsData.Limit
+	return a
+}
+
+type ReviewReportSchema struct {
+	ID         uuid.UUID           `json:"id"`
+	User       base.UserDataSchema `json:"user"`
+	Reason     reviewreport.Reason `json:"reason" example:"spam"`
+	Details    *string             `json:"details" example:"Links to an unrelated website"`
+	ResolvedAt *time.Time          `json:"resolved_at"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+func (r ReviewReportSchema) Assign(report *ent.ReviewReport) ReviewReportSchema {
+	r.ID = report.ID
+	r.User = r.User.Assign(report.Edges.User)
+	r.Reason = report.Reason
+	r.Details = report.Details
+	r.ResolvedAt = report.ResolvedAt
+	r.CreatedAt = report.CreatedAt
+	return r
+}
+
+type ReviewReportsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[ReviewReportSchema] `json:"data"`
+}
+
+func (r ReviewReportsResponseSchema) Assign(reportsData *config.PaginationResponse[*ent.ReviewReport]) ReviewReportsResponseSchema {
+	items := make([]ReviewReportSchema, 0)
+	for _, report := range reportsData.Items {
+		items = append(items, ReviewReportSchema{}.Assign(report))
+	}
+	r.Data.Items = items
+	r.Data.ItemsCount = reportsData.ItemsCount
+	r.Data.Page = reportsData.Page
+	r.Data.TotalPages = reportsData.TotalPages
+	r.Data.Limit = reportsData.Limit
+	return r
+}
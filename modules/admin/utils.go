@@ -0,0 +1,90 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+)
+
+const (
+	ANALYTICS_DAY   = "day"
+	ANALYTICS_WEEK  = "week"
+	ANALYTICS_MONTH = "month"
+
+	TOP_BY_ENROLLMENTS = "enrollments"
+	TOP_BY_RATING      = "rating"
+
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 731
+)
+
+// analyticsRange is the reporting window of an analytics request. End is exclusive
+type analyticsRange struct {
+	Start    time.Time
+	End      time.Time
+	Interval string
+}
+
+// Reads the start, end (both YYYY-MM-DD, end inclusive) and interval query params.
+// Defaults to daily buckets over the last 30 days
+func parseAnalyticsRange(c *fiber.Ctx) (*analyticsRange, *config.ErrorResponse) {
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if endStr := c.Query("end"); endStr != "" {
+		parsedEnd, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			errData := config.InvalidParamErr("Invalid end date. Use the YYYY-MM-DD format")
+			return nil, &errData
+		}
+		end = parsedEnd
+	}
+	start := end.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if startStr := c.Query("start"); startStr != "" {
+		parsedStart, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			errData := config.InvalidParamErr("Invalid start date. Use the YYYY-MM-DD format")
+			return nil, &errData
+		}
+		start = parsedStart
+	}
+	end = end.AddDate(0, 0, 1)
+	if !start.Before(end) {
+		errData := config.InvalidParamErr("Start date cannot be after the end date")
+		return nil, &errData
+	}
+	if end.Sub(start) > maxAnalyticsDays*24*time.Hour {
+		errData := config.InvalidParamErr("Date range cannot be longer than 2 years")
+		return nil, &errData
+	}
+
+	interval := c.Query("interval", ANALYTICS_DAY)
+	if interval != ANALYTICS_DAY && interval != ANALYTICS_WEEK && interval != ANALYTICS_MONTH {
+		errData := config.InvalidParamErr("Invalid interval. Use day, week or month")
+		return nil, &errData
+	}
+	return &analyticsRange{Start: start, End: end, Interval: interval}, nil
+}
+
+// Returns the start of the bucket containing t. Weeks start on Monday
+func bucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case ANALYTICS_WEEK:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case ANALYTICS_MONTH:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+func nextBucket(t time.Time, interval string) time.Time {
+	switch interval {
+	case ANALYTICS_WEEK:
+		return t.AddDate(0, 0, 7)
+	case ANALYTICS_MONTH:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
@@ -28,3 +28,13 @@ type InvalidErrorExample struct {
 	StatusData
 	Message string `json:"message" example:"Request was invalid due to ..."`
 }
+
+type ForbiddenErrorExample struct {
+	StatusData
+	Message string `json:"message" example:"Account locked/Not allowed"`
+}
+
+type TooManyRequestsErrorExample struct {
+	StatusData
+	Message string `json:"message" example:"Too many failed attempts. Try again later"`
+}
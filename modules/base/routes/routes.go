@@ -4,17 +4,23 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
-	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/admin"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/chats"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/discussions"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/general"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/instructors"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/notifications"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/profiles"
 )
 
-// All Endpoints (51)
+// All Endpoints (164)
 func SetupRoutes(app *fiber.App, db *ent.Client, cfg config.Config) {
 
+	// Well Known Routes (1)
+	app.Get("/.well-known/jwks.json", accounts.GetJWKS)
+
 	api := app.Group("/api/v1")
 	// HealthCheck Route (1)
 	api.Get("/healthcheck", HealthCheck)
@@ -23,37 +29,63 @@ func SetupRoutes(app *fiber.App, db *ent.Client, cfg config.Config) {
 	generalRouter := api.Group("/general")
 	generalRouter.Get("/site-detail", general.GetSiteDetails(db))
 
-	// Auth Routes (10)
+	// Auth Routes (29)
 	authRouter := api.Group("/auth")
 	authRouter.Post("/register", accounts.Register(db))
 	authRouter.Post("/verify-email", accounts.VerifyEmail(db))
 	authRouter.Post("/resend-verification-email", accounts.ResendVerificationEmail(db))
 	authRouter.Post("/send-password-reset-otp", accounts.SendPasswordResetOtp(db))
 	authRouter.Post("/set-new-password", accounts.SetNewPassword(db))
+	authRouter.Post("/unlock-account", accounts.UnlockAccount(db))
 	authRouter.Post("/login", accounts.Login(db))
+	authRouter.Post("/login/2fa", accounts.TwoFactorLogin(db))
+	authRouter.Post("/magic-link", accounts.SendMagicLink(db, cfg))
+	authRouter.Post("/magic-link/verify", accounts.MagicLinkLogin(db))
 	authRouter.Post("/google-login", accounts.GoogleLogin(db))
+	authRouter.Get("/social/providers", accounts.ListSocialProviders(cfg))
+	authRouter.Post("/social/:provider", accounts.SocialLogin(db, cfg))
+	authRouter.Post("/social/:provider/link", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.LinkSocialProvider(db, cfg))
+	authRouter.Get("/identities", accounts.AuthMiddleware(db), accounts.GetIdentities(db))
+	authRouter.Delete("/identities/:id", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.UnlinkIdentity(db))
 	authRouter.Post("/refresh", accounts.Refresh(db))
 	authRouter.Get("/logout", accounts.AuthMiddleware(db), accounts.Logout(db))
-	authRouter.Get("/logout/all", accounts.AuthMiddleware(db), accounts.LogoutAll(db))
+	authRouter.Get("/logout/all", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.LogoutAll(db))
+	authRouter.Get("/sessions", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.GetSessions(db))
+	authRouter.Get("/events", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.GetAuthEvents(db))
+	authRouter.Delete("/sessions/:id", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.RevokeSession(db))
+	authRouter.Get("/tokens", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.GetPersonalAccessTokens(db))
+	authRouter.Post("/tokens", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.CreatePersonalAccessToken(db))
+	authRouter.Delete("/tokens/:id", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.RevokePersonalAccessToken(db))
+	authRouter.Post("/2fa/setup", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.SetupTwoFactor(db))
+	authRouter.Post("/2fa/enable", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.EnableTwoFactor(db))
+	authRouter.Post("/2fa/disable", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.DisableTwoFactor(db))
+	authRouter.Post("/2fa/recovery-codes", accounts.AuthMiddleware(db), accounts.RequireSession(), accounts.RegenerateRecoveryCodes(db))
 
-	// Profiles Routes (7)
+	// Profiles Routes (12)
 	profilesRouter := api.Group("/profiles")
 	profilesRouter.Get("", accounts.AuthMiddleware(db), profiles.GetProfile(db))
-	profilesRouter.Put("", accounts.AuthMiddleware(db), profiles.UpdateProfile(db))
+	profilesRouter.Put("", accounts.AuthMiddleware(db), accounts.RequireSession(), profiles.UpdateProfile(db)).Name(config.UploadRouteName)
+	profilesRouter.Post("/email", accounts.AuthMiddleware(db), accounts.RequireSession(), profiles.RequestEmailChange(db))
+	profilesRouter.Post("/email/verify", accounts.AuthMiddleware(db), accounts.RequireSession(), profiles.VerifyEmailChange(db))
 	profilesRouter.Get("/courses", accounts.AuthMiddleware(db), profiles.GetEnrolledCourses(db))
 	profilesRouter.Get("/courses/:slug/progress", accounts.AuthMiddleware(db), profiles.GetCourseProgress(db))
 
 	profilesRouter.Post("/lessons/:slug/progress", accounts.AuthMiddleware(db), profiles.CreateOrUpdateLessonProgress(db))
 	profilesRouter.Get("/lessons/:slug/progress", accounts.AuthMiddleware(db), profiles.GetLessonProgress(db))
 	profilesRouter.Get("/leaderboard", accounts.AuthMiddleware(db), profiles.GetLeaderboard(db))
+	profilesRouter.Get("/export", accounts.AuthMiddleware(db), accounts.RequireSession(), profiles.ExportAccountData(db))
+	profilesRouter.Post("/deletion", accounts.AuthMiddleware(db), accounts.RequireSession(), profiles.RequestAccountDeletion(db))
+	profilesRouter.Delete("/deletion", accounts.AuthMiddleware(db), accounts.RequireSession(), profiles.CancelAccountDeletion(db))
 
-	// Courses Routes (17)
+	// Courses Routes (36)
 	coursesRouter := api.Group("/courses")
 	coursesRouter.Get("", courses.GetLatestCourses(db))
-	coursesRouter.Post("/pdf/summarize", accounts.AuthMiddleware(db), courses.PostSummarizePDF(db, cfg))
+	coursesRouter.Get("/categories", courses.GetCategories(db))
+	coursesRouter.Get("/tags", courses.GetTags(db))
+	coursesRouter.Post("/pdf/summarize", accounts.AuthMiddleware(db), courses.PostSummarizePDF(db, cfg)).Name(config.UploadRouteName)
 	coursesRouter.Get("/:slug", courses.GetCourseDetails(db))
-	coursesRouter.Get("/:slug/lessons", courses.GetCourseLessons(db))
-	coursesRouter.Get("/:course_slug/lessons/:lesson_slug", courses.GetCourseLessonDetails(db))
+	coursesRouter.Get("/:slug/lessons", accounts.OptionalAuthMiddleware(db), courses.GetCourseLessons(db))
+	coursesRouter.Get("/:course_slug/lessons/:lesson_slug", accounts.OptionalAuthMiddleware(db), courses.GetCourseLessonDetails(db))
 	coursesRouter.Post("/:slug/enroll", accounts.AuthMiddleware(db), courses.EnrollForACourse(db, cfg))
 	coursesRouter.Get("/lessons/:slug/quizzes", accounts.AuthMiddleware(db), courses.GetLessonQuizzes(db))
 	coursesRouter.Get("/quizzes/:quiz_slug", accounts.AuthMiddleware(db), courses.GetLessonQuizDetails(db))
@@ -68,27 +100,128 @@ func SetupRoutes(app *fiber.App, db *ent.Client, cfg config.Config) {
 	coursesRouter.Get("/reviews/:id", courses.GetCourseReview(db))
 	coursesRouter.Put("/reviews/:id", accounts.AuthMiddleware(db), courses.UpdateCourseReview(db))
 	coursesRouter.Delete("/reviews/:id", accounts.AuthMiddleware(db), courses.DeleteCourseReview(db))
+	coursesRouter.Post("/reviews/:id/report", accounts.AuthMiddleware(db), courses.ReportCourseReview(db))
+	coursesRouter.Post("/reviews/:id/helpful", accounts.AuthMiddleware(db), courses.VoteCourseReviewHelpful(db))
+	coursesRouter.Delete("/reviews/:id/helpful", accounts.AuthMiddleware(db), courses.RemoveCourseReviewVote(db))
+
+	// Lesson Discussions
+	coursesRouter.Get("/:course_slug/lessons/:lesson_slug/questions", accounts.AuthMiddleware(db), discussions.GetLessonQuestions(db))
+	coursesRouter.Post("/:course_slug/lessons/:lesson_slug/questions", accounts.AuthMiddleware(db), discussions.CreateLessonQuestion(db))
+	coursesRouter.Get("/questions/:id", accounts.AuthMiddleware(db), discussions.GetLessonQuestion(db))
+	coursesRouter.Delete("/questions/:id", accounts.AuthMiddleware(db), discussions.DeleteLessonQuestion(db))
+	coursesRouter.Post("/questions/:id/upvote", accounts.AuthMiddleware(db), discussions.UpvoteLessonQuestion(db))
+	coursesRouter.Delete("/questions/:id/upvote", accounts.AuthMiddleware(db), discussions.RemoveLessonQuestionUpvote(db))
+	coursesRouter.Post("/questions/:id/answers", accounts.AuthMiddleware(db), discussions.CreateQuestionAnswer(db))
+	coursesRouter.Delete("/answers/:id", accounts.AuthMiddleware(db), discussions.DeleteQuestionAnswer(db))
+	coursesRouter.Post("/answers/:id/upvote", accounts.AuthMiddleware(db), discussions.UpvoteQuestionAnswer(db))
+	coursesRouter.Delete("/answers/:id/upvote", accounts.AuthMiddleware(db), discussions.RemoveQuestionAnswerUpvote(db))
+	coursesRouter.Post("/answers/:id/accept", accounts.AuthMiddleware(db), discussions.AcceptQuestionAnswer(db))
+	coursesRouter.Delete("/answers/:id/accept", accounts.AuthMiddleware(db), discussions.UnacceptQuestionAnswer(db))
+	coursesRouter.Post("/answers/:id/endorse", accounts.AuthMiddleware(db), discussions.EndorseQuestionAnswer(db))
+	coursesRouter.Delete("/answers/:id/endorse", accounts.AuthMiddleware(db), discussions.RemoveQuestionAnswerEndorsement(db))
+
+	// Instructor Routes (27)
+	instructorsRouter := api.Group("/instructor", accounts.ScopedAuthMiddleware(db))
+	instructorsRouter.Get("/courses", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorCourses(db))
+	instructorsRouter.Post("/courses", accounts.RequirePermission(db, accounts.PERM_COURSE_CREATE), instructors.CreateCourse(db)).Name(config.UploadRouteName)
+	instructorsRouter.Get("/courses/:slug", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorCourseDetails(db))
+	instructorsRouter.Put("/courses/:slug", accounts.RequirePermission(db, accounts.PERM_COURSE_UPDATE), instructors.UpdateCourse(db)).Name(config.UploadRouteName)
+	instructorsRouter.Post("/courses/:slug/submit", accounts.RequirePermission(db, accounts.PERM_COURSE_UPDATE), instructors.SubmitCourse(db))
+	instructorsRouter.Post("/courses/:slug/publish", accounts.RequirePermission(db, accounts.PERM_COURSE_PUBLISH), instructors.PublishCourse(db))
+	instructorsRouter.Post("/courses/:slug/unpublish", accounts.RequirePermission(db, accounts.PERM_COURSE_PUBLISH), instructors.UnpublishCourse(db))
+	instructorsRouter.Delete("/courses/:slug", accounts.RequirePermission(db, accounts.PERM_COURSE_DELETE), instructors.DeleteACourse(db))
+	instructorsRouter.Get("/courses/:slug/lessons", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorCourseLessons(db))
+	instructorsRouter.Post("/courses/:slug/lessons", accounts.RequirePermission(db, accounts.PERM_LESSON_CREATE), instructors.CreateInstructorCourseLesson(db)).Name(config.UploadRouteName)
+
+	instructorsRouter.Get("/lessons/:slug", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorCourseLessonDetails(db))
+	instructorsRouter.Put("/lessons/:slug", accounts.RequirePermission(db, accounts.PERM_LESSON_UPDATE), instructors.UpdateCourseLesson(db)).Name(config.UploadRouteName)
+	instructorsRouter.Delete("/lessons/:slug", accounts.RequirePermission(db, accounts.PERM_LESSON_DELETE), instructors.DeleteCourseLesson(db))
+	instructorsRouter.Put("/lessons/:slug/section", accounts.RequirePermission(db, accounts.PERM_LESSON_UPDATE), instructors.MoveCourseLesson(db))
+	instructorsRouter.Put("/lessons/:slug/unlock-conditions", accounts.RequirePermission(db, accounts.PERM_LESSON_UPDATE), instructors.SetLessonUnlockConditions(db))
+
+	instructorsRouter.Get("/courses/:slug/sections", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorCourseSections(db))
+	instructorsRouter.Post("/courses/:slug/sections", accounts.RequirePermission(db, accounts.PERM_COURSE_UPDATE), instructors.CreateCourseSection(db))
+	instructorsRouter.Put("/sections/:id", accounts.RequirePermission(db, accounts.PERM_COURSE_UPDATE), instructors.UpdateCourseSection(db))
+	instructorsRouter.Delete("/sections/:id", accounts.RequirePermission(db, accounts.PERM_COURSE_UPDATE), instructors.DeleteCourseSection(db))
+
+	instructorsRouter.Get("/courses/:slug/quizzes", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorLessonQuizzes(db))
+	instructorsRouter.Post("/courses/:slug/quizzes", accounts.RequirePermission(db, accounts.PERM_QUIZ_CREATE), instructors.CreateInstructorLessonQuiz(db))
+	instructorsRouter.Get("/quizzes/:slug", accounts.RequirePermission(db, accounts.PERM_COURSE_READ), instructors.GetInstructorLessonQuizDetails(db))
+	instructorsRouter.Put("/quizzes/:slug", accounts.RequirePermission(db, accounts.PERM_QUIZ_UPDATE), instructors.UpdateLessonQuiz(db))
+	instructorsRouter.Delete("/quizzes/:slug", accounts.RequirePermission(db, accounts.PERM_QUIZ_DELETE), instructors.DeleteLessonQuiz(db))
+
+	instructorsRouter.Put("/reviews/:id/reply", accounts.RequirePermission(db, accounts.PERM_REVIEW_REPLY), instructors.ReplyToReview(db))
+	instructorsRouter.Delete("/reviews/:id/reply", accounts.RequirePermission(db, accounts.PERM_REVIEW_REPLY), instructors.DeleteReviewReply(db))
+	instructorsRouter.Post("/courses/:slug/announcements", accounts.RequirePermission(db, accounts.PERM_COURSE_UPDATE), instructors.MakeAnnouncement(db))
+
+	// Instructor Application Routes (2)
+	applicationsRouter := api.Group("/instructor-applications", accounts.AuthMiddleware(db))
+	applicationsRouter.Get("", instructors.GetMyInstructorApplications(db))
+	applicationsRouter.Post("", instructors.SubmitInstructorApplication(db)).Name(config.UploadRouteName)
+
+	// Admin Routes (40)
+	adminRouter := api.Group("/admin", accounts.ScopedAuthMiddleware(db))
+	adminRouter.Get("/lockouts", accounts.RequirePermission(db, accounts.PERM_LOCKOUT_VIEW), admin.GetLockouts(db))
+	adminRouter.Get("/auth-events", accounts.RequirePermission(db, accounts.PERM_AUTH_EVENT_VIEW), admin.GetAuthEvents(db))
+	adminRouter.Get("/permissions", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.GetPermissions(db))
+	adminRouter.Get("/roles", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.GetRoles(db))
+	adminRouter.Post("/roles", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.CreateRole(db))
+	adminRouter.Put("/roles/:id", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.UpdateRole(db))
+	adminRouter.Delete("/roles/:id", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.DeleteRole(db))
+	adminRouter.Get("/users", accounts.RequirePermission(db, accounts.PERM_USER_VIEW), admin.GetUsers(db))
+	adminRouter.Get("/users/:id", accounts.RequirePermission(db, accounts.PERM_USER_VIEW), admin.GetUser(db))
+	adminRouter.Get("/users/:id/enrollments", accounts.RequirePermission(db, accounts.PERM_USER_VIEW), admin.GetUserEnrollments(db))
+	adminRouter.Get("/users/:id/payments", accounts.RequirePermission(db, accounts.PERM_USER_VIEW), admin.GetUserPayments(db))
+	adminRouter.Post("/users/:id/deactivate", accounts.RequirePermission(db, accounts.PERM_USER_MANAGE), admin.DeactivateUser(db))
+	adminRouter.Post("/users/:id/reactivate", accounts.RequirePermission(db, accounts.PERM_USER_MANAGE), admin.ReactivateUser(db))
+	adminRouter.Post("/users/:id/logout", accounts.RequirePermission(db, accounts.PERM_USER_MANAGE), admin.ForceLogoutUser(db))
+	adminRouter.Post("/users/:id/password-reset", accounts.RequirePermission(db, accounts.PERM_USER_MANAGE), admin.TriggerPasswordReset(db))
+	adminRouter.Put("/users/:id/role", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.ChangeUserRole(db))
+	adminRouter.Post("/users/:id/roles", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.AssignUserRole(db))
+	adminRouter.Delete("/users/:id/roles/:role", accounts.RequirePermission(db, accounts.PERM_ROLE_MANAGE), admin.RemoveUserRole(db))
+	adminRouter.Get("/courses/submissions", accounts.RequirePermission(db, accounts.PERM_COURSE_SUBMISSION_REVIEW), admin.GetCourseSubmissions(db))
+	adminRouter.Post("/courses/:slug/approve", accounts.RequirePermission(db, accounts.PERM_COURSE_SUBMISSION_REVIEW), admin.ApproveCourse(db))
+	adminRouter.Post("/courses/:slug/reject", accounts.RequirePermission(db, accounts.PERM_COURSE_SUBMISSION_REVIEW), admin.RejectCourse(db))
+	adminRouter.Post("/categories", accounts.RequirePermission(db, accounts.PERM_CATEGORY_MANAGE), admin.CreateCategory(db))
+	adminRouter.Put("/categories/:slug", accounts.RequirePermission(db, accounts.PERM_CATEGORY_MANAGE), admin.UpdateCategory(db))
+	adminRouter.Post("/categories/:slug/merge", accounts.RequirePermission(db, accounts.PERM_CATEGORY_MANAGE), admin.MergeCategory(db))
+	adminRouter.Delete("/categories/:slug", accounts.RequirePermission(db, accounts.PERM_CATEGORY_MANAGE), admin.DeleteCategory(db))
+	adminRouter.Post("/tags", accounts.RequirePermission(db, accounts.PERM_TAG_MANAGE), admin.CreateTag(db))
+	adminRouter.Put("/tags/:slug", accounts.RequirePermission(db, accounts.PERM_TAG_MANAGE), admin.UpdateTag(db))
+	adminRouter.Post("/tags/:slug/merge", accounts.RequirePermission(db, accounts.PERM_TAG_MANAGE), admin.MergeTag(db))
+	adminRouter.Delete("/tags/:slug", accounts.RequirePermission(db, accounts.PERM_TAG_MANAGE), admin.DeleteTag(db))
+	adminRouter.Get("/instructor-applications", accounts.RequirePermission(db, accounts.PERM_INSTRUCTOR_APPLICATION_REVIEW), admin.GetInstructorApplications(db))
+	adminRouter.Get("/instructor-applications/:id", accounts.RequirePermission(db, accounts.PERM_INSTRUCTOR_APPLICATION_REVIEW), admin.GetInstructorApplication(db))
+	adminRouter.Post("/instructor-applications/:id/approve", accounts.RequirePermission(db, accounts.PERM_INSTRUCTOR_APPLICATION_REVIEW), admin.ApproveInstructorApplication(db))
+	adminRouter.Post("/instructor-applications/:id/reject", accounts.RequirePermission(db, accounts.PERM_INSTRUCTOR_APPLICATION_REVIEW), admin.RejectInstructorApplication(db))
+	adminRouter.Put("/site-detail", accounts.RequirePermission(db, accounts.PERM_SITE_DETAIL_MANAGE), admin.UpdateSiteDetail(db)).Name(config.UploadRouteName)
+	adminRouter.Get("/analytics", accounts.RequirePermission(db, accounts.PERM_ANALYTICS_VIEW), admin.GetAnalytics(db))
+	adminRouter.Get("/analytics/top-courses", accounts.RequirePermission(db, accounts.PERM_ANALYTICS_VIEW), admin.GetTopCourses(db))
+	adminRouter.Get("/reviews", accounts.RequirePermission(db, accounts.PERM_REVIEW_MODERATE), admin.GetModeratedReviews(db))
+	adminRouter.Get("/reviews/:id/reports", accounts.RequirePermission(db, accounts.PERM_REVIEW_MODERATE), admin.GetReviewReports(db))
+	adminRouter.Post("/reviews/:id/hide", accounts.RequirePermission(db, accounts.PERM_REVIEW_MODERATE), admin.HideReview(db))
+	adminRouter.Post("/reviews/:id/restore", accounts.RequirePermission(db, accounts.PERM_REVIEW_MODERATE), admin.RestoreReview(db))
 
+	// Chat Routes (9)
+	chatsRouter := api.Group("/chats")
+	chatsRouter.Get("/courses/:slug/ws", chats.SocketAuthMiddleware(db), chats.CourseChatSocket(db))
+	chatsRouter.Get("/courses/:slug/messages", accounts.AuthMiddleware(db), chats.GetChatMessages(db))
+	chatsRouter.Delete("/messages/:id", accounts.AuthMiddleware(db), chats.DeleteChatMessage(db))
+	chatsRouter.Get("/ws", chats.SocketAuthMiddleware(db), chats.InboxSocket(db))
+	chatsRouter.Get("/conversations", accounts.AuthMiddleware(db), chats.GetConversations(db))
+	chatsRouter.Post("/conversations", accounts.AuthMiddleware(db), chats.StartConversation(db))
+	chatsRouter.Get("/conversations/:id/messages", accounts.AuthMiddleware(db), chats.GetConversationMessages(db))
+	chatsRouter.Post("/conversations/:id/messages", accounts.AuthMiddleware(db), chats.SendDirectMessage(db)).Name(config.UploadRouteName)
+	chatsRouter.Post("/conversations/:id/read", accounts.AuthMiddleware(db), chats.MarkConversationRead(db))
 
-	// Instructor Routes (15)
-	instructorsRouter := api.Group("/instructor", accounts.AuthMiddleware(db, user.RoleInstructor))
-	instructorsRouter.Get("/courses", instructors.GetInstructorCourses(db))
-	instructorsRouter.Post("/courses", instructors.CreateCourse(db))
-	instructorsRouter.Get("/courses/:slug", instructors.GetInstructorCourseDetails(db))
-	instructorsRouter.Put("/courses/:slug", instructors.UpdateCourse(db))
-	instructorsRouter.Delete("/courses/:slug", instructors.DeleteACourse(db))
-	instructorsRouter.Get("/courses/:slug/lessons", instructors.GetInstructorCourseLessons(db))
-	instructorsRouter.Post("/courses/:slug/lessons", instructors.CreateInstructorCourseLesson(db))
-
-	instructorsRouter.Get("/lessons/:slug", instructors.GetInstructorCourseLessonDetails(db))
-	instructorsRouter.Put("/lessons/:slug", instructors.UpdateCourseLesson(db))
-	instructorsRouter.Delete("/lessons/:slug", instructors.DeleteCourseLesson(db))
-
-	instructorsRouter.Get("/courses/:slug/quizzes", instructors.GetInstructorLessonQuizzes(db))
-	instructorsRouter.Post("/courses/:slug/quizzes", instructors.CreateInstructorLessonQuiz(db))
-	instructorsRouter.Get("/quizzes/:slug", instructors.GetInstructorLessonQuizDetails(db))
-	instructorsRouter.Put("/quizzes/:slug", instructors.UpdateLessonQuiz(db))
-	instructorsRouter.Delete("/quizzes/:slug", instructors.DeleteLessonQuiz(db))
+	// Notification Routes (6)
+	notificationsRouter := api.Group("/notifications")
+	notificationsRouter.Get("", accounts.AuthMiddleware(db), notifications.GetNotifications(db))
+	notificationsRouter.Get("/stream", accounts.QueryTokenAuthMiddleware(db), notifications.StreamNotifications(db))
+	notificationsRouter.Post("/read-all", accounts.AuthMiddleware(db), notifications.MarkAllNotificationsRead(db))
+	notificationsRouter.Post("/:id/read", accounts.AuthMiddleware(db), notifications.MarkNotificationRead(db))
+	notificationsRouter.Get("/preferences", accounts.AuthMiddleware(db), notifications.GetNotificationPreferences(db))
+	notificationsRouter.Put("/preferences", accounts.AuthMiddleware(db), notifications.UpdateNotificationPreferences(db))
 }
 
 type HealthCheckSchema struct {
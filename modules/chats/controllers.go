@@ -1 +1,430 @@
-package chats
\ No newline at end of file
+package chats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
+)
+
+var chatManager = ChatManager{}
+var courseManager = courses.CourseManager{}
+var userManager = accounts.UserManager{}
+var dmManager = DirectMessageManager{}
+
+// getChatCourse returns the course whose room the request targets, if the user can join it
+func getChatCourse(db *ent.Client, c *fiber.Ctx) (*ent.Course, error) {
+	courseObj := courseManager.GetPublishedCourseBySlug(db, c.Context(), c.Params("slug"), false)
+	if courseObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
+	}
+	if !chatManager.CanJoin(db, c.Context(), base.RequestUser(c), courseObj) {
+		return nil, config.APIError(c, 403, config.ForbiddenErr("Only enrolled students and the instructor can access this chat"))
+	}
+	return courseObj, nil
+}
+
+// socketUser authenticates a connected socket's token again, so sockets close once their session ends or the account is deactivated
+func socketUser(db *ent.Client, ctx context.Context, token string) (*ent.User, *string) {
+	userObj, err := accounts.GetUser(db, ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !userObj.IsActive {
+		reason := "This account has been deactivated. Contact support"
+		return nil, &reason
+	}
+	return userObj, nil
+}
+
+// @Summary Join A Course Chat
+// @Description `This endpoint upgrades the connection to a websocket joined to the course's chat room. Send the access token in the Authorization header or the token query param`
+// @Description `Frames are JSON objects with a type. Clients send {"type": "message", "content": "..."} and {"type": "typing", "is_typing": true}`
+// @Description `The server sends message (ChatMessageSchema), typing (TypingSchema), message_deleted (MessageDeletedSchema) and error (EventErrorSchema) events as {"type": "...", "data": {...}}`
+// @Description `Clients can send 20 frames every 10 seconds. Access is checked again every minute, and the socket closes after an error event once the session ends, the account is deactivated or the user can't join the chat anymore`
+// @Tags Chats
+// @Param slug path string true "Course Slug"
+// @Param token query string false "Access token, for clients that can't set headers"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 426 {object} base.InvalidErrorExample
+// @Router /chats/courses/{slug}/ws [get]
+// @Security BearerAuth
+func CourseChatSocket(db *ent.Client) fiber.Handler {
+	socket := websocket.New(func(conn *websocket.Conn) {
+		userObj := conn.Locals("user").(*ent.User)
+		courseObj := conn.Locals("course").(*ent.Course)
+		token := conn.Locals("token").(string)
+		ctx := context.Background()
+
+		authorize := func() *string {
+			currentUser, reason := socketUser(db, ctx, token)
+			if reason != nil {
+				return reason
+			}
+			if !chatManager.CanJoin(db, ctx, currentUser, courseObj) {
+				reason := "You can no longer access this chat"
+				return &reason
+			}
+			return nil
+		}
+		hub.serve(conn, userObj, courseObj.ID, authorize, func(client *chatClient, frame []byte) {
+			event := IncomingEventSchema{}
+			if err := json.Unmarshal(frame, &event); err != nil {
+				client.sendEvent(OutgoingEventSchema{Type: EVENT_ERROR, Data: EventErrorSchema{Message: "Invalid event. Frames must be JSON objects"}})
+				return
+			}
+			switch event.Type {
+			case EVENT_MESSAGE:
+				content := strings.TrimSpace(event.Content)
+				if content == "" || len([]rune(content)) > MaxChatMessageLength {
+					client.sendEvent(OutgoingEventSchema{Type: EVENT_ERROR, Data: EventErrorSchema{Message: "Message must have between 1 and 2000 characters"}})
+					return
+				}
+				message := chatManager.CreateMessage(db, ctx, courseObj, userObj, content)
+				hub.broadcast(courseObj.ID, OutgoingEventSchema{Type: EVENT_MESSAGE, Data: ChatMessageSchema{}.Assign(message)}, nil)
+			case EVENT_TYPING:
+				typing := TypingSchema{User: base.UserDataSchema{}.Assign(userObj), IsTyping: event.IsTyping}
+				hub.broadcast(courseObj.ID, OutgoingEventSchema{Type: EVENT_TYPING, Data: typing}, client)
+			default:
+				client.sendEvent(OutgoingEventSchema{Type: EVENT_ERROR, Data: EventErrorSchema{Message: "Unknown event type"}})
+			}
+		})
+	})
+	return func(c *fiber.Ctx) error {
+		courseObj, err := getChatCourse(db, c)
+		if err != nil {
+			return err
+		}
+		c.Locals("course", courseObj)
+		return socket(c)
+	}
+}
+
+// @Summary Retrieve Chat History
+// @Description `This endpoint retrieves paginated messages of a course's chat room, newest first`
+// @Tags Chats
+// @Param slug path string true "Course Slug"
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} ChatMessagesResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /chats/courses/{slug}/messages [get]
+// @Security BearerAuth
+func GetChatMessages(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		courseObj, err := getChatCourse(db, c)
+		if err != nil {
+			return err
+		}
+		messages := chatManager.GetMessagesPaginated(db, c, courseObj)
+		response := ChatMessagesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Messages Fetched Successfully"),
+		}.Assign(messages)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete A Chat Message
+// @Description `This endpoint allows the course's instructor or a chat moderator to delete a message. Members connected to the room are notified`
+// @Tags Chats
+// @Param id path string true "Message ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /chats/messages/{id} [delete]
+// @Security BearerAuth
+func DeleteChatMessage(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		messageID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		message := chatManager.GetMessage(db, ctx, *messageID)
+		if message == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Message Not Found"))
+		}
+		if !chatManager.CanModerate(db, ctx, user, message.Edges.Course) {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the instructor and moderators can delete messages"))
+		}
+		chatManager.DeleteMessage(db, ctx, message, user)
+		hub.broadcast(message.CourseID, OutgoingEventSchema{Type: EVENT_MESSAGE_DELETED, Data: MessageDeletedSchema{ID: message.ID}}, nil)
+		return c.Status(200).JSON(base.ResponseMessage("Message Deleted Successfully"))
+	}
+}
+
+// getConversation returns the conversation in the id path param if the user is one of its participants
+func getConversation(db *ent.Client, c *fiber.Ctx) (*ent.Conversation, error) {
+	conversationID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	conversationObj := dmManager.GetConversation(db, c.Context(), base.RequestUser(c), *conversationID)
+	if conversationObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Conversation Not Found"))
+	}
+	return conversationObj, nil
+}
+
+// otherParticipant returns the participant of the conversation that isn't the user
+func otherParticipant(conversationObj *ent.Conversation, userObj *ent.User) *ent.User {
+	if conversationObj.StudentID == userObj.ID {
+		return conversationObj.Edges.Instructor
+	}
+	return conversationObj.Edges.Student
+}
+
+// @Summary Connect To Inbox
+// @Description `This endpoint upgrades the connection to a websocket that receives the user's direct message events. Send the access token in the Authorization header or the token query param`
+// @Description `The socket is receive only. The server sends direct_message (DirectMessageSchema) and messages_read (MessagesReadSchema) events as {"type": "...", "data": {...}}`
+// @Description `Users without a connected inbox are treated as offline and get an email for new messages instead`
+// @Description `The session is checked again every minute, and the socket closes after an error event once it ends or the account is deactivated`
+// @Tags Chats
+// @Param token query string false "Access token, for clients that can't set headers"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 426 {object} base.InvalidErrorExample
+// @Router /chats/ws [get]
+// @Security BearerAuth
+func InboxSocket(db *ent.Client) fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		userObj := conn.Locals("user").(*ent.User)
+		token := conn.Locals("token").(string)
+		authorize := func() *string {
+			_, reason := socketUser(db, context.Background(), token)
+			return reason
+		}
+		userHub.serve(conn, userObj, userObj.ID, authorize, func(client *chatClient, frame []byte) {
+			client.sendEvent(OutgoingEventSchema{Type: EVENT_ERROR, Data: EventErrorSchema{Message: "This socket is receive only. Send messages through the messages endpoint"}})
+		})
+	})
+}
+
+// @Summary Retrieve Conversations
+// @Description `This endpoint retrieves the user's conversations, most recently active first, with their last message and unread count`
+// @Tags Chats
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} ConversationsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /chats/conversations [get]
+// @Security BearerAuth
+func GetConversations(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		conversations := dmManager.GetConversationsPaginated(db, c, user)
+		unreadCounts := dmManager.GetUnreadCounts(db, c.Context(), user, conversations.Items)
+		response := ConversationsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Conversations Fetched Successfully"),
+		}.Assign(conversations, unreadCounts)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Start A Conversation
+// @Description `This endpoint starts a private conversation between a student and the instructor of a course they're enrolled in`
+// @Description `Students only send the course slug. Instructors also send the username of a student enrolled in the course`
+// @Description `A student and an instructor share a single conversation, so the existing one is returned if they already have one`
+// @Tags Chats
+// @Param conversation body ConversationCreateSchema true "Conversation object"
+// @Success 201 {object} ConversationResponseSchema
+// @Success 200 {object} ConversationResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /chats/conversations [post]
+// @Security BearerAuth
+func StartConversation(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		data := ConversationCreateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		courseObj := courseManager.GetPublishedCourseBySlug(db, ctx, data.CourseSlug, false)
+		if courseObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
+		}
+
+		var student, instructor *ent.User
+		if courseObj.InstructorID == user.ID {
+			if data.StudentUsername == nil {
+				return config.APIError(c, 422, config.ValidationErr("student_username", "Set the student to message"))
+			}
+			student = userManager.GetByUsername(db, ctx, *data.StudentUsername)
+			if student == nil || !dmManager.IsEnrolled(db, ctx, student, courseObj) {
+				return config.APIError(c, 404, config.NotFoundErr("Student Not Found"))
+			}
+			instructor = user
+		} else {
+			if !dmManager.IsEnrolled(db, ctx, user, courseObj) {
+				return config.APIError(c, 403, config.ForbiddenErr("Only students enrolled in this course can message its instructor"))
+			}
+			student = user
+			instructor = userManager.GetById(db, ctx, courseObj.InstructorID)
+		}
+		if student.ID == instructor.ID {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_INVALID_REQUEST, "You can't start a conversation with yourself"))
+		}
+
+		conversationObj, created := dmManager.GetOrCreateConversation(db, ctx, student, instructor, courseObj)
+		statusCode := 200
+		message := "Conversation Fetched Successfully"
+		if created {
+			statusCode = 201
+			message = "Conversation Started Successfully"
+		}
+		unreadCounts := dmManager.GetUnreadCounts(db, ctx, user, []*ent.Conversation{conversationObj})
+		response := ConversationResponseSchema{
+			ResponseSchema: base.ResponseMessage(message),
+			Data:           ConversationSchema{}.Assign(conversationObj, unreadCounts[conversationObj.ID]),
+		}
+		return c.Status(statusCode).JSON(response)
+	}
+}
+
+// @Summary Retrieve Conversation Messages
+// @Description `This endpoint retrieves paginated messages of a conversation, newest first`
+// @Tags Chats
+// @Param id path string true "Conversation ID"
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} DirectMessagesResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /chats/conversations/{id}/messages [get]
+// @Security BearerAuth
+func GetConversationMessages(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		conversationObj, err := getConversation(db, c)
+		if err != nil {
+			return err
+		}
+		messages := dmManager.GetMessagesPaginated(db, c, conversationObj)
+		response := DirectMessagesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Messages Fetched Successfully"),
+		}.Assign(messages)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Send A Direct Message
+// @Description `This endpoint sends a message in a conversation. A message needs content, an attachment (image, pdf or mp4 video) or both`
+// @Description `The recipient gets a direct_message event on their inbox socket. If they're offline, they're emailed about the first message they haven't read`
+// @Description `Messages can only be sent while the student is enrolled in one of the instructor's courses`
+// @Tags Chats
+// @Accept multipart/form-data
+// @Param id path string true "Conversation ID"
+// @Param content formData string false "Message content"
+// @Param attachment formData file false "Attachment"
+// @Success 201 {object} DirectMessageResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /chats/conversations/{id}/messages [post]
+// @Security BearerAuth
+func SendDirectMessage(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		conversationObj, err := getConversation(db, c)
+		if err != nil {
+			return err
+		}
+		// Checked on every send, since the conversation outlives a refunded or deleted enrollment
+		if !dmManager.StudentStillEnrolled(db, ctx, conversationObj) {
+			return config.APIError(c, 403, config.ForbiddenErr("The student is no longer enrolled in any of the instructor's courses"))
+		}
+		data := DirectMessageCreateSchema{}
+		if errCode, errData := config.ValidateFormRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		file, errData := config.ValidateAttachment(c, "attachment")
+		if errData != nil {
+			return c.Status(422).JSON(errData)
+		}
+		content := strings.TrimSpace(data.Content)
+		if content == "" && file == nil {
+			return config.APIError(c, 422, config.ValidationErr("content", "Send some content or an attachment"))
+		}
+		var attachmentUrl *string
+		if file != nil {
+			url := config.UploadFile(file, string(config.FF_CHAT_ATTACHMENTS))
+			attachmentUrl = &url
+		}
+
+		recipient := otherParticipant(conversationObj, user)
+		// Only the first unread message is emailed, so an offline recipient isn't flooded while the sender keeps typing
+		notifyByEmail := !userHub.isActive(recipient.ID) && !dmManager.HasUnreadFrom(db, ctx, conversationObj, user)
+		message := dmManager.CreateMessage(db, ctx, conversationObj, user, content, attachmentUrl)
+		messageData := DirectMessageSchema{}.Assign(message)
+		userHub.broadcast(recipient.ID, OutgoingEventSchema{Type: EVENT_DIRECT_MESSAGE, Data: messageData}, nil)
+		if notifyByEmail {
+			preview := content
+			if preview == "" {
+				preview = "Sent an attachment"
+			}
+			go config.SendMessageEmail(recipient, config.ET_NEW_DIRECT_MESSAGE, fmt.Sprintf("%s: %s", user.Name, preview))
+		}
+		response := DirectMessageResponseSchema{
+			ResponseSchema: base.ResponseMessage("Message Sent Successfully"),
+			Data:           messageData,
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Mark Conversation As Read
+// @Description `This endpoint marks every message the other participant sent in the conversation as read. They get a messages_read event on their inbox socket`
+// @Tags Chats
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} MessagesReadResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /chats/conversations/{id}/read [post]
+// @Security BearerAuth
+func MarkConversationRead(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		conversationObj, err := getConversation(db, c)
+		if err != nil {
+			return err
+		}
+		readAt := dmManager.MarkRead(db, c.Context(), conversationObj, user)
+		receipt := MessagesReadSchema{
+			ConversationID: conversationObj.ID,
+			Reader:         base.UserDataSchema{}.Assign(user),
+			ReadAt:         readAt,
+		}
+		userHub.broadcast(otherParticipant(conversationObj, user).ID, OutgoingEventSchema{Type: EVENT_MESSAGES_READ, Data: receipt}, nil)
+		response := MessagesReadResponseSchema{
+			ResponseSchema: base.ResponseMessage("Messages Marked As Read"),
+			Data:           receipt,
+		}
+		return c.Status(200).JSON(response)
+	}
+}
@@ -0,0 +1,232 @@
+package chats
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+// Each course has a room holding the sockets currently connected to its chat. Each user also has a personal room
+// holding their inbox sockets, which is also how we know whether they're online.
+// Rooms live in memory, so members of a room must be connected to the same instance. Running several
+// instances would need a shared pub/sub to fan events out across them.
+
+const (
+	clientSendBuffer = 32               // Events queued for a client before it's treated as too slow and frames are dropped
+	writeWait        = 10 * time.Second // Time allowed to write a frame
+	pongWait         = 60 * time.Second // Time allowed between pongs before the connection is considered dead
+	pingPeriod       = 50 * time.Second // Must be less than pongWait
+	maxFrameSize     = 8 * 1024
+	frameRateLimit   = 20 // Frames a client may send per frameRateWindow. Extra frames are rejected
+	frameRateWindow  = 10 * time.Second
+	recheckPeriod    = time.Minute // How often a connected client's access is checked again
+)
+
+var errAccessRevoked = errors.New("access revoked")
+
+type chatClient struct {
+	conn *websocket.Conn
+	user *ent.User
+	send chan []byte
+
+	// Read loop only
+	windowStart  time.Time
+	windowFrames int
+
+	revoked atomic.Bool
+}
+
+type chatHub struct {
+	mu    sync.RWMutex
+	rooms map[uuid.UUID]map[*chatClient]struct{}
+}
+
+var hub = &chatHub{rooms: map[uuid.UUID]map[*chatClient]struct{}{}}     // Course rooms
+var userHub = &chatHub{rooms: map[uuid.UUID]map[*chatClient]struct{}{}} // Personal rooms, keyed by user id
+
+func (h *chatHub) join(roomID uuid.UUID, client *chatClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[roomID] == nil {
+		h.rooms[roomID] = map[*chatClient]struct{}{}
+	}
+	h.rooms[roomID][client] = struct{}{}
+}
+
+// leave removes the client from the room and closes its send channel, which stops its writer
+func (h *chatHub) leave(roomID uuid.UUID, client *chatClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room := h.rooms[roomID]
+	if _, ok := room[client]; !ok {
+		return
+	}
+	delete(room, client)
+	close(client.send)
+	if len(room) == 0 {
+		delete(h.rooms, roomID)
+	}
+}
+
+// broadcast sends the event to every client in the room except the given one (which may be nil)
+func (h *chatHub) broadcast(roomID uuid.UUID, event OutgoingEventSchema, except *chatClient) {
+	payload, _ := json.Marshal(event)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.rooms[roomID] {
+		if client != except {
+			client.queue(payload)
+		}
+	}
+}
+
+// isActive reports whether any client is connected to the room
+func (h *chatHub) isActive(roomID uuid.UUID) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[roomID]) > 0
+}
+
+// queue never blocks: a client that can't keep up misses the frame rather than stalling the room
+func (c *chatClient) queue(payload []byte) {
+	select {
+	case c.send <- payload:
+	default:
+	}
+}
+
+// sendEvent replies to this client only. It must be called from the client's read loop or checker, which end before it leaves
+func (c *chatClient) sendEvent(event OutgoingEventSchema) {
+	payload, _ := json.Marshal(event)
+	c.queue(payload)
+}
+
+func (c *chatClient) sendError(message string) {
+	c.sendEvent(OutgoingEventSchema{Type: EVENT_ERROR, Data: EventErrorSchema{Message: message}})
+}
+
+// serve joins the connection to the room and passes every frame it reads to handle until the connection closes.
+// authorize is called every recheckPeriod and disconnects the client once it returns a reason, e.g when its session ends
+func (h *chatHub) serve(conn *websocket.Conn, user *ent.User, roomID uuid.UUID, authorize func() *string, handle func(client *chatClient, frame []byte)) {
+	client := &chatClient{conn: conn, user: user, send: make(chan []byte, clientSendBuffer)}
+	h.join(roomID, client)
+	writerDone := make(chan struct{})
+	go func() {
+		client.writePump()
+		close(writerDone)
+	}()
+	stopChecks := make(chan struct{})
+	checksDone := make(chan struct{})
+	go func() {
+		client.recheck(authorize, stopChecks)
+		close(checksDone)
+	}()
+	defer func() {
+		// The checker may still queue events, so it must stop before leave closes the send channel
+		close(stopChecks)
+		<-checksDone
+		h.leave(roomID, client)
+		<-writerDone
+	}()
+
+	conn.SetReadLimit(maxFrameSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		if client.revoked.Load() {
+			return errAccessRevoked
+		}
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if !client.allowFrame() {
+			client.sendError("Too many events. Slow down")
+			continue
+		}
+		client.run(func() { handle(client, frame) })
+	}
+}
+
+// allowFrame counts a frame against the client's rate limit
+func (c *chatClient) allowFrame() bool {
+	now := time.Now()
+	if now.Sub(c.windowStart) >= frameRateWindow {
+		c.windowStart = now
+		c.windowFrames = 0
+	}
+	c.windowFrames++
+	return c.windowFrames <= frameRateLimit
+}
+
+// run calls fn and turns a panic into an error event. Sockets run outside of fiber's handlers,
+// so RecoveryMiddleware doesn't cover them and a panic would take the whole server down
+func (c *chatClient) run(fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[PANIC] %v\n", r)
+			c.sendError("Something went wrong!")
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+// recheck calls authorize every recheckPeriod until stop is closed. Once access is lost the client gets the reason
+// and its read deadline is cut short, which ends serve's read loop
+func (c *chatClient) recheck(authorize func() *string, stop <-chan struct{}) {
+	ticker := time.NewTicker(recheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var reason *string
+			if !c.run(func() { reason = authorize() }) || reason == nil {
+				continue
+			}
+			c.sendError(*reason)
+			c.revoked.Store(true)
+			c.conn.SetReadDeadline(time.Now())
+			return
+		}
+	}
+}
+
+// writePump writes queued events and keepalive pings to the socket until the send channel is closed
+func (c *chatClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package chats
+
+import (
+	"context"
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/chatmessage"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/conversation"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/directmessage"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+)
+
+type ChatManager struct{}
+
+// CanModerate reports whether the user can delete messages in the course's room: its instructor and chat moderators
+func (m ChatManager) CanModerate(db *ent.Client, ctx context.Context, userObj *ent.User, courseObj *ent.Course) bool {
+	if courseObj.InstructorID == userObj.ID {
+		return true
+	}
+	return accounts.HasPermission(userManager.GetPermissions(db, ctx, userObj), accounts.PERM_CHAT_MODERATE)
+}
+
+// CanJoin reports whether the user can read and post in the course's room: moderators and students with a successful enrollment
+func (m ChatManager) CanJoin(db *ent.Client, ctx context.Context, userObj *ent.User, courseObj *ent.Course) bool {
+	if m.CanModerate(db, ctx, userObj, courseObj) {
+		return true
+	}
+	enrollmentObj := courseManager.GetExistentEnrollmentByUserAndCourse(db, ctx, userObj, courseObj, false)
+	return enrollmentObj != nil && enrollmentObj.PaymentStatus == enrollment.PaymentStatusSuccessful
+}
+
+func (m ChatManager) GetMessagesPaginated(db *ent.Client, fibCtx *fiber.Ctx, courseObj *ent.Course) *config.PaginationResponse[*ent.ChatMessage] {
+	query := db.ChatMessage.Query().
+		Where(chatmessage.CourseID(courseObj.ID), chatmessage.DeletedAtIsNil()).
+		WithSender().
+		Order(ent.Desc(chatmessage.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (m ChatManager) GetMessage(db *ent.Client, ctx context.Context, messageID uuid.UUID) *ent.ChatMessage {
+	message, _ := db.ChatMessage.Query().
+		Where(chatmessage.ID(messageID), chatmessage.DeletedAtIsNil()).
+		WithCourse().
+		Only(ctx)
+	return message
+}
+
+func (m ChatManager) CreateMessage(db *ent.Client, ctx context.Context, courseObj *ent.Course, sender *ent.User, content string) *ent.ChatMessage {
+	message := db.ChatMessage.Create().
+		SetCourseID(courseObj.ID).
+		SetSenderID(sender.ID).
+		SetContent(content).
+		SaveX(ctx)
+	message.Edges.Sender = sender
+	return message
+}
+
+func (m ChatManager) DeleteMessage(db *ent.Client, ctx context.Context, message *ent.ChatMessage, moderator *ent.User) {
+	message.Update().SetDeletedAt(time.Now()).SetDeletedBy(moderator.ID).ExecX(ctx)
+}
+
+// ------------------------------------------------------------------
+// DIRECT MESSAGES
+// ------------------------------------------------------------------
+
+type DirectMessageManager struct{}
+
+// IsEnrolled reports whether the user has a successful enrollment in the course
+func (m DirectMessageManager) IsEnrolled(db *ent.Client, ctx context.Context, userObj *ent.User, courseObj *ent.Course) bool {
+	enrollmentObj := courseManager.GetExistentEnrollmentByUserAndCourse(db, ctx, userObj, courseObj, false)
+	return enrollmentObj != nil && enrollmentObj.PaymentStatus == enrollment.PaymentStatusSuccessful
+}
+
+// StudentStillEnrolled reports whether the conversation's student still has a successful enrollment in one of its instructor's courses
+func (m DirectMessageManager) StudentStillEnrolled(db *ent.Client, ctx context.Context, conversationObj *ent.Conversation) bool {
+	return db.Enrollment.Query().
+		Where(
+			enrollment.UserID(conversationObj.StudentID),
+			enrollment.PaymentStatusEQ(enrollment.PaymentStatusSuccessful),
+			enrollment.HasCourseWith(course.InstructorID(conversationObj.InstructorID)),
+		).
+		ExistX(ctx)
+}
+
+func (m DirectMessageManager) loadConversation(query *ent.ConversationQuery) *ent.ConversationQuery {
+	return query.WithStudent().WithInstructor().WithCourse()
+}
+
+// GetConversation returns the conversation if the user is one of its participants
+func (m DirectMessageManager) GetConversation(db *ent.Client, ctx context.Context, userObj *ent.User, conversationID uuid.UUID) *ent.Conversation {
+	conversationObj, _ := m.loadConversation(db.Conversation.Query()).
+		Where(
+			conversation.ID(conversationID),
+			conversation.Or(conversation.StudentID(userObj.ID), conversation.InstructorID(userObj.ID)),
+		).
+		Only(ctx)
+	return conversationObj
+}
+
+// GetOrCreateConversation returns the student and instructor's conversation, starting it from the course if they have none.
+// The bool is true when it was created
+func (m DirectMessageManager) GetOrCreateConversation(db *ent.Client, ctx context.Context, student *ent.User, instructor *ent.User, courseObj *ent.Course) (*ent.Conversation, bool) {
+	query := db.Conversation.Query().Where(conversation.StudentID(student.ID), conversation.InstructorID(instructor.ID))
+	conversationObj, _ := m.loadConversation(query).Only(ctx)
+	if conversationObj != nil {
+		return conversationObj, false
+	}
+	conversationObj = db.Conversation.Create().
+		SetStudentID(student.ID).
+		SetInstructorID(instructor.ID).
+		SetCourseID(courseObj.ID).
+		SaveX(ctx)
+	conversationObj.Edges.Student = student
+	conversationObj.Edges.Instructor = instructor
+	conversationObj.Edges.Course = courseObj
+	return conversationObj, true
+}
+
+// GetConversationsPaginated returns the user's conversations, most recently active first, with their last messages loaded
+func (m DirectMessageManager) GetConversationsPaginated(db *ent.Client, fibCtx *fiber.Ctx, userObj *ent.User) *config.PaginationResponse[*ent.Conversation] {
+	ctx := fibCtx.Context()
+	query := m.loadConversation(db.Conversation.Query()).
+		Where(conversation.Or(conversation.StudentID(userObj.ID), conversation.InstructorID(userObj.ID))).
+		Order(
+			conversation.ByLastMessageAt(sql.OrderDesc(), sql.OrderNullsLast()),
+			conversation.ByCreatedAt(sql.OrderDesc()),
+		)
+	conversations := config.PaginateModel(fibCtx, query)
+	for _, conversationObj := range conversations.Items {
+		lastMessage, _ := db.DirectMessage.Query().
+			Where(directmessage.ConversationID(conversationObj.ID)).
+			WithSender().
+			Order(ent.Desc(directmessage.FieldCreatedAt)).
+			First(ctx)
+		if lastMessage != nil {
+			conversationObj.Edges.Messages = []*ent.DirectMessage{lastMessage}
+		}
+	}
+	return conversations
+}
+
+// GetUnreadCounts returns how many messages the user hasn't read in each of the conversations
+func (m DirectMessageManager) GetUnreadCounts(db *ent.Client, ctx context.Context, userObj *ent.User, conversations []*ent.Conversation) map[uuid.UUID]int {
+	conversationIDs := make([]uuid.UUID, 0, len(conversations))
+	for _, conversationObj := range conversations {
+		conversationIDs = append(conversationIDs, conversationObj.ID)
+	}
+	rows := []struct {
+		ConversationID uuid.UUID `json:"conversation_id"`
+		Count          int       `json:"count"`
+	}{}
+	db.DirectMessage.Query().
+		Where(
+			directmessage.ConversationIDIn(conversationIDs...),
+			directmessage.SenderIDNEQ(userObj.ID),
+			directmessage.ReadAtIsNil(),
+		).
+		GroupBy(directmessage.FieldConversationID).
+		Aggregate(ent.Count()).
+		ScanX(ctx, &rows)
+	unreadCounts := map[uuid.UUID]int{}
+	for _, row := range rows {
+		unreadCounts[row.ConversationID] = row.Count
+	}
+	return unreadCounts
+}
+
+func (m DirectMessageManager) GetMessagesPaginated(db *ent.Client, fibCtx *fiber.Ctx, conversationObj *ent.Conversation) *config.PaginationResponse[*ent.DirectMessage] {
+	query := db.DirectMessage.Query().
+		Where(directmessage.ConversationID(conversationObj.ID)).
+		WithSender().
+		Order(ent.Desc(directmessage.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+// HasUnreadFrom reports whether the conversation has messages from the sender that the recipient hasn't read yet
+func (m DirectMessageManager) HasUnreadFrom(db *ent.Client, ctx context.Context, conversationObj *ent.Conversation, sender *ent.User) bool {
+	exists, _ := db.DirectMessage.Query().
+		Where(
+			directmessage.ConversationID(conversationObj.ID),
+			directmessage.SenderID(sender.ID),
+			directmessage.ReadAtIsNil(),
+		).
+		Exist(ctx)
+	return exists
+}
+
+func (m DirectMessageManager) CreateMessage(db *ent.Client, ctx context.Context, conversationObj *ent.Conversation, sender *ent.User, content string, attachmentUrl *string) *ent.DirectMessage {
+	message := db.DirectMessage.Create().
+		SetConversationID(conversationObj.ID).
+		SetSenderID(sender.ID).
+		SetContent(content).
+		SetNillableAttachmentURL(attachmentUrl).
+		SaveX(ctx)
+	conversationObj.Update().SetLastMessageAt(message.CreatedAt).ExecX(ctx)
+	message.Edges.Sender = sender
+	return message
+}
+
+// MarkRead marks every message the other participant sent in the conversation as read by the user
+func (m DirectMessageManager) MarkRead(db *ent.Client, ctx context.Context, conversationObj *ent.Conversation, reader *ent.User) time.Time {
+	readAt := time.Now()
+	db.DirectMessage.Update().
+		Where(
+			directmessage.ConversationID(conversationObj.ID),
+			directmessage.SenderIDNEQ(reader.ID),
+			directmessage.ReadAtIsNil(),
+		).
+		SetReadAt(readAt).
+		ExecX(ctx)
+	return readAt
+}
@@ -0,0 +1,21 @@
+package chats
+
+import (
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
+)
+
+// SocketAuthMiddleware authenticates a websocket upgrade with the user's access token.
+// Browsers can't set headers on websocket requests, so the token may also be passed in the token query param.
+func SocketAuthMiddleware(db *ent.Client) fiber.Handler {
+	auth := accounts.QueryTokenAuthMiddleware(db)
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return config.APIError(c, fiber.StatusUpgradeRequired, config.RequestErr(config.ERR_INVALID_REQUEST, "Expected a websocket upgrade request"))
+		}
+		return auth(c)
+	}
+}
@@ -0,0 +1,199 @@
+package chats
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+)
+
+// Types of the events exchanged over a chat socket
+const (
+	EVENT_MESSAGE         = "message"         // client -> server: send a message. server -> client: a new message
+	EVENT_TYPING          = "typing"          // client -> server: typing started/stopped. server -> client: someone else is typing
+	EVENT_MESSAGE_DELETED = "message_deleted" // server -> client: a moderator deleted a message
+	EVENT_ERROR           = "error"           // server -> client: the last event was rejected
+	EVENT_DIRECT_MESSAGE  = "direct_message"  // server -> client: a new message in one of the user's conversations
+	EVENT_MESSAGES_READ   = "messages_read"   // server -> client: the other party read the user's messages
+)
+
+const MaxChatMessageLength = 2000
+
+type ChatMessageSchema struct {
+	ID        uuid.UUID           `json:"id"`
+	Sender    base.UserDataSchema `json:"sender"`
+	Content   string              `json:"content" example:"Has anyone finished lesson 3?"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+func (c ChatMessageSchema) Assign(message *ent.ChatMessage) ChatMessageSchema {
+	c.ID = message.ID
+	c.Sender = c.Sender.Assign(message.Edges.Sender)
+	c.Content = message.Content
+	c.CreatedAt = message.CreatedAt
+	return c
+}
+
+type ChatMessagesResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[ChatMessageSchema] `json:"data"`
+}
+
+func (c ChatMessagesResponseSchema) Assign(messagesData *config.PaginationResponse[*ent.ChatMessage]) ChatMessagesResponseSchema {
+	items := make([]ChatMessageSchema, 0)
+	for _, message := range messagesData.Items {
+		items = append(items, ChatMessageSchema{}.Assign(message))
+	}
+	c.Data.Items = items
+	c.Data.ItemsCount = messagesData.ItemsCount
+	c.Data.Page = messagesData.Page
+	c.Data.TotalPages = messagesData.TotalPages
+	c.Data.Limit = messagesData.Limit
+	return c
+}
+
+// IncomingEventSchema is a frame sent by a client over the socket
+type IncomingEventSchema struct {
+	Type     string `json:"type" example:"message"`
+	Content  string `json:"content" example:"Has anyone finished lesson 3?"` // For message events
+	IsTyping bool   `json:"is_typing" example:"true"`                        // For typing events
+}
+
+// OutgoingEventSchema is a frame sent by the server over the socket
+type OutgoingEventSchema struct {
+	Type string      `json:"type" example:"message"`
+	Data interface{} `json:"data"`
+}
+
+type TypingSchema struct {
+	User     base.UserDataSchema `json:"user"`
+	IsTyping bool                `json:"is_typing" example:"true"`
+}
+
+type MessageDeletedSchema struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type EventErrorSchema struct {
+	Message string `json:"message" example:"Message cannot be empty"`
+}
+
+type ConversationCreateSchema struct {
+	CourseSlug      string  `json:"course_slug" validate:"required" example:"introduction-to-go"`
+	StudentUsername *string `json:"student_username" example:"john-doe"` // Required when the instructor starts the conversation
+}
+
+type ConversationCourseSchema struct {
+	Title string `json:"title" example:"Introduction To Go"`
+	Slug  string `json:"slug" example:"introduction-to-go"`
+}
+
+type DirectMessageSchema struct {
+	ID             uuid.UUID           `json:"id"`
+	ConversationID uuid.UUID           `json:"conversation_id"`
+	Sender         base.UserDataSchema `json:"sender"`
+	Content        string              `json:"content" example:"Hi, I have a question about lesson 3"`
+	AttachmentUrl  *string             `json:"attachment_url" example:"https://file.url"`
+	ReadAt         *time.Time          `json:"read_at"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+func (d DirectMessageSchema) Assign(message *ent.DirectMessage) DirectMessageSchema {
+	d.ID = message.ID
+	d.ConversationID = message.ConversationID
+	d.Sender = d.Sender.Assign(message.Edges.Sender)
+	d.Content = message.Content
+	d.AttachmentUrl = message.AttachmentURL
+	d.ReadAt = message.ReadAt
+	d.CreatedAt = message.CreatedAt
+	return d
+}
+
+type ConversationSchema struct {
+	ID            uuid.UUID                `json:"id"`
+	Student       base.UserDataSchema      `json:"student"`
+	Instructor    base.UserDataSchema      `json:"instructor"`
+	Course        ConversationCourseSchema `json:"course"`
+	LastMessage   *DirectMessageSchema     `json:"last_message"`
+	UnreadCount   int                      `json:"unread_count" example:"2"`
+	LastMessageAt *time.Time               `json:"last_message_at"`
+	CreatedAt     time.Time                `json:"created_at"`
+}
+
+func (c ConversationSchema) Assign(conversation *ent.Conversation, unreadCount int) ConversationSchema {
+	c.ID = conversation.ID
+	c.Student = c.Student.Assign(conversation.Edges.Student)
+	c.Instructor = c.Instructor.Assign(conversation.Edges.Instructor)
+	c.Course = ConversationCourseSchema{Title: conversation.Edges.Course.Title, Slug: conversation.Edges.Course.Slug}
+	if len(conversation.Edges.Messages) > 0 {
+		lastMessage := DirectMessageSchema{}.Assign(conversation.Edges.Messages[0])
+		c.LastMessage = &lastMessage
+	}
+	c.UnreadCount = unreadCount
+	c.LastMessageAt = conversation.LastMessageAt
+	c.CreatedAt = conversation.CreatedAt
+	return c
+}
+
+type ConversationResponseSchema struct {
+	base.ResponseSchema
+	Data ConversationSchema `json:"data"`
+}
+
+type ConversationsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[ConversationSchema] `json:"data"`
+}
+
+func (c ConversationsResponseSchema) Assign(conversationsData *config.PaginationResponse[*ent.Conversation], unreadCounts map[uuid.UUID]int) ConversationsResponseSchema {
+	items := make([]ConversationSchema, 0)
+	for _, conversation := range conversationsData.Items {
+		items = append(items, ConversationSchema{}.Assign(conversation, unreadCounts[conversation.ID]))
+	}
+	c.Data.Items = items
+	c.Data.ItemsCount = conversationsData.ItemsCount
+	c.Data.Page = conversationsData.Page
+	c.Data.TotalPages = conversationsData.TotalPages
+	c.Data.Limit = conversationsData.Limit
+	return c
+}
+
+type DirectMessageCreateSchema struct {
+	Content string `form:"content" validate:"max=2000" example:"Hi, I have a question about lesson 3"`
+}
+
+type DirectMessageResponseSchema struct {
+	base.ResponseSchema
+	Data DirectMessageSchema `json:"data"`
+}
+
+type DirectMessagesResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[DirectMessageSchema] `json:"data"`
+}
+
+func (d DirectMessagesResponseSchema) Assign(messagesData *config.PaginationResponse[*ent.DirectMessage]) DirectMessagesResponseSchema {
+	items := make([]DirectMessageSchema, 0)
+	for _, message := range messagesData.Items {
+		items = append(items, DirectMessageSchema{}.Assign(message))
+	}
+	d.Data.Items = items
+	d.Data.ItemsCount = messagesData.ItemsCount
+	d.Data.Page = messagesData.Page
+	d.Data.TotalPages = messagesData.TotalPages
+	d.Data.Limit = messagesData.Limit
+	return d
+}
+
+type MessagesReadSchema struct {
+	ConversationID uuid.UUID           `json:"conversation_id"`
+	Reader         base.UserDataSchema `json:"reader"`
+	ReadAt         time.Time           `json:"read_at"`
+}
+
+type MessagesReadResponseSchema struct {
+	base.ResponseSchema
+	Data MessagesReadSchema `json:"data"`
+}
@@ -18,10 +18,16 @@ import (
 	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/lesson"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/lessonprogress"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/payment"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/questionoption"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/quiz"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/quizresult"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/review"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewreport"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewvote"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/section"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/tag"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/courses/certs"
 )
@@ -43,9 +49,42 @@ func (c CourseManager) GetCategoryBySlug(db *ent.Client, ctx context.Context, sl
 	return category
 }
 
+// GetCategoriesWithCourses returns every category along with the ids of its published courses, for counting
+func (c CourseManager) GetCategoriesWithCourses(db *ent.Client, ctx context.Context) []*ent.Category {
+	return db.Category.Query().
+		WithCourses(func(q *ent.CourseQuery) {
+			q.Where(course.IsPublishedEQ(true)).Select(course.FieldID)
+		}).
+		Order(ent.Asc(category.FieldName)).
+		AllX(ctx)
+}
+
+func (c CourseManager) GetTagBySlug(db *ent.Client, ctx context.Context, slug string) *ent.Tag {
+	tagObj, _ := db.Tag.Query().Where(tag.SlugEQ(slug)).Only(ctx)
+	return tagObj
+}
+
+// GetTagsBySlugs returns the tags matching the slugs. Unknown slugs are skipped
+func (c CourseManager) GetTagsBySlugs(db *ent.Client, ctx context.Context, slugs []string) []*ent.Tag {
+	return db.Tag.Query().Where(tag.SlugIn(slugs...)).AllX(ctx)
+}
+
+// GetTagsWithCourses returns every tag along with the ids of its published courses, for counting
+func (c CourseManager) GetTagsWithCourses(db *ent.Client, ctx context.Context) []*ent.Tag {
+	return db.Tag.Query().
+		WithCourses(func(q *ent.CourseQuery) {
+			q.Where(course.IsPublishedEQ(true)).Select(course.FieldID)
+		}).
+		Order(ent.Asc(tag.FieldName)).
+		AllX(ctx)
+}
+
 func (c CourseManager) ApplyCourseFilters(fibCtx *fiber.Ctx, query *ent.CourseQuery) *ent.CourseQuery {
 	filters := map[string]func(string){
-		"title": func(value string) { query.Where(course.TitleContainsFold(value)) },
+		"title":    func(value string) { query.Where(course.TitleContainsFold(value)) },
+		"category": func(value string) { query.Where(course.HasCategoryWith(category.SlugEQ(value))) },
+		"tag":      func(value string) { query.Where(course.HasTagsWith(tag.SlugEQ(value))) },
+		"status":   func(value string) { query.Where(course.StatusEQ(course.Status(value))) },
 		"instructor": func(value string) {
 			query.Where(course.HasInstructorWith(user.Or(user.NameContainsFold(value), user.UsernameContainsFold(value))))
 		},
@@ -106,7 +145,7 @@ func (c CourseManager) GetAllPaginated(db *ent.Client, fibCtx *fiber.Ctx) *confi
 		WithInstructor().
 		WithCategory().
 		WithTags().
-		WithReviews().
+		WithReviews(VisibleReviews).
 		WithEnrollments().
 		WithLessons()
 
@@ -143,12 +182,21 @@ func (c CourseManager) GetCourseBySlug(db *ent.Client, ctx context.Context, slug
 			WithCategory().
 			WithTags().
 			WithEnrollments().
-			WithReviews()
+			WithReviews(VisibleReviews)
 	}
 	course, _ := query.Only(ctx)
 	return course
 }
 
+// GetPublishedCourseBySlug is GetCourseBySlug for the public and student paths. Courses that aren't live are not found.
+func (c CourseManager) GetPublishedCourseBySlug(db *ent.Client, ctx context.Context, slug string, loaded bool) *ent.Course {
+	courseObj := c.GetCourseBySlug(db, ctx, slug, nil, loaded)
+	if courseObj == nil || courseObj.Status != course.StatusPublished || !courseObj.IsPublished {
+		return nil
+	}
+	return courseObj
+}
+
 func (c CourseManager) ApplyLessonFilters(fibCtx *fiber.Ctx, query *ent.LessonQuery) *ent.LessonQuery {
 	filters := map[string]func(string){
 		"title": func(value string) { query.Where(lesson.TitleContainsFold(value)) },
@@ -174,6 +222,21 @@ func (c CourseManager) GetLessons(db *ent.Client, course *ent.Course, fibCtx *fi
 	return lessons
 }
 
+// GetCurriculum returns the course's sections with their lessons, and the lessons that aren't in any section, all in order
+func (c CourseManager) GetCurriculum(db *ent.Client, ctx context.Context, course *ent.Course) ([]*ent.Section, []*ent.Lesson) {
+	sections := db.Section.Query().
+		Where(section.CourseID(course.ID)).
+		WithLessons(func(lq *ent.LessonQuery) { lq.Order(ent.Asc(lesson.FieldOrder)).WithPrerequisite() }).
+		Order(ent.Asc(section.FieldOrder)).
+		AllX(ctx)
+	unsectionedLessons := db.Lesson.Query().
+		Where(lesson.CourseID(course.ID), lesson.SectionIDIsNil()).
+		Order(ent.Asc(lesson.FieldOrder)).
+		WithPrerequisite().
+		AllX(ctx)
+	return sections, unsectionedLessons
+}
+
 func (c CourseManager) ApplyQuizFilters(fibCtx *fiber.Ctx, query *ent.QuizQuery) *ent.QuizQuery {
 	filters := map[string]func(string){
 		"title": func(value string) { query.Where(quiz.TitleContainsFold(value)) },
@@ -233,7 +296,8 @@ func (c CourseManager) GetCourseLessonBySlug(db *ent.Client, ctx context.Context
 	if loaded {
 		query = query.
 			WithQuizzes().
-			WithCourse()
+			WithCourse().
+			WithPrerequisite()
 	}
 	lesson, _ := query.Only(ctx)
 	return lesson
@@ -254,6 +318,130 @@ func (c CourseManager) GetExistentEnrollmentByUserAndCourse(db *ent.Client, ctx
 	return enrollmentObj
 }
 
+// HasAccess reports whether the user can take part in the course's lessons: its instructor and students with a successful enrollment
+func (c CourseManager) HasAccess(db *ent.Client, ctx context.Context, user *ent.User, course *ent.Course) bool {
+	if course.InstructorID == user.ID {
+		return true
+	}
+	enrollmentObj := c.GetExistentEnrollmentByUserAndCourse(db, ctx, user, course, false)
+	return enrollmentObj != nil && enrollmentObj.PaymentStatus == enrollment.PaymentStatusSuccessful
+}
+
+// LessonGate is where a lesson's unlock conditions stand for a user
+type LessonGate struct {
+	IsLocked  bool
+	UnlocksAt *time.Time // When the time conditions are met. Nil when it can't be known yet, as before enrollment
+	Reason    string     // Why the lesson is still locked
+}
+
+// LessonGates works out which of the course's lessons are still locked for the user, who may be nil.
+// Lessons without unlock conditions are left out, and the course's instructor has every lesson unlocked.
+func (c CourseManager) LessonGates(db *ent.Client, ctx context.Context, user *ent.User, courseObj *ent.Course, lessons []*ent.Lesson) map[uuid.UUID]LessonGate {
+	gates := make(map[uuid.UUID]LessonGate)
+	gated := make([]*ent.Lesson, 0)
+	for _, lessonObj := range lessons {
+		if lessonObj.UnlockAfterDays != nil || lessonObj.UnlockAt != nil || lessonObj.PrerequisiteID != nil {
+			gated = append(gated, lessonObj)
+		}
+	}
+	if len(gated) == 0 || (user != nil && user.ID == courseObj.InstructorID) {
+		return gates
+	}
+
+	var enrolledAt *time.Time
+	if user != nil {
+		enrollmentObj := c.GetExistentEnrollmentByUserAndCourse(db, ctx, user, courseObj, false)
+		if enrollmentObj != nil && enrollmentObj.PaymentStatus == enrollment.PaymentStatusSuccessful {
+			enrolledAt = &enrollmentObj.CreatedAt
+		}
+	}
+	metPrerequisites := c.metPrerequisites(db, ctx, user, gated)
+	now := time.Now()
+	for _, lessonObj := range gated {
+		unlocksAt := lessonObj.UnlockAt
+		if lessonObj.UnlockAfterDays != nil {
+			unlocksAt = nil
+			if enrolledAt != nil {
+				afterDays := enrolledAt.AddDate(0, 0, int(*lessonObj.UnlockAfterDays))
+				unlocksAt = &afterDays
+				if lessonObj.UnlockAt != nil && lessonObj.UnlockAt.After(afterDays) {
+					unlocksAt = lessonObj.UnlockAt
+				}
+			}
+		}
+
+		gate := LessonGate{UnlocksAt: unlocksAt}
+		switch {
+		case enrolledAt == nil:
+			gate.Reason = "Only for enrolled users"
+		case unlocksAt != nil && unlocksAt.After(now):
+			gate.Reason = fmt.Sprintf("This lesson unlocks on %s", unlocksAt.Format(time.RFC1123))
+		case lessonObj.PrerequisiteID != nil && !metPrerequisites[lessonObj.ID]:
+			gate.Reason = "Complete the prerequisite lesson first"
+			if lessonObj.PrerequisiteMinScore != nil {
+				gate.Reason = fmt.Sprintf("Score at least %.0f%% on the prerequisite lesson's quizzes first", *lessonObj.PrerequisiteMinScore)
+			}
+		}
+		gate.IsLocked = gate.Reason != ""
+		gates[lessonObj.ID] = gate
+	}
+	return gates
+}
+
+// metPrerequisites reports, for each of the lessons with a prerequisite, whether the user has met it.
+// That's completing the prerequisite or, with a minimum score, scoring it on each of the prerequisite's published quizzes.
+// A prerequisite without published quizzes only needs to be completed.
+func (c CourseManager) metPrerequisites(db *ent.Client, ctx context.Context, user *ent.User, lessons []*ent.Lesson) map[uuid.UUID]bool {
+	met := make(map[uuid.UUID]bool)
+	prerequisiteIDs := make([]uuid.UUID, 0)
+	for _, lessonObj := range lessons {
+		if lessonObj.PrerequisiteID != nil {
+			prerequisiteIDs = append(prerequisiteIDs, *lessonObj.PrerequisiteID)
+		}
+	}
+	if user == nil || len(prerequisiteIDs) == 0 {
+		return met
+	}
+
+	completed := make(map[uuid.UUID]bool)
+	progress := db.LessonProgress.Query().
+		Where(lessonprogress.UserID(user.ID), lessonprogress.LessonIDIn(prerequisiteIDs...), lessonprogress.CompletedAtNotNil()).
+		AllX(ctx)
+	for _, lessonProgress := range progress {
+		completed[lessonProgress.LessonID] = true
+	}
+	quizzes := db.Quiz.Query().
+		Where(quiz.LessonIDIn(prerequisiteIDs...), quiz.IsPublished(true)).
+		WithResults(func(rq *ent.QuizResultQuery) {
+			rq.Where(quizresult.UserID(user.ID), quizresult.CompletedAtNotNil())
+		}).
+		AllX(ctx)
+
+	for _, lessonObj := range lessons {
+		if lessonObj.PrerequisiteID == nil {
+			continue
+		}
+		prerequisiteID := *lessonObj.PrerequisiteID
+		if lessonObj.PrerequisiteMinScore == nil {
+			met[lessonObj.ID] = completed[prerequisiteID]
+			continue
+		}
+		passed, hasQuizzes := true, false
+		for _, quizObj := range quizzes {
+			if quizObj.LessonID != prerequisiteID {
+				continue
+			}
+			hasQuizzes = true
+			// A quiz can only be taken once, so there's at most one result
+			if len(quizObj.Edges.Results) == 0 || quizObj.Edges.Results[0].Score < *lessonObj.PrerequisiteMinScore {
+				passed = false
+			}
+		}
+		met[lessonObj.ID] = passed && (hasQuizzes || completed[prerequisiteID])
+	}
+	return met
+}
+
 func (c CourseManager) CreateEnrollment(db *ent.Client, ctx context.Context, user *ent.User, course *ent.Course) (*ent.Enrollment, *config.ErrorResponse) {
 	existentEnrollment := c.GetExistentEnrollmentByUserAndCourse(db, ctx, user, course, false)
 	if existentEnrollment != nil {
@@ -275,17 +463,47 @@ func (c CourseManager) CreateEnrollment(db *ent.Client, ctx context.Context, use
 	return enrollmentObj, nil
 }
 
-func (c CourseManager) UpdateEnrollment(db *ent.Client, ctx context.Context, enrollmentID uuid.UUID, paymentStatus enrollment.PaymentStatus) {
-	enrollmentObj, err := db.Enrollment.Query().Where(enrollment.ID(enrollmentID)).Only(ctx)
+// UpdateEnrollment sets the enrollment's payment outcome. It returns the enrollment, with its user and course,
+// only when the payment status changed, since Stripe can send several events with the same outcome
+func (c CourseManager) UpdateEnrollment(db *ent.Client, ctx context.Context, enrollmentID uuid.UUID, paymentStatus enrollment.PaymentStatus) *ent.Enrollment {
+	enrollmentObj, err := db.Enrollment.Query().Where(enrollment.ID(enrollmentID)).WithUser().WithCourse().Only(ctx)
 	if err != nil {
 		log.Printf("Error fetching enrollment: %v", err)
-		return
+		return nil
+	}
+	if enrollmentObj.PaymentStatus == paymentStatus {
+		return nil
 	}
 	enrollmentStatus := enrollment.StatusInactive
 	if paymentStatus == enrollment.PaymentStatusSuccessful {
 		enrollmentStatus = enrollment.StatusActive
 	}
-	enrollmentObj.Update().SetPaymentStatus(paymentStatus).SetStatus(enrollment.Status(enrollmentStatus)).SaveX(ctx)
+	updatedEnrollment := enrollmentObj.Update().SetPaymentStatus(paymentStatus).SetStatus(enrollment.Status(enrollmentStatus)).SaveX(ctx)
+	updatedEnrollment.Edges = enrollmentObj.Edges
+	return updatedEnrollment
+}
+
+// RecordPayment saves the outcome of a checkout session.
+// Stripe can send several events for the same session, so the session id is used as the transaction id
+func (c CourseManager) RecordPayment(db *ent.Client, ctx context.Context, enrollmentID uuid.UUID, transactionID string, amount float64, status payment.Status) {
+	enrollmentObj, err := db.Enrollment.Get(ctx, enrollmentID)
+	if err != nil {
+		log.Printf("Error fetching enrollment: %v", err)
+		return
+	}
+	existingPayment, _ := db.Payment.Query().Where(payment.TransactionID(transactionID)).Only(ctx)
+	if existingPayment != nil {
+		existingPayment.Update().SetStatus(status).ExecX(ctx)
+		return
+	}
+	db.Payment.Create().
+		SetUserID(enrollmentObj.UserID).
+		SetCourseID(enrollmentObj.CourseID).
+		SetAmount(amount).
+		SetStatus(status).
+		SetPaymentMethod("stripe").
+		SetTransactionID(transactionID).
+		ExecX(ctx)
 }
 
 func (c CourseManager) GetAverageRating(reviews []*ent.Review) float64 {
@@ -399,11 +617,22 @@ func (c CourseManager) GenerateCertificate(db *ent.Client, ctx context.Context,
 	cert := certs.GenerateCertificate(user, course, course.Edges.Instructor.Name)
 	db.Enrollment.Update().Where(enrollment.CourseID(course.ID), enrollment.UserID(user.ID)).
 		SetCert(cert).
+		SetStatus(enrollment.StatusCompleted).
+		SetCompletedAt(time.Now()).
 		SaveX(ctx)
 }
 
-func (c CourseManager) GetReviews(db *ent.Client, course *ent.Course, fibCtx *fiber.Ctx) *config.PaginationResponse[*ent.Review] {
-	query := db.Review.Query().Where(review.CourseID(course.ID)).Order(ent.Desc(review.FieldCreatedAt)).WithUser()
+// VisibleReviews limits eager loaded reviews to the ones not hidden by a moderator
+func VisibleReviews(q *ent.ReviewQuery) {
+	q.Where(review.IsHidden(false))
+}
+
+func (c CourseManager) GetReviews(db *ent.Client, course *ent.Course, fibCtx *fiber.Ctx, sortBy string) *config.PaginationResponse[*ent.Review] {
+	query := db.Review.Query().Where(review.CourseID(course.ID), review.IsHidden(false)).WithUser()
+	if sortBy == REVIEWS_SORT_HELPFUL {
+		query = query.Order(ent.Desc(review.FieldHelpfulCount))
+	}
+	query = query.Order(ent.Desc(review.FieldCreatedAt))
 	reviews := config.PaginateModel(fibCtx, query)
 	return reviews
 }
@@ -438,6 +667,49 @@ func (c CourseManager) CreateReview(db *ent.Client, ctx context.Context, user *e
 	return review, nil
 }
 
+func (c CourseManager) ReportReview(db *ent.Client, ctx context.Context, user *ent.User, reviewObj *ent.Review, data ReviewReportSchema) *config.ErrorResponse {
+	if reviewObj.UserID == user.ID {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You cannot report your own review")
+		return &err
+	}
+	if db.ReviewReport.Query().Where(reviewreport.UserID(user.ID), reviewreport.ReviewID(reviewObj.ID)).ExistX(ctx) {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have already reported this review")
+		return &err
+	}
+	db.ReviewReport.Create().
+		SetUserID(user.ID).
+		SetReviewID(reviewObj.ID).
+		SetReason(data.Reason).
+		SetNillableDetails(data.Details).
+		ExecX(ctx)
+	reviewObj.Update().AddReportsCount(1).ExecX(ctx)
+	return nil
+}
+
+func (c CourseManager) VoteReviewHelpful(db *ent.Client, ctx context.Context, user *ent.User, reviewObj *ent.Review) *config.ErrorResponse {
+	if reviewObj.UserID == user.ID {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You cannot vote on your own review")
+		return &err
+	}
+	if db.ReviewVote.Query().Where(reviewvote.UserID(user.ID), reviewvote.ReviewID(reviewObj.ID)).ExistX(ctx) {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have already voted this review helpful")
+		return &err
+	}
+	db.ReviewVote.Create().SetUserID(user.ID).SetReviewID(reviewObj.ID).ExecX(ctx)
+	reviewObj.Update().AddHelpfulCount(1).ExecX(ctx)
+	return nil
+}
+
+func (c CourseManager) RemoveReviewVote(db *ent.Client, ctx context.Context, user *ent.User, reviewObj *ent.Review) *config.ErrorResponse {
+	deleted := db.ReviewVote.Delete().Where(reviewvote.UserID(user.ID), reviewvote.ReviewID(reviewObj.ID)).ExecX(ctx)
+	if deleted == 0 {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have not voted this review helpful")
+		return &err
+	}
+	reviewObj.Update().AddHelpfulCount(-1).ExecX(ctx)
+	return nil
+}
+
 func (c CourseManager) UpdateReview(db *ent.Client, ctx context.Context, reviewObj *ent.Review, data ReviewSchema) *ent.Review {
 	updatedReviewObj := reviewObj.Update().
 		SetRating(data.Rating).
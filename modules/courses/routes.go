@@ -1,6 +1,7 @@
 package courses
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
@@ -9,7 +10,10 @@ import (
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/general"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/notifications"
 )
 
 var courseManager = CourseManager{}
@@ -21,6 +25,8 @@ var courseManager = CourseManager{}
 // @Param limit query int false "Page Limit" default(100)
 // @Param title query string false "Filter By Title"
 // @Param instructor query string false "Filter By Instructor's Name Or Username"
+// @Param category query string false "Filter By Category Slug"
+// @Param tag query string false "Filter By Tag Slug"
 // @Param isFree query bool false "Filter By Free Status"
 // @Param sortByRating query string false "Sort By Rating (asc or desc)"
 // @Success 200 {object} CoursesResponseSchema
@@ -35,6 +41,36 @@ func GetLatestCourses(db *ent.Client) fiber.Handler {
 	}
 }
 
+// @Summary Retrieve Categories
+// @Description This endpoint retrieves all course categories along with their number of published courses
+// @Tags Courses
+// @Success 200 {object} CategoriesResponseSchema
+// @Router /courses/categories [get]
+func GetCategories(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		categories := courseManager.GetCategoriesWithCourses(db, c.Context())
+		response := CategoriesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Categories Fetched Successfully"),
+		}.Assign(categories)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Retrieve Tags
+// @Description This endpoint retrieves all course tags along with their number of published courses
+// @Tags Courses
+// @Success 200 {object} TagsResponseSchema
+// @Router /courses/tags [get]
+func GetTags(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tags := courseManager.GetTagsWithCourses(db, c.Context())
+		response := TagsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Tags Fetched Successfully"),
+		}.Assign(tags)
+		return c.Status(200).JSON(response)
+	}
+}
+
 // @Summary Retrieve Course Details
 // @Description This endpoint retrieves the details of a particular course
 // @Tags Courses
@@ -45,7 +81,7 @@ func GetLatestCourses(db *ent.Client) fiber.Handler {
 func GetCourseDetails(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.Context()
-		course := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), nil, true)
+		course := courseManager.GetPublishedCourseBySlug(db, ctx, c.Params("slug"), true)
 		if course == nil {
 			return config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
 		}
@@ -57,49 +93,58 @@ func GetCourseDetails(db *ent.Client) fiber.Handler {
 	}
 }
 
-// @Summary Retrieve Course Lessons
-// @Description This endpoint retrieves paginated responses of a course lessons
+// @Summary Retrieve Course Curriculum
+// @Description `This endpoint retrieves a course's curriculum: its sections in order, each with its lessons, duration and lessons count`
+// @Description `Lessons that aren't in any section are listed after the sections`
+// @Description `Lessons with unlock conditions show them, and whether they're still locked for the authenticated user. Anonymous users see them all locked`
 // @Tags Courses
 // @Param slug path string true "Course Slug"
-// @Param page query int false "Current Page" default(1)
-// @Param limit query int false "Page Limit" default(100)
-// @Param title query string false "Filter By Title"
-// @Param isFreePreview query bool false "Filter By Free Preview"
 // @Success 404 {object} base.NotFoundErrorExample
-// @Success 200 {object} LessonsResponseSchema
+// @Success 200 {object} CurriculumResponseSchema
 // @Router /courses/{slug}/lessons [get]
+// @Security BearerAuth
 func GetCourseLessons(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		course := courseManager.GetCourseBySlug(db, c.Context(), c.Params("slug"), nil, false)
+		ctx := c.Context()
+		course := courseManager.GetPublishedCourseBySlug(db, ctx, c.Params("slug"), false)
 		if course == nil {
 			return config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
 		}
-		lessons := courseManager.GetLessons(db, course, c)
+		sections, unsectionedLessons := courseManager.GetCurriculum(db, ctx, course)
+		lessons := unsectionedLessons
+		for _, section := range sections {
+			lessons = append(lessons, section.Edges.Lessons...)
+		}
+		gates := courseManager.LessonGates(db, ctx, base.RequestUser(c), course, lessons)
 
-		response := LessonsResponseSchema{
-			ResponseSchema: base.ResponseMessage("Lessons Fetched Successfully"),
-		}.Assign(lessons)
+		response := CurriculumResponseSchema{
+			ResponseSchema: base.ResponseMessage("Curriculum Fetched Successfully"),
+			Data:           CurriculumSchema{}.Assign(sections, unsectionedLessons, gates),
+		}
 		return c.Status(200).JSON(response)
 	}
 }
 
 // @Summary Retrieve Lesson Details
 // @Description This endpoint retrieves the details of a particular lesson
+// @Description `Lessons with unlock conditions are only available to enrolled users who meet them`
 // @Tags Courses
 // @Param course_slug path string true "Course Slug"
 // @Param lesson_slug path string true "Lesson Slug"
 // @Success 200 {object} LessonResponseSchema
 // @Success 404 {object} base.NotFoundErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
 // @Router /courses/{course_slug}/lessons/{lesson_slug} [get]
+// @Security BearerAuth
 func GetCourseLessonDetails(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		lesson := courseManager.GetCourseLessonBySlug(db, ctx, c.Params("lesson_slug"), nil, true)
-		if lesson == nil {
-			return config.APIError(c, 404, config.NotFoundErr("Lesson Not Found"))
+		lesson, err := GetCourseLesson(db, c)
+		if err != nil {
+			return err
 		}
-		if lesson.Edges.Course.Slug != c.Params("course_slug") {
-			return config.APIError(c, 404, config.NotFoundErr("Lesson Not Found for specified course"))
+		gate := courseManager.LessonGates(db, c.Context(), base.RequestUser(c), lesson.Edges.Course, []*ent.Lesson{lesson})[lesson.ID]
+		if gate.IsLocked {
+			return config.APIError(c, 403, config.ForbiddenErr(gate.Reason))
 		}
 		response := LessonResponseSchema{
 			ResponseSchema: base.ResponseMessage("Lesson Details Fetched Successfully"),
@@ -109,6 +154,18 @@ func GetCourseLessonDetails(db *ent.Client) fiber.Handler {
 	}
 }
 
+// GetCourseLesson returns the lesson in the lesson_slug path param if it belongs to the course in the course_slug path param
+func GetCourseLesson(db *ent.Client, c *fiber.Ctx) (*ent.Lesson, error) {
+	lesson := courseManager.GetCourseLessonBySlug(db, c.Context(), c.Params("lesson_slug"), nil, true)
+	if lesson == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Lesson Not Found"))
+	}
+	if lesson.Edges.Course.Slug != c.Params("course_slug") {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Lesson Not Found for specified course"))
+	}
+	return lesson, nil
+}
+
 // @Summary Enroll for a course
 // @Description This endpoint allows a user to enroll for a specific course
 // @Tags Courses
@@ -124,7 +181,7 @@ func EnrollForACourse(db *ent.Client, cfg config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.Context()
 		user := base.RequestUser(c)
-		course := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), nil, true)
+		course := courseManager.GetPublishedCourseBySlug(db, ctx, c.Params("slug"), true)
 		if course == nil {
 			return config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
 		}
@@ -139,7 +196,7 @@ func EnrollForACourse(db *ent.Client, cfg config.Config) fiber.Handler {
 			return config.APIError(c, 400, *err)
 		}
 
-		checkoutUrl, err := CreateCheckoutSession(cfg, course, data.SuccessUrl, data.CancelUrl, enrollment)
+		checkoutUrl, err := CreateCheckoutSession(cfg, course, general.Settings(db, ctx).DefaultCurrency, data.SuccessUrl, data.CancelUrl, enrollment)
 		if err != nil {
 			return config.APIError(c, 500, *err)
 		}
@@ -155,7 +212,7 @@ func EnrollForACourse(db *ent.Client, cfg config.Config) fiber.Handler {
 }
 
 // @Summary Retrieve Lesson Quizzes
-// @Description This endpoint retrieves paginated responses of a lesson quizzes
+// @Description `This endpoint retrieves paginated responses of a lesson quizzes. The lesson must be unlocked for the user`
 // @Tags Courses
 // @Param slug path string true "Lesson Slug"
 // @Param page query int false "Current Page" default(1)
@@ -163,6 +220,7 @@ func EnrollForACourse(db *ent.Client, cfg config.Config) fiber.Handler {
 // @Param title query string false "Filter By Title"
 // @Success 404 {object} base.NotFoundErrorExample
 // @Success 200 {object} QuizzesResponseSchema
+// @Failure 403 {object} base.ForbiddenErrorExample
 // @Router /courses/lessons/{slug}/quizzes [get]
 // @Security BearerAuth
 func GetLessonQuizzes(db *ent.Client) fiber.Handler {
@@ -178,6 +236,9 @@ func GetLessonQuizzes(db *ent.Client) fiber.Handler {
 		if enrollmentObj == nil || enrollmentObj.PaymentStatus != enrollment.PaymentStatusSuccessful {
 			return config.APIError(c, 403, config.ForbiddenErr("Only for enrolled users"))
 		}
+		if gate := courseManager.LessonGates(db, ctx, user, lesson.Edges.Course, []*ent.Lesson{lesson})[lesson.ID]; gate.IsLocked {
+			return config.APIError(c, 403, config.ForbiddenErr(gate.Reason))
+		}
 		quizzes := courseManager.GetQuizzes(db, lesson, c)
 
 		response := QuizzesResponseSchema{
@@ -188,11 +249,12 @@ func GetLessonQuizzes(db *ent.Client) fiber.Handler {
 }
 
 // @Summary Retrieve Quiz Details
-// @Description This endpoint retrieves the details of a particular quiz
+// @Description `This endpoint retrieves the details of a particular quiz. Its lesson must be unlocked for the user`
 // @Tags Courses
 // @Param quiz_slug path string true "Quiz Slug"
 // @Success 200 {object} QuizResponseSchema
 // @Success 404 {object} base.NotFoundErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
 // @Router /courses/quizzes/{quiz_slug} [get]
 // @Security BearerAuth
 func GetLessonQuizDetails(db *ent.Client) fiber.Handler {
@@ -208,6 +270,10 @@ func GetLessonQuizDetails(db *ent.Client) fiber.Handler {
 		if enrollmentObj == nil || enrollmentObj.PaymentStatus != enrollment.PaymentStatusSuccessful {
 			return config.APIError(c, 403, config.ForbiddenErr("Only for enrolled users"))
 		}
+		lesson := quiz.Edges.Lesson
+		if gate := courseManager.LessonGates(db, ctx, user, lesson.Edges.Course, []*ent.Lesson{lesson})[lesson.ID]; gate.IsLocked {
+			return config.APIError(c, 403, config.ForbiddenErr(gate.Reason))
+		}
 		response := QuizResponseSchema{
 			ResponseSchema: base.ResponseMessage("Quiz Details Fetched Successfully"),
 			Data:           QuizDetailSchema{}.Assign(quiz),
@@ -217,12 +283,13 @@ func GetLessonQuizDetails(db *ent.Client) fiber.Handler {
 }
 
 // @Summary Start Quiz
-// @Description `This endpoint allows a user to start a quiz`
+// @Description `This endpoint allows a user to start a quiz. Its lesson must be unlocked for the user`
 // @Tags Courses
 // @Param quiz_slug path string true "Quiz Slug"
 // @Success 200 {object} base.ResponseSchema
 // @Success 404 {object} base.NotFoundErrorExample
 // @Success 400 {object} base.InvalidErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
 // @Router /courses/quizzes/{quiz_slug}/start [get]
 // @Security BearerAuth
 func StartQuiz(db *ent.Client) fiber.Handler {
@@ -238,6 +305,10 @@ func StartQuiz(db *ent.Client) fiber.Handler {
 		if enrollmentObj == nil || enrollmentObj.PaymentStatus != enrollment.PaymentStatusSuccessful {
 			return config.APIError(c, 403, config.ForbiddenErr("Only for enrolled users"))
 		}
+		lesson := quiz.Edges.Lesson
+		if gate := courseManager.LessonGates(db, ctx, user, lesson.Edges.Course, []*ent.Lesson{lesson})[lesson.ID]; gate.IsLocked {
+			return config.APIError(c, 403, config.ForbiddenErr(gate.Reason))
+		}
 
 		_, err := courseManager.CreateQuizResultData(db, ctx, user, quiz)
 		if err != nil {
@@ -287,9 +358,15 @@ func SubmitQuizResult(db *ent.Client) fiber.Handler {
 			return config.APIError(c, 400, *err)
 		}
 
+		courseObj := quiz.Edges.Lesson.QueryCourse().WithInstructor().OnlyX(ctx)
+		notifications.Notify(db, ctx, user, notification.TypeQuizGraded, "Quiz graded",
+			fmt.Sprintf("You scored %.0f%% on %s.", quizResult.Score, quiz.Title), courseObj)
+
 		// Generate cert if this is the last quiz in the course
 		if courseManager.IsLastQuizInCourse(db, ctx, quiz) {
-			courseManager.GenerateCertificate(db, ctx, user, quiz.Edges.Lesson.QueryCourse().WithInstructor().OnlyX(ctx))
+			courseManager.GenerateCertificate(db, ctx, user, courseObj)
+			notifications.Notify(db, ctx, user, notification.TypeCertificateIssued, "Certificate issued",
+				fmt.Sprintf("Congratulations on completing %s! Your certificate is ready.", courseObj.Title), courseObj)
 		}
 		
 		response := QuizResultResponseSchema{
@@ -350,8 +427,9 @@ func PostSummarizePDF(db *ent.Client, cfg config.Config) fiber.Handler {
 		// Check daily limit
 		now := time.Now()
 		if user.LastSummaryDate != nil && user.LastSummaryDate.Year() == now.Year() && user.LastSummaryDate.YearDay() == now.YearDay() {
-			if user.SummaryCount >= 10 {
-				return config.APIError(c, fiber.StatusTooManyRequests, config.RequestErr(config.ERR_TOO_MANY_REQUESTS, "You have reached your daily limit of 10 PDF summaries."))
+			dailyLimit := general.Settings(db, c.Context()).AiSummaryDailyLimit
+			if user.SummaryCount >= dailyLimit {
+				return config.APIError(c, fiber.StatusTooManyRequests, config.RequestErr(config.ERR_TOO_MANY_REQUESTS, fmt.Sprintf("You have reached your daily limit of %d PDF summaries.", dailyLimit)))
 			}
 		} else {
 			// Reset count for a new day
@@ -388,16 +466,22 @@ func PostSummarizePDF(db *ent.Client, cfg config.Config) fiber.Handler {
 // @Param slug path string true "Course Slug"
 // @Param page query int false "Current Page" default(1)
 // @Param limit query int false "Page Limit" default(100)
+// @Param sort query string false "Sort By (newest or helpful)" default(newest)
 // @Success 404 {object} base.NotFoundErrorExample
+// @Success 400 {object} base.InvalidErrorExample
 // @Success 200 {object} ReviewsResponseSchema
 // @Router /courses/{slug}/reviews [get]
 func GetCourseReviews(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		course := courseManager.GetCourseBySlug(db, c.Context(), c.Params("slug"), nil, false)
+		course := courseManager.GetPublishedCourseBySlug(db, c.Context(), c.Params("slug"), false)
 		if course == nil {
 			return config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
 		}
-		reviews := courseManager.GetReviews(db, course, c)
+		sortBy := c.Query("sort", REVIEWS_SORT_NEWEST)
+		if sortBy != REVIEWS_SORT_NEWEST && sortBy != REVIEWS_SORT_HELPFUL {
+			return config.APIError(c, 400, config.InvalidParamErr("Invalid sort. Use newest or helpful"))
+		}
+		reviews := courseManager.GetReviews(db, course, c, sortBy)
 
 		response := ReviewsResponseSchema{
 			ResponseSchema: base.ResponseMessage("Reviews Fetched Successfully"),
@@ -421,7 +505,7 @@ func CreateCourseReview(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx := c.Context()
 		user := base.RequestUser(c)
-		course := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), nil, false)
+		course := courseManager.GetPublishedCourseBySlug(db, ctx, c.Params("slug"), false)
 		if course == nil {
 			return config.APIError(c, 404, config.NotFoundErr("Course Not Found"))
 		}
@@ -456,7 +540,7 @@ func GetCourseReview(db *ent.Client) fiber.Handler {
 		ctx := c.Context()
 		reviewID, _ := uuid.Parse(c.Params("id"))
 		review := courseManager.GetReview(db, ctx, reviewID)
-		if review == nil {
+		if review == nil || review.IsHidden {
 			return config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
 		}
 		response := ReviewResponseSchema{
@@ -528,4 +612,86 @@ func DeleteCourseReview(db *ent.Client) fiber.Handler {
 		db.Review.DeleteOne(review).ExecX(ctx)
 		return c.Status(200).JSON(base.ResponseMessage("Review Deleted Successfully"))
 	}
-}
\ No newline at end of file
+}
+
+// @Summary Report a review
+// @Description `This endpoint allows a user to report a review to the moderators. A review can only be reported once per user`
+// @Tags Courses
+// @Param id path string true "Review ID"
+// @Param data body ReviewReportSchema true "Report object"
+// @Success 201 {object} base.ResponseSchema
+// @Success 404 {object} base.NotFoundErrorExample
+// @Success 400 {object} base.InvalidErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /courses/reviews/{id}/report [post]
+// @Security BearerAuth
+func ReportCourseReview(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		reviewID, _ := uuid.Parse(c.Params("id"))
+		review := courseManager.GetReview(db, ctx, reviewID)
+		if review == nil || review.IsHidden {
+			return config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
+		}
+		data := ReviewReportSchema{}
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if err := courseManager.ReportReview(db, ctx, user, review, data); err != nil {
+			return config.APIError(c, 400, *err)
+		}
+		return c.Status(201).JSON(base.ResponseMessage("Review Reported Successfully"))
+	}
+}
+
+// @Summary Vote a review helpful
+// @Description `This endpoint allows a user to mark a review as helpful. Users can't vote on their own reviews`
+// @Tags Courses
+// @Param id path string true "Review ID"
+// @Success 200 {object} base.ResponseSchema
+// @Success 404 {object} base.NotFoundErrorExample
+// @Success 400 {object} base.InvalidErrorExample
+// @Router /courses/reviews/{id}/helpful [post]
+// @Security BearerAuth
+func VoteCourseReviewHelpful(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		reviewID, _ := uuid.Parse(c.Params("id"))
+		review := courseManager.GetReview(db, ctx, reviewID)
+		if review == nil || review.IsHidden {
+			return config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
+		}
+		if err := courseManager.VoteReviewHelpful(db, ctx, user, review); err != nil {
+			return config.APIError(c, 400, *err)
+		}
+		return c.Status(200).JSON(base.ResponseMessage("Review Voted Helpful"))
+	}
+}
+
+// @Summary Remove a helpful vote
+// @Description `This endpoint allows a user to take back their helpful vote on a review`
+// @Tags Courses
+// @Param id path string true "Review ID"
+// @Success 200 {object} base.ResponseSchema
+// @Success 404 {object} base.NotFoundErrorExample
+// @Success 400 {object} base.InvalidErrorExample
+// @Router /courses/reviews/{id}/helpful [delete]
+// @Security BearerAuth
+func RemoveCourseReviewVote(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		reviewID, _ := uuid.Parse(c.Params("id"))
+		review := courseManager.GetReview(db, ctx, reviewID)
+		if review == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
+		}
+		if err := courseManager.RemoveReviewVote(db, ctx, user, review); err != nil {
+			return config.APIError(c, 400, *err)
+		}
+		return c.Status(200).JSON(base.ResponseMessage("Helpful Vote Removed"))
+	}
+}
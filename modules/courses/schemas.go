@@ -7,6 +7,7 @@ import (
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewreport"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
 )
@@ -27,25 +28,67 @@ func (c CategoryOrTagSchema) Assign(category *ent.Category, tag *ent.Tag) Catego
 	return c
 }
 
+// CategoryOrTagListSchema - A category or tag with the number of published courses in it
+type CategoryOrTagListSchema struct {
+	CategoryOrTagSchema
+	CoursesCount int `json:"courses_count" example:"12"`
+}
+
+type CategoriesResponseSchema struct {
+	base.ResponseSchema
+	Data []CategoryOrTagListSchema `json:"data"`
+}
+
+func (c CategoriesResponseSchema) Assign(categories []*ent.Category) CategoriesResponseSchema {
+	items := make([]CategoryOrTagListSchema, 0)
+	for _, categoryObj := range categories {
+		items = append(items, CategoryOrTagListSchema{
+			CategoryOrTagSchema: CategoryOrTagSchema{}.Assign(categoryObj, nil),
+			CoursesCount:        len(categoryObj.Edges.Courses),
+		})
+	}
+	c.Data = items
+	return c
+}
+
+type TagsResponseSchema struct {
+	base.ResponseSchema
+	Data []CategoryOrTagListSchema `json:"data"`
+}
+
+func (t TagsResponseSchema) Assign(tags []*ent.Tag) TagsResponseSchema {
+	items := make([]CategoryOrTagListSchema, 0)
+	for _, tagObj := range tags {
+		items = append(items, CategoryOrTagListSchema{
+			CategoryOrTagSchema: CategoryOrTagSchema{}.Assign(nil, tagObj),
+			CoursesCount:        len(tagObj.Edges.Courses),
+		})
+	}
+	t.Data = items
+	return t
+}
+
 // CourseListSchema - Summary of a course for listings
 type CourseListSchema struct {
-	Instructor    base.UserDataSchema `json:"instructor"`
-	Title         string              `json:"title" example:"Go Programming for Beginners"`
-	Slug          string              `json:"slug" example:"go-programming-for-beginners"`
-	Desc          string              `json:"desc"`
-	ThumbnailURL  string              `json:"thumbnail_url" example:"https://ednet-images.com/courses/go.jpg"`
-	Language      string              `json:"language" example:"English"`
-	Difficulty    course.Difficulty   `json:"difficulty" example:"Beginner"`
-	DiscountPrice *float64            `json:"discount_price,omitempty"`
-	Price         float64             `json:"price" example:"19.99"`
-	IsFree        bool                `json:"is_free" example:"false"`
-	IsPublished   bool                `json:"is_published" example:"false"`
-	Rating        float64             `json:"rating" example:"4.8"`
-	StudentsCount int                 `json:"students_count" example:"1200"`
-	LessonsCount  int                 `json:"lessons_count" example:"20"`
-	Category      CategoryOrTagSchema `json:"category"`
-	CreatedAt     time.Time           `json:"created_at"`
-	UpdatedAt     time.Time           `json:"updated_at"`
+	Instructor    base.UserDataSchema   `json:"instructor"`
+	Title         string                `json:"title" example:"Go Programming for Beginners"`
+	Slug          string                `json:"slug" example:"go-programming-for-beginners"`
+	Desc          string                `json:"desc"`
+	ThumbnailURL  string                `json:"thumbnail_url" example:"https://ednet-images.com/courses/go.jpg"`
+	Language      string                `json:"language" example:"English"`
+	Difficulty    course.Difficulty     `json:"difficulty" example:"Beginner"`
+	DiscountPrice *float64              `json:"discount_price,omitempty"`
+	Price         float64               `json:"price" example:"19.99"`
+	IsFree        bool                  `json:"is_free" example:"false"`
+	IsPublished   bool                  `json:"is_published" example:"false"`
+	Status        course.Status         `json:"status" example:"published"`
+	Rating        float64               `json:"rating" example:"4.8"`
+	StudentsCount int                   `json:"students_count" example:"1200"`
+	LessonsCount  int                   `json:"lessons_count" example:"20"`
+	Category      CategoryOrTagSchema   `json:"category"`
+	Tags          []CategoryOrTagSchema `json:"tags"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
 }
 
 // Assign values from Course to CourseListSchema
@@ -61,10 +104,15 @@ func (c CourseListSchema) Assign(course *ent.Course) CourseListSchema {
 	c.Price = course.Price
 	c.IsFree = course.IsFree
 	c.IsPublished = course.IsPublished
+	c.Status = course.Status
 	c.Rating = courseManager.GetAverageRating(course.Edges.Reviews)
 	c.StudentsCount = len(course.Edges.Enrollments)
 	c.LessonsCount = len(course.Edges.Lessons)
 	c.Category = c.Category.Assign(course.Edges.Category, nil)
+	c.Tags = make([]CategoryOrTagSchema, 0)
+	for _, tagObj := range course.Edges.Tags {
+		c.Tags = append(c.Tags, CategoryOrTagSchema{}.Assign(nil, tagObj))
+	}
 	c.CreatedAt = course.CreatedAt
 	c.UpdatedAt = course.CreatedAt
 	return c
@@ -91,11 +139,13 @@ func (c CoursesResponseSchema) Assign(coursesData *config.PaginationResponse[*en
 // CourseDetailSchema - Full details of a course
 type CourseDetailSchema struct {
 	CourseListSchema
-	IntroVideoURL  *string               `json:"intro_video_url,omitempty"`
-	Duration       uint                  `json:"duration"` // in minutes
-	EnrollmentType course.EnrollmentType `json:"enrollment_type"`
-	Certification  bool                  `json:"certification"`
-	ReviewsCount   int                   `json:"reviews_count"`
+	IntroVideoURL   *string               `json:"intro_video_url,omitempty"`
+	Duration        uint                  `json:"duration"` // in minutes
+	EnrollmentType  course.EnrollmentType `json:"enrollment_type"`
+	Certification   bool                  `json:"certification"`
+	ReviewsCount    int                   `json:"reviews_count"`
+	RejectionReason *string               `json:"rejection_reason,omitempty"`
+	SubmittedAt     *time.Time            `json:"submitted_at,omitempty"`
 }
 
 // Assign values from Course to CourseDetailSchema
@@ -106,6 +156,8 @@ func (c CourseDetailSchema) Assign(course *ent.Course) CourseDetailSchema {
 	c.EnrollmentType = course.EnrollmentType
 	c.Certification = course.Certification
 	c.ReviewsCount = len(course.Edges.Reviews)
+	c.RejectionReason = course.RejectionReason
+	c.SubmittedAt = course.SubmittedAt
 	return c
 }
 
@@ -115,18 +167,23 @@ type CourseResponseSchema struct {
 }
 
 type LessonListSchema struct {
-	Title         string `json:"title"`
-	Slug          string `json:"slug"`
-	Desc          string `json:"desc"`
-	Order         uint   `json:"order"`
-	Duration      uint   `json:"duration"`
-	IsPublished   bool   `json:"is_published"`
-	IsFreePreview bool   `json:"is_free_preview"`
-	ThumbnailURL  string `json:"thumbnail_url" example:"https://ednet-images.com/lessons/go.jpg"`
+	SectionID        *uuid.UUID                    `json:"section_id"`
+	Title            string                        `json:"title"`
+	Slug             string                        `json:"slug"`
+	Desc             string                        `json:"desc"`
+	Order            uint                          `json:"order"`
+	Duration         uint                          `json:"duration"`
+	IsPublished      bool                          `json:"is_published"`
+	IsFreePreview    bool                          `json:"is_free_preview"`
+	ThumbnailURL     string                        `json:"thumbnail_url" example:"https://ednet-images.com/lessons/go.jpg"`
+	UnlockConditions *LessonUnlockConditionsSchema `json:"unlock_conditions"` // Null if the lesson is never locked
+	IsLocked         bool                          `json:"is_locked"`
+	UnlocksAt        *time.Time                    `json:"unlocks_at"` // When the time conditions are met for the requesting user, if known
 }
 
 // Assign values from Lesson to LessonSchema
 func (l LessonListSchema) Assign(lesson *ent.Lesson) LessonListSchema {
+	l.SectionID = lesson.SectionID
 	l.Title = lesson.Title
 	l.Slug = lesson.Slug
 	l.Desc = lesson.Desc
@@ -135,9 +192,35 @@ func (l LessonListSchema) Assign(lesson *ent.Lesson) LessonListSchema {
 	l.IsPublished = lesson.IsPublished
 	l.IsFreePreview = lesson.IsFreePreview
 	l.ThumbnailURL = lesson.ThumbnailURL
+	if lesson.UnlockAfterDays != nil || lesson.UnlockAt != nil || lesson.PrerequisiteID != nil {
+		conditions := LessonUnlockConditionsSchema{
+			UnlockAfterDays:      lesson.UnlockAfterDays,
+			UnlockAt:             lesson.UnlockAt,
+			PrerequisiteMinScore: lesson.PrerequisiteMinScore,
+		}
+		if lesson.Edges.Prerequisite != nil {
+			conditions.PrerequisiteSlug = &lesson.Edges.Prerequisite.Slug
+		}
+		l.UnlockConditions = &conditions
+	}
+	return l
+}
+
+// Lock sets where the lesson's unlock conditions stand for the requesting user
+func (l LessonListSchema) Lock(gate LessonGate) LessonListSchema {
+	l.IsLocked = gate.IsLocked
+	l.UnlocksAt = gate.UnlocksAt
 	return l
 }
 
+// LessonUnlockConditionsSchema holds the conditions a lesson unlocks on. It unlocks once every one that is set is met
+type LessonUnlockConditionsSchema struct {
+	UnlockAfterDays      *uint      `json:"unlock_after_days" validate:"omitempty,min=1" example:"7"` // Days after enrollment
+	UnlockAt             *time.Time `json:"unlock_at" example:"2026-01-02T15:04:05Z"`
+	PrerequisiteSlug     *string    `json:"prerequisite_slug" example:"go-basics"`
+	PrerequisiteMinScore *float64   `json:"prerequisite_min_score" validate:"omitempty,min=0,max=100" example:"70"` // Set to require this score on each of the prerequisite's quizzes instead of completing it
+}
+
 type LessonsResponseSchema struct {
 	base.ResponseSchema
 	Data config.PaginationResponse[LessonListSchema] `json:"data"`
@@ -156,6 +239,66 @@ func (l LessonsResponseSchema) Assign(lessonsData *config.PaginationResponse[*en
 	return l
 }
 
+type SectionSchema struct {
+	ID           uuid.UUID          `json:"id"`
+	Title        string             `json:"title" example:"Getting Started"`
+	Description  string             `json:"description" example:"Set up your environment and write your first program"`
+	Order        uint               `json:"order" example:"1"`
+	LessonsCount int                `json:"lessons_count" example:"4"`
+	Duration     uint               `json:"duration" example:"45"` // Total duration of its lessons
+	Lessons      []LessonListSchema `json:"lessons"`
+}
+
+// Assign values from Section to SectionSchema. gates locks its lessons for the requesting user and may be nil
+func (s SectionSchema) Assign(section *ent.Section, gates map[uuid.UUID]LessonGate) SectionSchema {
+	s.ID = section.ID
+	s.Title = section.Title
+	s.Description = section.Description
+	s.Order = section.Order
+	s.Lessons = make([]LessonListSchema, 0)
+	for _, lesson := range section.Edges.Lessons {
+		s.Lessons = append(s.Lessons, LessonListSchema{}.Assign(lesson).Lock(gates[lesson.ID]))
+		s.Duration += lesson.Duration
+	}
+	s.LessonsCount = len(s.Lessons)
+	return s
+}
+
+type SectionResponseSchema struct {
+	base.ResponseSchema
+	Data SectionSchema `json:"data"`
+}
+
+// CurriculumSchema is a course's sections with their lessons, in order
+type CurriculumSchema struct {
+	Sections     []SectionSchema    `json:"sections"`
+	Lessons      []LessonListSchema `json:"lessons"` // Lessons not in any section
+	LessonsCount int                `json:"lessons_count" example:"12"`
+	Duration     uint               `json:"duration" example:"180"`
+}
+
+func (c CurriculumSchema) Assign(sections []*ent.Section, unsectionedLessons []*ent.Lesson, gates map[uuid.UUID]LessonGate) CurriculumSchema {
+	c.Sections = make([]SectionSchema, 0)
+	for _, section := range sections {
+		sectionData := SectionSchema{}.Assign(section, gates)
+		c.Sections = append(c.Sections, sectionData)
+		c.LessonsCount += sectionData.LessonsCount
+		c.Duration += sectionData.Duration
+	}
+	c.Lessons = make([]LessonListSchema, 0)
+	for _, lesson := range unsectionedLessons {
+		c.Lessons = append(c.Lessons, LessonListSchema{}.Assign(lesson).Lock(gates[lesson.ID]))
+		c.LessonsCount++
+		c.Duration += lesson.Duration
+	}
+	return c
+}
+
+type CurriculumResponseSchema struct {
+	base.ResponseSchema
+	Data CurriculumSchema `json:"data"`
+}
+
 type LessonDetailSchema struct {
 	LessonListSchema
 	QuizzesCount int    `json:"quizzes_count"`
@@ -346,20 +489,42 @@ type PDFSummaryResponseSchema struct {
 	Data PDFSummarySchema `json:"data"`
 }
 
+const (
+	REVIEWS_SORT_NEWEST  = "newest"
+	REVIEWS_SORT_HELPFUL = "helpful"
+)
+
 // ReviewSchema for creating and updating reviews
 type ReviewSchema struct {
 	Rating  float64 `json:"rating" validate:"required,min=1,max=5"`
 	Comment string        `json:"content"`
 }
 
+type ReviewReportSchema struct {
+	Reason  reviewreport.Reason `json:"reason" validate:"required,review_report_reason_validator" example:"spam"`
+	Details *string             `json:"details" validate:"omitempty,max=1000" example:"Links to an unrelated website"`
+}
+
+type ReviewReplySchema struct {
+	Content string `json:"content" validate:"required,max=2000" example:"Thanks, glad the course helped!"`
+}
+
+// ReviewReplyData is the instructor's public reply to a review
+type ReviewReplyData struct {
+	Content   string    `json:"content" example:"Thanks, glad the course helped!"`
+	RepliedAt time.Time `json:"replied_at"`
+}
+
 // ReviewResponseData for single review response
 type ReviewResponseData struct {
-	ID        uuid.UUID           `json:"id"`
-	User      base.UserDataSchema `json:"user"`
-	Rating    float64             `json:"rating"`
-	Comment   string              `json:"content"`
-	CreatedAt time.Time           `json:"created_at"`
-	UpdatedAt time.Time           `json:"updated_at"`
+	ID           uuid.UUID           `json:"id"`
+	User         base.UserDataSchema `json:"user"`
+	Rating       float64             `json:"rating"`
+	Comment      string              `json:"content"`
+	HelpfulCount int                 `json:"helpful_count" example:"12"`
+	Reply        *ReviewReplyData    `json:"reply"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
 }
 
 func (r ReviewResponseData) Assign(review *ent.Review) ReviewResponseData {
@@ -367,6 +532,10 @@ func (r ReviewResponseData) Assign(review *ent.Review) ReviewResponseData {
 	r.User = r.User.Assign(review.Edges.User)
 	r.Rating = review.Rating
 	r.Comment = review.Comment
+	r.HelpfulCount = review.HelpfulCount
+	if review.Reply != nil && review.RepliedAt != nil {
+		r.Reply = &ReviewReplyData{Content: *review.Reply, RepliedAt: *review.RepliedAt}
+	}
 	r.CreatedAt = review.CreatedAt
 	r.UpdatedAt = review.UpdatedAt
 	return r
@@ -49,7 +49,7 @@ func GetCurrentOrigin(c *fiber.Ctx) string {
 	return origin
 }
 
-func CreateCheckoutSession(cfg config.Config, course *ent.Course, successUrl string, cancelUrl string, enrollmentObj *ent.Enrollment) (*string, *config.ErrorResponse) {
+func CreateCheckoutSession(cfg config.Config, course *ent.Course, currency string, successUrl string, cancelUrl string, enrollmentObj *ent.Enrollment) (*string, *config.ErrorResponse) {
 	stripe.Key = cfg.StripeSecretKey
 
 	price := course.DiscountPrice
@@ -64,7 +64,7 @@ func CreateCheckoutSession(cfg config.Config, course *ent.Course, successUrl str
 		LineItems: []*stripe.CheckoutSessionLineItemParams{
 			{
 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-					Currency: stripe.String("usd"),
+					Currency: stripe.String(currency),
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name: stripe.String(course.Title),
 						Description: stripe.String(course.Desc),
@@ -1,7 +1,9 @@
 package courses
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +11,9 @@ import (
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/payment"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/notifications"
 	"github.com/stripe/stripe-go/v82"
 	"github.com/stripe/stripe-go/v82/webhook"
 )
@@ -34,15 +39,41 @@ func StripeWebhook(db *ent.Client, cfg config.Config) fiber.Handler {
 			return config.APIError(c, fiber.StatusBadRequest, config.ServerErr("Invalid enrollment ID"))
 		}
 		log.Println("Parsed Enrollment ID:", enrollmentID)
+		amount := float64(session.AmountTotal) / 100
+		var updatedEnrollment *ent.Enrollment
 		switch event.Type {
 		case "checkout.session.completed", "checkout.session.async_payment_succeeded":
-			courseManager.UpdateEnrollment(db, ctx, enrollmentID, enrollment.PaymentStatusSuccessful)
+			updatedEnrollment = courseManager.UpdateEnrollment(db, ctx, enrollmentID, enrollment.PaymentStatusSuccessful)
+			courseManager.RecordPayment(db, ctx, enrollmentID, session.ID, amount, payment.StatusSuccessful)
 		case "checkout.session.expired":
-			courseManager.UpdateEnrollment(db, ctx, enrollmentID, enrollment.PaymentStatusCancelled)
+			updatedEnrollment = courseManager.UpdateEnrollment(db, ctx, enrollmentID, enrollment.PaymentStatusCancelled)
 		case "checkout.session.async_payment_failed":
-			courseManager.UpdateEnrollment(db, ctx, enrollmentID, enrollment.PaymentStatusFailed)
+			updatedEnrollment = courseManager.UpdateEnrollment(db, ctx, enrollmentID, enrollment.PaymentStatusFailed)
+			courseManager.RecordPayment(db, ctx, enrollmentID, session.ID, amount, payment.StatusFailed)
+		}
+		if updatedEnrollment != nil {
+			notifyPaymentOutcome(db, ctx, updatedEnrollment)
 		}
 
 		return c.SendStatus(fiber.StatusOK)
 	}
 }
+
+// notifyPaymentOutcome tells the student how the payment for their enrollment went
+func notifyPaymentOutcome(db *ent.Client, ctx context.Context, enrollmentObj *ent.Enrollment) {
+	userObj, courseObj := enrollmentObj.Edges.User, enrollmentObj.Edges.Course
+	if userObj == nil || courseObj == nil {
+		return
+	}
+	switch enrollmentObj.PaymentStatus {
+	case enrollment.PaymentStatusSuccessful:
+		notifications.Notify(db, ctx, userObj, notification.TypePaymentSucceeded, "Payment successful",
+			fmt.Sprintf("Your payment for %s went through. You can start learning now.", courseObj.Title), courseObj)
+	case enrollment.PaymentStatusFailed:
+		notifications.Notify(db, ctx, userObj, notification.TypePaymentFailed, "Payment failed",
+			fmt.Sprintf("Your payment for %s failed. Enroll again to retry.", courseObj.Title), courseObj)
+	case enrollment.PaymentStatusCancelled:
+		notifications.Notify(db, ctx, userObj, notification.TypePaymentCanceled, "Payment canceled",
+			fmt.Sprintf("Your checkout for %s expired before the payment was made.", courseObj.Title), courseObj)
+	}
+}
@@ -0,0 +1,197 @@
+package discussions
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/answervote"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/lessonanswer"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/lessonquestion"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/questionvote"
+)
+
+type DiscussionManager struct{}
+
+// ------------------------------------------------------------------
+// QUESTIONS
+// ------------------------------------------------------------------
+
+func (d DiscussionManager) GetQuestionsPaginated(db *ent.Client, fibCtx *fiber.Ctx, lessonObj *ent.Lesson, search string, unanswered bool, sortBy string) *config.PaginationResponse[*ent.LessonQuestion] {
+	query := db.LessonQuestion.Query().Where(lessonquestion.LessonID(lessonObj.ID)).WithUser()
+	if search != "" {
+		query = query.Where(lessonquestion.Or(lessonquestion.TitleContainsFold(search), lessonquestion.BodyContainsFold(search)))
+	}
+	if unanswered {
+		query = query.Where(lessonquestion.AnswersCount(0))
+	}
+	if sortBy == QUESTIONS_SORT_VOTES {
+		query = query.Order(ent.Desc(lessonquestion.FieldUpvotesCount))
+	}
+	query = query.Order(ent.Desc(lessonquestion.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+// GetQuestion returns the question with its lesson and course. When loaded, its answers come with their replies,
+// accepted and endorsed answers first
+func (d DiscussionManager) GetQuestion(db *ent.Client, ctx context.Context, questionID uuid.UUID, loaded bool) *ent.LessonQuestion {
+	query := db.LessonQuestion.Query().
+		Where(lessonquestion.ID(questionID)).
+		WithUser().
+		WithLesson(func(lq *ent.LessonQuery) { lq.WithCourse() })
+	if loaded {
+		query = query.WithAnswers(func(aq *ent.LessonAnswerQuery) {
+			aq.Where(lessonanswer.ParentIDIsNil()).
+				WithUser().
+				WithReplies(func(rq *ent.LessonAnswerQuery) {
+					rq.WithUser().Order(ent.Asc(lessonanswer.FieldCreatedAt))
+				}).
+				Order(
+					ent.Desc(lessonanswer.FieldIsAccepted),
+					ent.Desc(lessonanswer.FieldIsEndorsed),
+					ent.Desc(lessonanswer.FieldUpvotesCount),
+					ent.Asc(lessonanswer.FieldCreatedAt),
+				)
+		})
+	}
+	question, _ := query.Only(ctx)
+	return question
+}
+
+func (d DiscussionManager) CreateQuestion(db *ent.Client, ctx context.Context, user *ent.User, lessonObj *ent.Lesson, data QuestionCreateSchema) *ent.LessonQuestion {
+	question := db.LessonQuestion.Create().
+		SetLessonID(lessonObj.ID).
+		SetUserID(user.ID).
+		SetTitle(data.Title).
+		SetBody(data.Body).
+		SaveX(ctx)
+	question.Edges.User = user
+	return question
+}
+
+func (d DiscussionManager) DeleteQuestion(db *ent.Client, ctx context.Context, question *ent.LessonQuestion) {
+	db.LessonQuestion.DeleteOne(question).ExecX(ctx)
+}
+
+func (d DiscussionManager) VoteQuestion(db *ent.Client, ctx context.Context, user *ent.User, question *ent.LessonQuestion) *config.ErrorResponse {
+	if question.UserID == user.ID {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You cannot upvote your own question")
+		return &err
+	}
+	if db.QuestionVote.Query().Where(questionvote.UserID(user.ID), questionvote.QuestionID(question.ID)).ExistX(ctx) {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have already upvoted this question")
+		return &err
+	}
+	db.QuestionVote.Create().SetUserID(user.ID).SetQuestionID(question.ID).ExecX(ctx)
+	question.Update().AddUpvotesCount(1).ExecX(ctx)
+	return nil
+}
+
+func (d DiscussionManager) RemoveQuestionVote(db *ent.Client, ctx context.Context, user *ent.User, question *ent.LessonQuestion) *config.ErrorResponse {
+	deleted := db.QuestionVote.Delete().Where(questionvote.UserID(user.ID), questionvote.QuestionID(question.ID)).ExecX(ctx)
+	if deleted == 0 {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have not upvoted this question")
+		return &err
+	}
+	question.Update().AddUpvotesCount(-1).ExecX(ctx)
+	return nil
+}
+
+// ------------------------------------------------------------------
+// ANSWERS
+// ------------------------------------------------------------------
+
+// GetAnswer returns the answer with its question, lesson and course
+func (d DiscussionManager) GetAnswer(db *ent.Client, ctx context.Context, answerID uuid.UUID) *ent.LessonAnswer {
+	answer, _ := db.LessonAnswer.Query().
+		Where(lessonanswer.ID(answerID)).
+		WithUser().
+		WithQuestion(func(qq *ent.LessonQuestionQuery) {
+			qq.WithLesson(func(lq *ent.LessonQuery) { lq.WithCourse() })
+		}).
+		Only(ctx)
+	return answer
+}
+
+// CreateAnswer answers the question, or replies to the parent answer when one is given.
+// Threads are one level deep, so replying to a reply adds to the thread of the answer it belongs to
+func (d DiscussionManager) CreateAnswer(db *ent.Client, ctx context.Context, user *ent.User, question *ent.LessonQuestion, parent *ent.LessonAnswer, body string) *ent.LessonAnswer {
+	create := db.LessonAnswer.Create().
+		SetQuestionID(question.ID).
+		SetUserID(user.ID).
+		SetBody(body)
+	if parent != nil {
+		parentID := parent.ID
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		create = create.SetParentID(parentID)
+	}
+	answer := create.SaveX(ctx)
+	question.Update().AddAnswersCount(1).ExecX(ctx)
+	answer.Edges.User = user
+	return answer
+}
+
+// DeleteAnswer deletes the answer along with its replies
+func (d DiscussionManager) DeleteAnswer(db *ent.Client, ctx context.Context, answer *ent.LessonAnswer) {
+	repliesCount := db.LessonAnswer.Query().Where(lessonanswer.ParentID(answer.ID)).CountX(ctx)
+	db.LessonAnswer.DeleteOne(answer).ExecX(ctx)
+	questionUpdate := db.LessonQuestion.UpdateOneID(answer.QuestionID).AddAnswersCount(-(repliesCount + 1))
+	if answer.IsAccepted {
+		questionUpdate = questionUpdate.SetIsResolved(false)
+	}
+	questionUpdate.ExecX(ctx)
+}
+
+func (d DiscussionManager) VoteAnswer(db *ent.Client, ctx context.Context, user *ent.User, answer *ent.LessonAnswer) *config.ErrorResponse {
+	if answer.UserID == user.ID {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You cannot upvote your own answer")
+		return &err
+	}
+	if db.AnswerVote.Query().Where(answervote.UserID(user.ID), answervote.AnswerID(answer.ID)).ExistX(ctx) {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have already upvoted this answer")
+		return &err
+	}
+	db.AnswerVote.Create().SetUserID(user.ID).SetAnswerID(answer.ID).ExecX(ctx)
+	answer.Update().AddUpvotesCount(1).ExecX(ctx)
+	return nil
+}
+
+func (d DiscussionManager) RemoveAnswerVote(db *ent.Client, ctx context.Context, user *ent.User, answer *ent.LessonAnswer) *config.ErrorResponse {
+	deleted := db.AnswerVote.Delete().Where(answervote.UserID(user.ID), answervote.AnswerID(answer.ID)).ExecX(ctx)
+	if deleted == 0 {
+		err := config.RequestErr(config.ERR_NOT_ALLOWED, "You have not upvoted this answer")
+		return &err
+	}
+	answer.Update().AddUpvotesCount(-1).ExecX(ctx)
+	return nil
+}
+
+// AcceptAnswer marks the answer as the question's accepted answer, replacing any previously accepted one
+func (d DiscussionManager) AcceptAnswer(db *ent.Client, ctx context.Context, answer *ent.LessonAnswer) *ent.LessonAnswer {
+	db.LessonAnswer.Update().
+		Where(lessonanswer.QuestionID(answer.QuestionID), lessonanswer.IsAccepted(true)).
+		SetIsAccepted(false).
+		ExecX(ctx)
+	db.LessonQuestion.UpdateOneID(answer.QuestionID).SetIsResolved(true).ExecX(ctx)
+	return d.updateAnswer(ctx, answer, answer.Update().SetIsAccepted(true))
+}
+
+func (d DiscussionManager) UnacceptAnswer(db *ent.Client, ctx context.Context, answer *ent.LessonAnswer) *ent.LessonAnswer {
+	db.LessonQuestion.UpdateOneID(answer.QuestionID).SetIsResolved(false).ExecX(ctx)
+	return d.updateAnswer(ctx, answer, answer.Update().SetIsAccepted(false))
+}
+
+func (d DiscussionManager) SetEndorsed(ctx context.Context, answer *ent.LessonAnswer, endorsed bool) *ent.LessonAnswer {
+	return d.updateAnswer(ctx, answer, answer.Update().SetIsEndorsed(endorsed))
+}
+
+// updateAnswer saves the update and keeps the answer's loaded edges on the result
+func (d DiscussionManager) updateAnswer(ctx context.Context, answer *ent.LessonAnswer, update *ent.LessonAnswerUpdateOne) *ent.LessonAnswer {
+	updatedAnswer := update.SaveX(ctx)
+	updatedAnswer.Edges = answer.Edges
+	return updatedAnswer
+}
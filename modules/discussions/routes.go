@@ -0,0 +1,484 @@
+package discussions
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
+)
+
+var discussionManager = DiscussionManager{}
+var courseManager = courses.CourseManager{}
+
+// checkAccess limits a lesson's discussions to the course's instructor and enrolled students the lesson is unlocked for.
+// The lesson must have its course loaded
+func checkAccess(db *ent.Client, c *fiber.Ctx, lessonObj *ent.Lesson) error {
+	ctx := c.Context()
+	user := base.RequestUser(c)
+	if !courseManager.HasAccess(db, ctx, user, lessonObj.Edges.Course) {
+		return config.APIError(c, 403, config.ForbiddenErr("Only for enrolled users"))
+	}
+	if gate := courseManager.LessonGates(db, ctx, user, lessonObj.Edges.Course, []*ent.Lesson{lessonObj})[lessonObj.ID]; gate.IsLocked {
+		return config.APIError(c, 403, config.ForbiddenErr(gate.Reason))
+	}
+	return nil
+}
+
+// getLesson returns the lesson in the path params if the user can take part in its discussions.
+// The lesson is looked up like the lesson details endpoint, then access is checked with checkAccess
+func getLesson(db *ent.Client, c *fiber.Ctx) (*ent.Lesson, error) {
+	lessonObj, err := courses.GetCourseLesson(db, c)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccess(db, c, lessonObj); err != nil {
+		return nil, err
+	}
+	return lessonObj, nil
+}
+
+// getQuestion returns the question in the id path param if the user can take part in its lesson's discussions
+func getQuestion(db *ent.Client, c *fiber.Ctx, loaded bool) (*ent.LessonQuestion, error) {
+	questionID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	question := discussionManager.GetQuestion(db, c.Context(), *questionID, loaded)
+	if question == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Question Not Found"))
+	}
+	if err := checkAccess(db, c, question.Edges.Lesson); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// getAnswer returns the answer in the id path param if the user can take part in its lesson's discussions
+func getAnswer(db *ent.Client, c *fiber.Ctx) (*ent.LessonAnswer, error) {
+	answerID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	answer := discussionManager.GetAnswer(db, c.Context(), *answerID)
+	if answer == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Answer Not Found"))
+	}
+	if err := checkAccess(db, c, answer.Edges.Question.Edges.Lesson); err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// isInstructor reports whether the user teaches the course the answer's question was asked in
+func isInstructor(user *ent.User, answer *ent.LessonAnswer) bool {
+	return answer.Edges.Question.Edges.Lesson.Edges.Course.InstructorID == user.ID
+}
+
+// @Summary Retrieve Lesson Questions
+// @Description `This endpoint retrieves paginated questions of a lesson's discussion board. Only the course's instructor and enrolled users the lesson is unlocked for can access it`
+// @Tags Discussions
+// @Param course_slug path string true "Course Slug"
+// @Param lesson_slug path string true "Lesson Slug"
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param search query string false "Search In Titles And Bodies"
+// @Param unanswered query bool false "Only Questions Without Answers"
+// @Param sort query string false "Sort By (newest or votes)" default(newest)
+// @Success 200 {object} QuestionsResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/{course_slug}/lessons/{lesson_slug}/questions [get]
+// @Security BearerAuth
+func GetLessonQuestions(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonObj, err := getLesson(db, c)
+		if err != nil {
+			return err
+		}
+		sortBy := c.Query("sort", QUESTIONS_SORT_NEWEST)
+		if sortBy != QUESTIONS_SORT_NEWEST && sortBy != QUESTIONS_SORT_VOTES {
+			return config.APIError(c, 400, config.InvalidParamErr("Invalid sort. Use newest or votes"))
+		}
+		questions := discussionManager.GetQuestionsPaginated(db, c, lessonObj, strings.TrimSpace(c.Query("search")), c.QueryBool("unanswered"), sortBy)
+		response := QuestionsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Questions Fetched Successfully"),
+		}.Assign(questions)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Ask A Question
+// @Description `This endpoint allows the course's instructor and enrolled users the lesson is unlocked for to ask a question on a lesson's discussion board`
+// @Tags Discussions
+// @Param course_slug path string true "Course Slug"
+// @Param lesson_slug path string true "Lesson Slug"
+// @Param question body QuestionCreateSchema true "Question object"
+// @Success 201 {object} QuestionResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /courses/{course_slug}/lessons/{lesson_slug}/questions [post]
+// @Security BearerAuth
+func CreateLessonQuestion(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lessonObj, err := getLesson(db, c)
+		if err != nil {
+			return err
+		}
+		data := QuestionCreateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		question := discussionManager.CreateQuestion(db, c.Context(), base.RequestUser(c), lessonObj, data)
+		response := QuestionResponseSchema{
+			ResponseSchema: base.ResponseMessage("Question Created Successfully"),
+			Data:           QuestionSchema{}.Assign(question),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Retrieve A Question
+// @Description `This endpoint retrieves a question with its answers and their replies. Accepted and endorsed answers come first, then the most upvoted`
+// @Tags Discussions
+// @Param id path string true "Question ID"
+// @Success 200 {object} QuestionDetailResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/questions/{id} [get]
+// @Security BearerAuth
+func GetLessonQuestion(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		question, err := getQuestion(db, c, true)
+		if err != nil {
+			return err
+		}
+		response := QuestionDetailResponseSchema{
+			ResponseSchema: base.ResponseMessage("Question Fetched Successfully"),
+			Data:           QuestionDetailSchema{}.Assign(question),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete A Question
+// @Description `This endpoint allows the asker or the course's instructor to delete a question along with its answers`
+// @Tags Discussions
+// @Param id path string true "Question ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/questions/{id} [delete]
+// @Security BearerAuth
+func DeleteLessonQuestion(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		question, err := getQuestion(db, c, false)
+		if err != nil {
+			return err
+		}
+		if question.UserID != user.ID && question.Edges.Lesson.Edges.Course.InstructorID != user.ID {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the asker and the instructor can delete this question"))
+		}
+		discussionManager.DeleteQuestion(db, c.Context(), question)
+		return c.Status(200).JSON(base.ResponseMessage("Question Deleted Successfully"))
+	}
+}
+
+// @Summary Upvote A Question
+// @Description `This endpoint allows a user to upvote a question. Users can't upvote their own questions`
+// @Tags Discussions
+// @Param id path string true "Question ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/questions/{id}/upvote [post]
+// @Security BearerAuth
+func UpvoteLessonQuestion(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		question, err := getQuestion(db, c, false)
+		if err != nil {
+			return err
+		}
+		if errData := discussionManager.VoteQuestion(db, c.Context(), base.RequestUser(c), question); errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		return c.Status(200).JSON(base.ResponseMessage("Question Upvoted Successfully"))
+	}
+}
+
+// @Summary Remove A Question Upvote
+// @Description `This endpoint allows a user to take back their upvote on a question`
+// @Tags Discussions
+// @Param id path string true "Question ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/questions/{id}/upvote [delete]
+// @Security BearerAuth
+func RemoveLessonQuestionUpvote(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		question, err := getQuestion(db, c, false)
+		if err != nil {
+			return err
+		}
+		if errData := discussionManager.RemoveQuestionVote(db, c.Context(), base.RequestUser(c), question); errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		return c.Status(200).JSON(base.ResponseMessage("Question Upvote Removed"))
+	}
+}
+
+// @Summary Answer A Question
+// @Description `This endpoint allows the course's instructor and enrolled users the lesson is unlocked for to answer a question, or reply to an answer by setting parent_id`
+// @Description `Threads are one level deep, so a reply to a reply is added to the thread of the answer it belongs to`
+// @Tags Discussions
+// @Param id path string true "Question ID"
+// @Param answer body AnswerCreateSchema true "Answer object"
+// @Success 201 {object} AnswerResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /courses/questions/{id}/answers [post]
+// @Security BearerAuth
+func CreateQuestionAnswer(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		question, err := getQuestion(db, c, false)
+		if err != nil {
+			return err
+		}
+		data := AnswerCreateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		var parent *ent.LessonAnswer
+		if data.ParentID != nil {
+			parent = discussionManager.GetAnswer(db, ctx, *data.ParentID)
+			if parent == nil || parent.QuestionID != question.ID {
+				return config.APIError(c, 422, config.ValidationErr("parent_id", "Answer not found for this question"))
+			}
+		}
+		answer := discussionManager.CreateAnswer(db, ctx, base.RequestUser(c), question, parent, data.Body)
+		response := AnswerResponseSchema{
+			ResponseSchema: base.ResponseMessage("Answer Created Successfully"),
+			Data:           AnswerSchema{}.Assign(answer),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Delete An Answer
+// @Description `This endpoint allows the answerer or the course's instructor to delete an answer along with its replies`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id} [delete]
+// @Security BearerAuth
+func DeleteQuestionAnswer(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if answer.UserID != user.ID && !isInstructor(user, answer) {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the answerer and the instructor can delete this answer"))
+		}
+		discussionManager.DeleteAnswer(db, c.Context(), answer)
+		return c.Status(200).JSON(base.ResponseMessage("Answer Deleted Successfully"))
+	}
+}
+
+// @Summary Upvote An Answer
+// @Description `This endpoint allows a user to upvote an answer or a reply. Users can't upvote their own answers`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id}/upvote [post]
+// @Security BearerAuth
+func UpvoteQuestionAnswer(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if errData := discussionManager.VoteAnswer(db, c.Context(), base.RequestUser(c), answer); errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		return c.Status(200).JSON(base.ResponseMessage("Answer Upvoted Successfully"))
+	}
+}
+
+// @Summary Remove An Answer Upvote
+// @Description `This endpoint allows a user to take back their upvote on an answer`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id}/upvote [delete]
+// @Security BearerAuth
+func RemoveQuestionAnswerUpvote(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if errData := discussionManager.RemoveAnswerVote(db, c.Context(), base.RequestUser(c), answer); errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		return c.Status(200).JSON(base.ResponseMessage("Answer Upvote Removed"))
+	}
+}
+
+// @Summary Accept An Answer
+// @Description `This endpoint allows the asker or the course's instructor to accept an answer, which resolves the question. Replies can't be accepted`
+// @Description `A question has a single accepted answer, so accepting another one replaces it`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} AnswerResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id}/accept [post]
+// @Security BearerAuth
+func AcceptQuestionAnswer(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if answer.Edges.Question.UserID != user.ID && !isInstructor(user, answer) {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the asker and the instructor can accept answers"))
+		}
+		if answer.ParentID != nil {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Replies can't be accepted"))
+		}
+		answer = discussionManager.AcceptAnswer(db, c.Context(), answer)
+		response := AnswerResponseSchema{
+			ResponseSchema: base.ResponseMessage("Answer Accepted Successfully"),
+			Data:           AnswerSchema{}.Assign(answer),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Unaccept An Answer
+// @Description `This endpoint allows the asker or the course's instructor to take back the acceptance of an answer, which reopens the question`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} AnswerResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id}/accept [delete]
+// @Security BearerAuth
+func UnacceptQuestionAnswer(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if answer.Edges.Question.UserID != user.ID && !isInstructor(user, answer) {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the asker and the instructor can unaccept answers"))
+		}
+		if !answer.IsAccepted {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "This answer is not accepted"))
+		}
+		answer = discussionManager.UnacceptAnswer(db, c.Context(), answer)
+		response := AnswerResponseSchema{
+			ResponseSchema: base.ResponseMessage("Answer Unaccepted Successfully"),
+			Data:           AnswerSchema{}.Assign(answer),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Endorse An Answer
+// @Description `This endpoint allows the course's instructor to endorse an answer or a reply`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} AnswerResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id}/endorse [post]
+// @Security BearerAuth
+func EndorseQuestionAnswer(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if !isInstructor(base.RequestUser(c), answer) {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the instructor can endorse answers"))
+		}
+		answer = discussionManager.SetEndorsed(c.Context(), answer, true)
+		response := AnswerResponseSchema{
+			ResponseSchema: base.ResponseMessage("Answer Endorsed Successfully"),
+			Data:           AnswerSchema{}.Assign(answer),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Remove An Answer Endorsement
+// @Description `This endpoint allows the course's instructor to take back their endorsement of an answer`
+// @Tags Discussions
+// @Param id path string true "Answer ID"
+// @Success 200 {object} AnswerResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /courses/answers/{id}/endorse [delete]
+// @Security BearerAuth
+func RemoveQuestionAnswerEndorsement(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		answer, err := getAnswer(db, c)
+		if err != nil {
+			return err
+		}
+		if !isInstructor(base.RequestUser(c), answer) {
+			return config.APIError(c, 403, config.ForbiddenErr("Only the instructor can remove endorsements"))
+		}
+		answer = discussionManager.SetEndorsed(c.Context(), answer, false)
+		response := AnswerResponseSchema{
+			ResponseSchema: base.ResponseMessage("Answer Endorsement Removed"),
+			Data:           AnswerSchema{}.Assign(answer),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
@@ -0,0 +1,122 @@
+package discussions
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+)
+
+const (
+	QUESTIONS_SORT_NEWEST = "newest"
+	QUESTIONS_SORT_VOTES  = "votes"
+)
+
+type QuestionCreateSchema struct {
+	Title string `json:"title" validate:"required,max=200" example:"Why does the loop skip the last item?"`
+	Body  string `json:"body" validate:"required,max=5000" example:"At 04:12 the range loop stops before the last element. What am I missing?"`
+}
+
+type AnswerCreateSchema struct {
+	Body     string     `json:"body" validate:"required,max=5000" example:"The slice is re-sliced inside the loop, so its length changes"`
+	ParentID *uuid.UUID `json:"parent_id"` // Set to reply to an answer
+}
+
+type QuestionSchema struct {
+	ID           uuid.UUID           `json:"id"`
+	User         base.UserDataSchema `json:"user"`
+	Title        string              `json:"title" example:"Why does the loop skip the last item?"`
+	Body         string              `json:"body" example:"At 04:12 the range loop stops before the last element. What am I missing?"`
+	UpvotesCount int                 `json:"upvotes_count" example:"4"`
+	AnswersCount int                 `json:"answers_count" example:"2"`
+	IsResolved   bool                `json:"is_resolved" example:"false"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+func (q QuestionSchema) Assign(question *ent.LessonQuestion) QuestionSchema {
+	q.ID = question.ID
+	q.User = q.User.Assign(question.Edges.User)
+	q.Title = question.Title
+	q.Body = question.Body
+	q.UpvotesCount = question.UpvotesCount
+	q.AnswersCount = question.AnswersCount
+	q.IsResolved = question.IsResolved
+	q.CreatedAt = question.CreatedAt
+	return q
+}
+
+type AnswerSchema struct {
+	ID           uuid.UUID           `json:"id"`
+	User         base.UserDataSchema `json:"user"`
+	ParentID     *uuid.UUID          `json:"parent_id"`
+	Body         string              `json:"body" example:"The slice is re-sliced inside the loop, so its length changes"`
+	UpvotesCount int                 `json:"upvotes_count" example:"3"`
+	IsAccepted   bool                `json:"is_accepted" example:"true"`
+	IsEndorsed   bool                `json:"is_endorsed" example:"false"`
+	Replies      []AnswerSchema      `json:"replies,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+func (a AnswerSchema) Assign(answer *ent.LessonAnswer) AnswerSchema {
+	a.ID = answer.ID
+	a.User = a.User.Assign(answer.Edges.User)
+	a.ParentID = answer.ParentID
+	a.Body = answer.Body
+	a.UpvotesCount = answer.UpvotesCount
+	a.IsAccepted = answer.IsAccepted
+	a.IsEndorsed = answer.IsEndorsed
+	for _, reply := range answer.Edges.Replies {
+		a.Replies = append(a.Replies, AnswerSchema{}.Assign(reply))
+	}
+	a.CreatedAt = answer.CreatedAt
+	return a
+}
+
+type QuestionDetailSchema struct {
+	QuestionSchema
+	Answers []AnswerSchema `json:"answers"`
+}
+
+func (q QuestionDetailSchema) Assign(question *ent.LessonQuestion) QuestionDetailSchema {
+	q.QuestionSchema = q.QuestionSchema.Assign(question)
+	q.Answers = make([]AnswerSchema, 0)
+	for _, answer := range question.Edges.Answers {
+		q.Answers = append(q.Answers, AnswerSchema{}.Assign(answer))
+	}
+	return q
+}
+
+type QuestionResponseSchema struct {
+	base.ResponseSchema
+	Data QuestionSchema `json:"data"`
+}
+
+type QuestionDetailResponseSchema struct {
+	base.ResponseSchema
+	Data QuestionDetailSchema `json:"data"`
+}
+
+type QuestionsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[QuestionSchema] `json:"data"`
+}
+
+func (q QuestionsResponseSchema) Assign(questionsData *config.PaginationResponse[*ent.LessonQuestion]) QuestionsResponseSchema {
+	items := make([]QuestionSchema, 0)
+	for _, question := range questionsData.Items {
+		items = append(items, QuestionSchema{}.Assign(question))
+	}
+	q.Data.Items = items
+	q.Data.ItemsCount = questionsData.ItemsCount
+	q.Data.Page = questionsData.Page
+	q.Data.TotalPages = questionsData.TotalPages
+	q.Data.Limit = questionsData.Limit
+	return q
+}
+
+type AnswerResponseSchema struct {
+	base.ResponseSchema
+	Data AnswerSchema `json:"data"`
+}
@@ -13,7 +13,7 @@ import (
 // @Router /general/site-detail [get]
 func GetSiteDetails(db *ent.Client) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		sitedetail := SiteDetailService{}.GetOrCreate(db, c.Context())
+		sitedetail := Settings(db, c.Context())
 		responseSiteDetail := SiteDetailResponseSchema{
 			ResponseSchema: base.ResponseMessage("Site Details Fetched!"),
 			Data:           SiteDetailSchema{}.Init(sitedetail),
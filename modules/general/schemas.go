@@ -14,6 +14,11 @@ type SiteDetailSchema struct {
 	Tw 			string		`json:"tw" example:"https://twitter.com"`
 	Wh 			string		`json:"wh" example:"https://wa.me/2348133831036"`
 	Ig 			string		`json:"ig" example:"https://instagram.com"`
+	SupportEmail 		string 		`json:"support_email" example:"support@email.com"`
+	Logo 			*string 	`json:"logo" example:"https://ednet.com/logo.png"`
+	DefaultCurrency 	string 		`json:"default_currency" example:"usd"`
+	AiSummaryDailyLimit 	int 		`json:"ai_summary_daily_limit" example:"10"`
+	MaxUploadSizeMB 	int 		`json:"max_upload_size_mb" example:"10"`
 }
 
 func (s SiteDetailSchema) Init(obj *ent.SiteDetail) SiteDetailSchema {
@@ -25,6 +30,11 @@ func (s SiteDetailSchema) Init(obj *ent.SiteDetail) SiteDetailSchema {
 	s.Tw = obj.Tw
 	s.Wh = obj.Wh
 	s.Ig = obj.Ig
+	s.SupportEmail = obj.SupportEmail
+	s.Logo = obj.Logo
+	s.DefaultCurrency = obj.DefaultCurrency
+	s.AiSummaryDailyLimit = obj.AiSummaryDailyLimit
+	s.MaxUploadSizeMB = obj.MaxUploadSizeMB
 	return s
 }
 
@@ -0,0 +1,66 @@
+package general
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+// Platform settings live on the single SiteDetail row. They are cached in memory so hot paths don't
+// query them on every request, and reloaded periodically (and right after an admin update) so that
+// changes apply on every instance without a restart.
+
+const settingsReloadInterval = time.Minute
+
+type settingsCache struct {
+	mu     sync.RWMutex
+	detail *ent.SiteDetail
+}
+
+var platformSettings = &settingsCache{}
+
+// LoadSettings reads the settings from the database (creating them with defaults if missing) and replaces the cached copy
+func LoadSettings(db *ent.Client, ctx context.Context) *ent.SiteDetail {
+	sitedetail := SiteDetailService{}.GetOrCreate(db, ctx)
+	platformSettings.mu.Lock()
+	platformSettings.detail = sitedetail
+	platformSettings.mu.Unlock()
+	config.SetMaxUploadSizeMB(sitedetail.MaxUploadSizeMB)
+	return sitedetail
+}
+
+// Settings returns the cached settings, loading them on first use
+func Settings(db *ent.Client, ctx context.Context) *ent.SiteDetail {
+	platformSettings.mu.RLock()
+	sitedetail := platformSettings.detail
+	platformSettings.mu.RUnlock()
+	if sitedetail == nil {
+		sitedetail = LoadSettings(db, ctx)
+	}
+	return sitedetail
+}
+
+// StartSettingsReloader reloads the settings periodically until the context is cancelled
+func StartSettingsReloader(db *ent.Client, ctx context.Context) {
+	ticker := time.NewTicker(settingsReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("Settings reload failed: %v\n", r)
+					}
+				}()
+				LoadSettings(db, ctx)
+			}()
+		}
+	}
+}
@@ -2,39 +2,47 @@ package instructors
 
 import (
 	"context"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/instructorapplication"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/lesson"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/question"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/questionoption"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/quiz"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/review"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/section"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
 )
 
 type InstructorManager struct{}
 
-func (i InstructorManager) CreateCourse(db *ent.Client, ctx context.Context, instructor *ent.User, category *ent.Category, thumbnailUrl string, introVideoUrl *string, data CourseCreateSchema) *ent.Course {
+func (i InstructorManager) CreateCourse(db *ent.Client, ctx context.Context, instructor *ent.User, category *ent.Category, tags []*ent.Tag, thumbnailUrl string, introVideoUrl *string, data CourseCreateSchema) *ent.Course {
 	slug := i.GenerateCourseSlug(db, ctx, data.Title)
 	course := db.Course.Create().SetTitle(data.Title).SetSlug(slug).SetDesc(data.Desc).
 		SetInstructor(instructor).SetCategoryID(category.ID).SetLanguage(data.Language).
 		SetDifficulty(data.Difficulty).SetDuration(data.Duration).SetIsFree(data.IsFree).
 		SetThumbnailURL(thumbnailUrl).SetNillableIntroVideoURL(introVideoUrl).
 		SetPrice(data.Price).SetDiscountPrice(data.DiscountPrice).SetEnrollmentType(data.EnrollmentType).
-		SetCertification(data.Certification).SaveX(ctx)
+		SetCertification(data.Certification).AddTags(tags...).SaveX(ctx)
 
 	// Edges reassignment to prevent reload
 	course.Edges.Instructor = instructor
 	course.Edges.Category = category
+	course.Edges.Tags = tags
 	course.Edges.Reviews = []*ent.Review{}
 	course.Edges.Enrollments = []*ent.Enrollment{}
 	course.Edges.Lessons = []*ent.Lesson{}
 	return course
 }
 
-func (i InstructorManager) UpdateCourse(db *ent.Client, ctx context.Context, course *ent.Course, category *ent.Category, thumbnailUrl *string, introVideoUrl *string, data CourseCreateSchema) *ent.Course {
+func (i InstructorManager) UpdateCourse(db *ent.Client, ctx context.Context, course *ent.Course, category *ent.Category, tags []*ent.Tag, thumbnailUrl *string, introVideoUrl *string, data CourseCreateSchema) *ent.Course {
 	slug := course.Slug
 	if data.Title != course.Title {
 		slug = i.GenerateCourseSlug(db, ctx, data.Title)
@@ -43,24 +51,76 @@ func (i InstructorManager) UpdateCourse(db *ent.Client, ctx context.Context, cou
 		SetCategoryID(category.ID).SetLanguage(data.Language).
 		SetDifficulty(data.Difficulty).SetDuration(data.Duration).SetIsFree(data.IsFree).
 		SetPrice(data.Price).SetDiscountPrice(data.DiscountPrice).SetEnrollmentType(data.EnrollmentType).
-		SetCertification(data.Certification)
+		SetCertification(data.Certification).ClearTags().AddTags(tags...)
 	if thumbnailUrl != nil {
 		updatedCourseQuery = updatedCourseQuery.SetThumbnailURL(*thumbnailUrl)
 	}
 	if introVideoUrl != nil {
 		updatedCourseQuery = updatedCourseQuery.SetIntroVideoURL(*introVideoUrl)
 	}
+	// Major edits of a reviewed course must be reviewed again, and take it off the catalogue meanwhile
+	if i.NeedsReReview(course, category, thumbnailUrl, introVideoUrl, data) {
+		updatedCourseQuery = sendBackForReview(course, updatedCourseQuery)
+	}
 	updatedCourse := updatedCourseQuery.SaveX(ctx)
 
 	// Edges reassignment to prevent reload
 	updatedCourse.Edges.Instructor = course.Edges.Instructor
 	updatedCourse.Edges.Category = category
+	updatedCourse.Edges.Tags = tags
 	updatedCourse.Edges.Reviews = course.Edges.Reviews
 	updatedCourse.Edges.Enrollments = course.Edges.Enrollments
 	updatedCourse.Edges.Lessons = course.Edges.Lessons
 	return updatedCourse
 }
 
+// NeedsReReview reports whether an update of a reviewed course changes what reviewers approved:
+// the title, description, category or media.
+// Lesson and quiz edits are exempt on purpose. Reviews approve the course listing, and live courses keep getting
+// lessons and quizzes without going offline. Published lessons with paid enrollments still can't be deleted.
+func (i InstructorManager) NeedsReReview(courseObj *ent.Course, category *ent.Category, thumbnailUrl *string, introVideoUrl *string, data CourseCreateSchema) bool {
+	if courseObj.Status != course.StatusApproved && courseObj.Status != course.StatusPublished {
+		return false
+	}
+	return data.Title != courseObj.Title || data.Desc != courseObj.Desc || category.ID != courseObj.CategoryID ||
+		thumbnailUrl != nil || introVideoUrl != nil
+}
+
+// sendBackForReview queues the course for review again. Only a course that is live now goes live again once approved
+func sendBackForReview(courseObj *ent.Course, updateQuery *ent.CourseUpdateOne) *ent.CourseUpdateOne {
+	return updateQuery.SetStatus(course.StatusSubmitted).SetIsPublished(false).SetSubmittedAt(time.Now()).
+		SetRepublishOnApproval(courseObj.Status == course.StatusPublished)
+}
+
+func (i InstructorManager) CourseHasLessons(db *ent.Client, ctx context.Context, courseObj *ent.Course) bool {
+	return db.Lesson.Query().Where(lesson.CourseIDEQ(courseObj.ID)).ExistX(ctx)
+}
+
+// SubmitCourse sends a draft or rejected course to the review queue
+func (i InstructorManager) SubmitCourse(db *ent.Client, ctx context.Context, courseObj *ent.Course) *ent.Course {
+	updatedCourse := courseObj.Update().
+		SetStatus(course.StatusSubmitted).
+		SetSubmittedAt(time.Now()).
+		ClearRejectionReason().
+		SaveX(ctx)
+	updatedCourse.Edges = courseObj.Edges
+	return updatedCourse
+}
+
+// SetCoursePublished publishes an approved course, or takes a published one back to approved
+func (i InstructorManager) SetCoursePublished(db *ent.Client, ctx context.Context, courseObj *ent.Course, publish bool) *ent.Course {
+	updateQuery := courseObj.Update().SetIsPublished(publish).SetStatus(course.StatusApproved)
+	if publish {
+		updateQuery = updateQuery.SetStatus(course.StatusPublished)
+		if courseObj.PublishedAt == nil {
+			updateQuery = updateQuery.SetPublishedAt(time.Now())
+		}
+	}
+	updatedCourse := updateQuery.SaveX(ctx)
+	updatedCourse.Edges = courseObj.Edges
+	return updatedCourse
+}
+
 func (i InstructorManager) DeleteCourse(db *ent.Client, ctx context.Context, courseObj *ent.Course) *string {
 	// Prevent deletion if there's a paid enrollment
 	enrollmentExists := db.Enrollment.Query().Where(enrollment.CourseIDEQ(courseObj.ID), enrollment.PaymentStatusEQ(enrollment.PaymentStatusSuccessful)).ExistX(ctx)
@@ -91,12 +151,11 @@ func (i InstructorManager) GetCoursesPaginated(db *ent.Client, fibCtx *fiber.Ctx
 		WithInstructor().
 		WithCategory().
 		WithTags().
-		WithReviews().
+		WithReviews(courses.VisibleReviews).
 		WithEnrollments().
 		WithLessons()
 	query = courseManager.ApplyCourseFilters(fibCtx, query)
-	courses := config.PaginateModel(fibCtx, query)
-	return courses
+	return config.PaginateModel(fibCtx, query)
 }
 
 func (i InstructorManager) GenerateLessonSlug(db *ent.Client, ctx context.Context, title string) string {
@@ -112,10 +171,10 @@ func (i InstructorManager) GenerateLessonSlug(db *ent.Client, ctx context.Contex
 	return uniqueSlug
 }
 
-func (i InstructorManager) CreateLesson(db *ent.Client, ctx context.Context, course *ent.Course, thumbnailUrl string, videoUrl *string, data LessonCreateSchema) *ent.Lesson {
+func (i InstructorManager) CreateLesson(db *ent.Client, ctx context.Context, course *ent.Course, sectionID *uuid.UUID, thumbnailUrl string, videoUrl *string, data LessonCreateSchema) *ent.Lesson {
 	slug := i.GenerateLessonSlug(db, ctx, data.Title)
 	lessonObj := db.Lesson.Create().SetTitle(data.Title).SetSlug(slug).SetDesc(data.Desc).
-		SetCourse(course).SetNillableContent(data.Content).SetOrder(data.Order).
+		SetCourse(course).SetNillableSectionID(sectionID).SetNillableContent(data.Content).SetOrder(data.Order).
 		SetIsPublished(data.IsPublished).SetDuration(data.Duration).SetIsFreePreview(data.IsFreePreview).
 		SetThumbnailURL(thumbnailUrl).SetNillableVideoURL(videoUrl).
 		SaveX(ctx)
@@ -155,6 +214,87 @@ func (i InstructorManager) DeleteLesson(db *ent.Client, ctx context.Context, les
 	return nil
 }
 
+// GetCourseSection returns the section if it's in one of the instructor's courses
+func (i InstructorManager) GetCourseSection(db *ent.Client, ctx context.Context, instructor *ent.User, sectionID uuid.UUID) *ent.Section {
+	sectionObj, _ := db.Section.Query().
+		Where(section.ID(sectionID), section.HasCourseWith(course.InstructorIDEQ(instructor.ID))).
+		WithLessons(func(lq *ent.LessonQuery) { lq.Order(ent.Asc(lesson.FieldOrder)).WithPrerequisite() }).
+		Only(ctx)
+	return sectionObj
+}
+
+func (i InstructorManager) SectionInCourse(db *ent.Client, ctx context.Context, courseObj *ent.Course, sectionID uuid.UUID) bool {
+	return db.Section.Query().Where(section.ID(sectionID), section.CourseID(courseObj.ID)).ExistX(ctx)
+}
+
+func (i InstructorManager) CreateSection(db *ent.Client, ctx context.Context, courseObj *ent.Course, data SectionCreateSchema) *ent.Section {
+	sectionObj := db.Section.Create().
+		SetCourseID(courseObj.ID).
+		SetTitle(data.Title).
+		SetDescription(data.Description).
+		SetOrder(data.Order).
+		SaveX(ctx)
+	sectionObj.Edges.Lessons = []*ent.Lesson{}
+	return sectionObj
+}
+
+func (i InstructorManager) UpdateSection(db *ent.Client, ctx context.Context, sectionObj *ent.Section, data SectionCreateSchema) *ent.Section {
+	updatedSection := sectionObj.Update().
+		SetTitle(data.Title).
+		SetDescription(data.Description).
+		SetOrder(data.Order).
+		SaveX(ctx)
+	updatedSection.Edges = sectionObj.Edges
+	return updatedSection
+}
+
+// DeleteSection deletes the section. Its lessons are kept, outside any section
+func (i InstructorManager) DeleteSection(db *ent.Client, ctx context.Context, sectionObj *ent.Section) {
+	db.Section.DeleteOne(sectionObj).ExecX(ctx)
+}
+
+// MoveLesson puts the lesson at the given position of a section of its course, or out of any section when sectionID is nil
+func (i InstructorManager) MoveLesson(db *ent.Client, ctx context.Context, lessonObj *ent.Lesson, sectionID *uuid.UUID, order uint) *ent.Lesson {
+	update := lessonObj.Update().SetOrder(order)
+	if sectionID != nil {
+		update = update.SetSectionID(*sectionID)
+	} else {
+		update = update.ClearSectionID()
+	}
+	return update.SaveX(ctx)
+}
+
+// CreatesPrerequisiteCycle reports whether making prerequisite the lesson's prerequisite would have the lesson require itself
+func (i InstructorManager) CreatesPrerequisiteCycle(db *ent.Client, ctx context.Context, lessonObj *ent.Lesson, prerequisite *ent.Lesson) bool {
+	for current := prerequisite; ; current = db.Lesson.GetX(ctx, *current.PrerequisiteID) {
+		if current.ID == lessonObj.ID {
+			return true
+		}
+		if current.PrerequisiteID == nil {
+			return false
+		}
+	}
+}
+
+// SetUnlockConditions replaces the lesson's unlock conditions. Conditions left out of data are cleared
+func (i InstructorManager) SetUnlockConditions(db *ent.Client, ctx context.Context, lessonObj *ent.Lesson, prerequisite *ent.Lesson, data courses.LessonUnlockConditionsSchema) *ent.Lesson {
+	update := lessonObj.Update().
+		ClearUnlockAfterDays().
+		ClearUnlockAt().
+		ClearPrerequisiteID().
+		ClearPrerequisiteMinScore().
+		SetNillableUnlockAfterDays(data.UnlockAfterDays).
+		SetNillableUnlockAt(data.UnlockAt).
+		SetNillablePrerequisiteMinScore(data.PrerequisiteMinScore)
+	if prerequisite != nil {
+		update = update.SetPrerequisiteID(prerequisite.ID)
+	}
+	updatedLesson := update.SaveX(ctx)
+	updatedLesson.Edges = lessonObj.Edges
+	updatedLesson.Edges.Prerequisite = prerequisite
+	return updatedLesson
+}
+
 func (i InstructorManager) GenerateQuizSlug(db *ent.Client, ctx context.Context, title string) string {
 	baseSlug := config.Slugify(title)
 	uniqueSlug := baseSlug
@@ -243,3 +383,65 @@ func (i InstructorManager) DeleteQuiz(db *ent.Client, ctx context.Context, quizO
 	db.Quiz.DeleteOne(quizObj).ExecX(ctx)
 	return nil
 }
+
+// ----------------------------------
+// INSTRUCTOR APPLICATIONS
+// --------------------------------
+func (i InstructorManager) GetPendingApplication(db *ent.Client, ctx context.Context, userID uuid.UUID) *ent.InstructorApplication {
+	application, _ := db.InstructorApplication.Query().
+		Where(instructorapplication.UserID(userID), instructorapplication.StatusEQ(instructorapplication.StatusPending)).
+		Only(ctx)
+	return application
+}
+
+func (i InstructorManager) GetUserApplicationsPaginated(db *ent.Client, fibCtx *fiber.Ctx, applicant *ent.User) *config.PaginationResponse[*ent.InstructorApplication] {
+	query := db.InstructorApplication.Query().
+		Where(instructorapplication.UserID(applicant.ID)).
+		WithUser().
+		Order(ent.Desc(instructorapplication.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (i InstructorManager) CreateApplication(db *ent.Client, ctx context.Context, applicant *ent.User, sampleFileUrl *string, data InstructorApplicationCreateSchema) *ent.InstructorApplication {
+	application := db.InstructorApplication.Create().
+		SetUserID(applicant.ID).
+		SetBio(data.Bio).
+		SetExpertise(data.Expertise).
+		SetNillableSampleURL(data.SampleUrl).
+		SetNillableSampleFileURL(sampleFileUrl).
+		SaveX(ctx)
+	application.Edges.User = applicant
+	return application
+}
+
+// GetCourseReview returns a visible review left on one of the instructor's courses
+func (i InstructorManager) GetCourseReview(db *ent.Client, ctx context.Context, instructor *ent.User, reviewID uuid.UUID) *ent.Review {
+	reviewObj, _ := db.Review.Query().
+		Where(
+			review.ID(reviewID),
+			review.IsHidden(false),
+			review.HasCourseWith(course.InstructorIDEQ(instructor.ID)),
+		).
+		WithUser().
+		WithCourse().
+		Only(ctx)
+	return reviewObj
+}
+
+// GetEnrolledStudents returns the students with a successful enrollment in the course
+func (i InstructorManager) GetEnrolledStudents(db *ent.Client, ctx context.Context, courseObj *ent.Course) []*ent.User {
+	return db.User.Query().
+		Where(user.HasEnrollmentsWith(enrollment.CourseID(courseObj.ID), enrollment.PaymentStatusEQ(enrollment.PaymentStatusSuccessful))).
+		AllX(ctx)
+}
+
+// SetReviewReply creates or replaces the instructor's reply. A review has at most one reply
+func (i InstructorManager) SetReviewReply(db *ent.Client, ctx context.Context, reviewObj *ent.Review, content string) *ent.Review {
+	updatedReview := reviewObj.Update().SetReply(content).SetRepliedAt(time.Now()).SaveX(ctx)
+	updatedReview.Edges = reviewObj.Edges
+	return updatedReview
+}
+
+func (i InstructorManager) DeleteReviewReply(db *ent.Client, ctx context.Context, reviewObj *ent.Review) {
+	reviewObj.Update().ClearReply().ClearRepliedAt().ExecX(ctx)
+}
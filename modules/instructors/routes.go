@@ -1,11 +1,19 @@
 package instructors
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/notifications"
 )
 
 var instructorManager = InstructorManager{}
@@ -34,6 +42,31 @@ func GetInstructorCourses(db *ent.Client) fiber.Handler {
 	}
 }
 
+// MaxCourseTags caps how many tags a course can have
+const MaxCourseTags = 10
+
+// getCourseTags resolves the tag slugs sent with a course form
+func getCourseTags(db *ent.Client, ctx context.Context, tagSlugs []string) ([]*ent.Tag, *config.ErrorResponse) {
+	slugs := []string{}
+	seen := map[string]bool{}
+	for _, slug := range tagSlugs {
+		if slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > MaxCourseTags {
+		errData := config.ValidationErr("tag_slugs", fmt.Sprintf("%d tags max", MaxCourseTags))
+		return nil, &errData
+	}
+	tags := courseManager.GetTagsBySlugs(db, ctx, slugs)
+	if len(tags) != len(slugs) {
+		errData := config.ValidationErr("tag_slugs", "Invalid tag slug")
+		return nil, &errData
+	}
+	return tags, nil
+}
+
 // @Summary Create A Course
 // @Description `This endpoint allows an instructor to create a course`
 // @Tags Instructor
@@ -55,6 +88,10 @@ func CreateCourse(db *ent.Client) fiber.Handler {
 		if category == nil {
 			return config.APIError(c, 422, config.ValidationErr("categorySlug", "Invalid category slug"))
 		}
+		tags, errData := getCourseTags(db, ctx, data.TagSlugs)
+		if errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
 
 		// Check and validate files
 		thumbnail, err := config.ValidateFile(c, "thumbnail", true, false)
@@ -68,7 +105,7 @@ func CreateCourse(db *ent.Client) fiber.Handler {
 		thumbnailUrl := config.UploadFile(thumbnail, string(config.FF_THUMBNAIL))
 		introVideoUrl := config.UploadFile(introVideo, string(config.FF_INTRO_VIDEOS))
 
-		course := instructorManager.CreateCourse(db, ctx, user, category, thumbnailUrl, &introVideoUrl, data)
+		course := instructorManager.CreateCourse(db, ctx, user, category, tags, thumbnailUrl, &introVideoUrl, data)
 		response := courses.CourseResponseSchema{
 			ResponseSchema: base.ResponseMessage("Course Created Successfully"),
 			Data:           courses.CourseDetailSchema{}.Assign(course),
@@ -129,6 +166,10 @@ func UpdateCourse(db *ent.Client) fiber.Handler {
 		if category == nil {
 			return config.APIError(c, 422, config.ValidationErr("categorySlug", "Invalid category slug"))
 		}
+		tags, errData := getCourseTags(db, ctx, data.TagSlugs)
+		if errData != nil {
+			return config.APIError(c, 422, *errData)
+		}
 
 		// Check and validate files
 		thumbnail, err := config.ValidateFile(c, "thumbnail", false, false)
@@ -151,15 +192,109 @@ func UpdateCourse(db *ent.Client) fiber.Handler {
 			url := config.UploadFile(introVideo, string(config.FF_INTRO_VIDEOS))
 			introVideoUrl = &url
 		}
-		updatedCourse := instructorManager.UpdateCourse(db, ctx, course, category, thumbnailUrl, introVideoUrl, data)
+		updatedCourse := instructorManager.UpdateCourse(db, ctx, course, category, tags, thumbnailUrl, introVideoUrl, data)
+		message := "Course Updated Successfully"
+		if updatedCourse.Status != course.Status {
+			message = "Course Updated Successfully. The changes must be reviewed again before it goes live"
+		}
 		response := courses.CourseResponseSchema{
-			ResponseSchema: base.ResponseMessage("Course Updated Successfully"),
+			ResponseSchema: base.ResponseMessage(message),
 			Data:           courses.CourseDetailSchema{}.Assign(updatedCourse),
 		}
 		return c.Status(200).JSON(response)
 	}
 }
 
+// @Summary Submit A Course For Review
+// @Description `This endpoint sends a draft or rejected course to the admins' review queue. The course needs at least one lesson`
+// @Tags Instructor
+// @Param slug path string true "Course Slug"
+// @Success 200 {object} courses.CourseResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /instructor/courses/{slug}/submit [post]
+// @Security BearerAuth
+func SubmitCourse(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		courseObj := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), user, true)
+		if courseObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor has no course with that slug"))
+		}
+		if courseObj.Status != course.StatusDraft && courseObj.Status != course.StatusRejected {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, fmt.Sprintf("A %s course can't be submitted", courseObj.Status)))
+		}
+		if !instructorManager.CourseHasLessons(db, ctx, courseObj) {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Add at least one lesson before submitting the course"))
+		}
+		courseObj = instructorManager.SubmitCourse(db, ctx, courseObj)
+		response := courses.CourseResponseSchema{
+			ResponseSchema: base.ResponseMessage("Course Submitted For Review"),
+			Data:           courses.CourseDetailSchema{}.Assign(courseObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Publish A Course
+// @Description `This endpoint makes an approved course visible in the catalogue`
+// @Tags Instructor
+// @Param slug path string true "Course Slug"
+// @Success 200 {object} courses.CourseResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /instructor/courses/{slug}/publish [post]
+// @Security BearerAuth
+func PublishCourse(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		courseObj := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), user, true)
+		if courseObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor has no course with that slug"))
+		}
+		if courseObj.Status != course.StatusApproved {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Only approved courses can be published"))
+		}
+		courseObj = instructorManager.SetCoursePublished(db, ctx, courseObj, true)
+		response := courses.CourseResponseSchema{
+			ResponseSchema: base.ResponseMessage("Course Published Successfully"),
+			Data:           courses.CourseDetailSchema{}.Assign(courseObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Unpublish A Course
+// @Description `This endpoint removes a published course from the catalogue. It stays approved and can be published again without review`
+// @Tags Instructor
+// @Param slug path string true "Course Slug"
+// @Success 200 {object} courses.CourseResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /instructor/courses/{slug}/unpublish [post]
+// @Security BearerAuth
+func UnpublishCourse(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		courseObj := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), user, true)
+		if courseObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor has no course with that slug"))
+		}
+		if courseObj.Status != course.StatusPublished {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Course is not published"))
+		}
+		courseObj = instructorManager.SetCoursePublished(db, ctx, courseObj, false)
+		response := courses.CourseResponseSchema{
+			ResponseSchema: base.ResponseMessage("Course Unpublished Successfully"),
+			Data:           courses.CourseDetailSchema{}.Assign(courseObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
 // @Summary Delete A Course
 // @Description `This endpoint allows an authenticated instructor to delete a course`
 // @Tags Instructor
@@ -214,7 +349,7 @@ func GetInstructorCourseLessons(db *ent.Client) fiber.Handler {
 }
 
 // @Summary Create Course Lesson
-// @Description `This endpoint creates a lesson of a particular course for the authenticated instructor`
+// @Description `This endpoint creates a lesson of a particular course for the authenticated instructor. Set section_id to add it to one of the course's sections`
 // @Tags Instructor
 // @Param slug path string true "Course Slug"
 // @Param lesson formData LessonCreateSchema true "Lesson object"
@@ -246,6 +381,14 @@ func CreateInstructorCourseLesson(db *ent.Client) fiber.Handler {
 		if err != nil {
 			return c.Status(422).JSON(err)
 		}
+		var sectionID *uuid.UUID
+		if data.SectionID != nil {
+			id := uuid.MustParse(*data.SectionID)
+			if !instructorManager.SectionInCourse(db, ctx, course, id) {
+				return config.APIError(c, 422, config.ValidationErr("section_id", "Section not found in this course"))
+			}
+			sectionID = &id
+		}
 		thumbnailUrl := config.UploadFile(thumbnail, string(config.FF_THUMBNAIL))
 		var videoUrl *string
 		if video != nil {
@@ -253,7 +396,7 @@ func CreateInstructorCourseLesson(db *ent.Client) fiber.Handler {
 			videoUrl = &url
 		}
 
-		lesson := instructorManager.CreateLesson(db, ctx, course, thumbnailUrl, videoUrl, data)
+		lesson := instructorManager.CreateLesson(db, ctx, course, sectionID, thumbnailUrl, videoUrl, data)
 
 		response := courses.LessonResponseSchema{
 			ResponseSchema: base.ResponseMessage("Lesson Created Successfully"),
@@ -263,6 +406,207 @@ func CreateInstructorCourseLesson(db *ent.Client) fiber.Handler {
 	}
 }
 
+// getSection returns the section in the id path param if it's in one of the instructor's courses
+func getSection(db *ent.Client, c *fiber.Ctx) (*ent.Section, error) {
+	sectionID, errData := config.ParseUUID(c.Params("id"))
+	if errData != nil {
+		return nil, config.APIError(c, 400, *errData)
+	}
+	sectionObj := instructorManager.GetCourseSection(db, c.Context(), base.RequestUser(c), *sectionID)
+	if sectionObj == nil {
+		return nil, config.APIError(c, 404, config.NotFoundErr("Instructor section not found"))
+	}
+	return sectionObj, nil
+}
+
+// @Summary Retrieve Course Curriculum
+// @Description `This endpoint retrieves the sections of a particular course for the authenticated instructor, each with its lessons. Lessons that aren't in any section are listed after them`
+// @Tags Instructor
+// @Param slug path string true "Course Slug"
+// @Success 200 {object} courses.CurriculumResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /instructor/courses/{slug}/sections [get]
+// @Security BearerAuth
+func GetInstructorCourseSections(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		course := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), user, false)
+		if course == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor has no course with that slug"))
+		}
+		sections, unsectionedLessons := courseManager.GetCurriculum(db, ctx, course)
+		response := courses.CurriculumResponseSchema{
+			ResponseSchema: base.ResponseMessage("Curriculum Fetched Successfully"),
+			Data:           courses.CurriculumSchema{}.Assign(sections, unsectionedLessons, nil),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Create Course Section
+// @Description `This endpoint creates a section in a particular course for the authenticated instructor`
+// @Tags Instructor
+// @Param slug path string true "Course Slug"
+// @Param section body SectionCreateSchema true "Section object"
+// @Success 201 {object} courses.SectionResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /instructor/courses/{slug}/sections [post]
+// @Security BearerAuth
+func CreateCourseSection(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		course := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), user, false)
+		if course == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor has no course with that slug"))
+		}
+		data := SectionCreateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		section := instructorManager.CreateSection(db, ctx, course, data)
+		response := courses.SectionResponseSchema{
+			ResponseSchema: base.ResponseMessage("Section Created Successfully"),
+			Data:           courses.SectionSchema{}.Assign(section, nil),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Update Course Section
+// @Description `This endpoint updates a section of one of the authenticated instructor's courses`
+// @Tags Instructor
+// @Param id path string true "Section ID"
+// @Param section body SectionCreateSchema true "Section object"
+// @Success 200 {object} courses.SectionResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /instructor/sections/{id} [put]
+// @Security BearerAuth
+func UpdateCourseSection(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		section, err := getSection(db, c)
+		if err != nil {
+			return err
+		}
+		data := SectionCreateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		section = instructorManager.UpdateSection(db, c.Context(), section, data)
+		response := courses.SectionResponseSchema{
+			ResponseSchema: base.ResponseMessage("Section Updated Successfully"),
+			Data:           courses.SectionSchema{}.Assign(section, nil),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete Course Section
+// @Description `This endpoint deletes a section of one of the authenticated instructor's courses. Its lessons are kept, outside any section`
+// @Tags Instructor
+// @Param id path string true "Section ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /instructor/sections/{id} [delete]
+// @Security BearerAuth
+func DeleteCourseSection(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		section, err := getSection(db, c)
+		if err != nil {
+			return err
+		}
+		instructorManager.DeleteSection(db, c.Context(), section)
+		return c.Status(200).JSON(base.ResponseMessage("Section Deleted Successfully"))
+	}
+}
+
+// @Summary Move Course Lesson
+// @Description `This endpoint moves a lesson to a position in another section of its course. Set section_id to null to take it out of its section`
+// @Tags Instructor
+// @Param slug path string true "Lesson Slug"
+// @Param data body LessonMoveSchema true "Move object"
+// @Success 200 {object} courses.LessonResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /instructor/lessons/{slug}/section [put]
+// @Security BearerAuth
+func MoveCourseLesson(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		lesson := courseManager.GetCourseLessonBySlug(db, ctx, c.Params("slug"), user, true)
+		if lesson == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor lesson not found"))
+		}
+		data := LessonMoveSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if data.SectionID != nil && !instructorManager.SectionInCourse(db, ctx, lesson.Edges.Course, *data.SectionID) {
+			return config.APIError(c, 422, config.ValidationErr("section_id", "Section not found in this lesson's course"))
+		}
+		movedLesson := instructorManager.MoveLesson(db, ctx, lesson, data.SectionID, data.Order)
+		movedLesson.Edges = lesson.Edges
+		response := courses.LessonResponseSchema{
+			ResponseSchema: base.ResponseMessage("Lesson Moved Successfully"),
+			Data:           courses.LessonDetailSchema{}.Assign(movedLesson),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Set Lesson Unlock Conditions
+// @Description `This endpoint replaces the conditions a lesson unlocks on for students. It unlocks once every one that is set is met:`
+// @Description `unlock_after_days after the student's enrollment, on the unlock_at date, and after the prerequisite lesson is completed`
+// @Description `Set prerequisite_min_score to require that score on each of the prerequisite's published quizzes instead. Send an empty object to remove every condition`
+// @Tags Instructor
+// @Param slug path string true "Lesson Slug"
+// @Param data body courses.LessonUnlockConditionsSchema true "Unlock conditions object"
+// @Success 200 {object} courses.LessonResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /instructor/lessons/{slug}/unlock-conditions [put]
+// @Security BearerAuth
+func SetLessonUnlockConditions(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		lesson := courseManager.GetCourseLessonBySlug(db, ctx, c.Params("slug"), user, true)
+		if lesson == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor lesson not found"))
+		}
+		data := courses.LessonUnlockConditionsSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		var prerequisite *ent.Lesson
+		if data.PrerequisiteSlug != nil {
+			prerequisite = courseManager.GetCourseLessonBySlug(db, ctx, *data.PrerequisiteSlug, user, false)
+			if prerequisite == nil || prerequisite.CourseID != lesson.CourseID {
+				return config.APIError(c, 422, config.ValidationErr("prerequisite_slug", "Lesson not found in this lesson's course"))
+			}
+			if instructorManager.CreatesPrerequisiteCycle(db, ctx, lesson, prerequisite) {
+				return config.APIError(c, 422, config.ValidationErr("prerequisite_slug", "A lesson can't require itself, directly or through its prerequisites"))
+			}
+		} else if data.PrerequisiteMinScore != nil {
+			return config.APIError(c, 422, config.ValidationErr("prerequisite_min_score", "Only allowed with a prerequisite lesson"))
+		}
+
+		lesson = instructorManager.SetUnlockConditions(db, ctx, lesson, prerequisite, data)
+		response := courses.LessonResponseSchema{
+			ResponseSchema: base.ResponseMessage("Lesson Unlock Conditions Set Successfully"),
+			Data:           courses.LessonDetailSchema{}.Assign(lesson),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
 // @Summary Retrieve Instructor Lesson Details
 // @Description This endpoint retrieves the details of a particular lesson belonging to an instructor
 // @Tags Instructor
@@ -507,3 +851,170 @@ func DeleteLessonQuiz(db *ent.Client) fiber.Handler {
 		return c.Status(200).JSON(base.ResponseMessage("Quiz deleted successfully"))
 	}
 }
+
+// @Summary Submit An Instructor Application
+// @Description `This endpoint allows a student to apply to become an instructor`
+// @Description `Provide a sample of your teaching, either as a link (sample_url) or an uploaded image/mp4 (sample_file)`
+// @Tags Instructor Applications
+// @Param application formData InstructorApplicationCreateSchema true "Application object"
+// @Param sample_file formData file false "Sample lesson"
+// @Success 201 {object} InstructorApplicationResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /instructor-applications [post]
+// @Security BearerAuth
+func SubmitInstructorApplication(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		userObj := base.RequestUser(c)
+		if userObj.Role != user.RoleStudent {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "Only students can apply to become instructors"))
+		}
+		if instructorManager.GetPendingApplication(db, ctx, userObj.ID) != nil {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "You already have a pending application"))
+		}
+		data := InstructorApplicationCreateSchema{}
+		if errCode, errData := config.ValidateFormRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		// Check and validate files
+		sampleFile, err := config.ValidateFile(c, "sample_file", false, true)
+		if err != nil {
+			return c.Status(422).JSON(err)
+		}
+		if sampleFile == nil && data.SampleUrl == nil {
+			return config.APIError(c, 422, config.ValidationErr("sample_url", "Provide a sample url or upload a sample file"))
+		}
+		var sampleFileUrl *string
+		if sampleFile != nil {
+			url := config.UploadFile(sampleFile, string(config.FF_INSTRUCTOR_SAMPLES))
+			sampleFileUrl = &url
+		}
+
+		application := instructorManager.CreateApplication(db, ctx, userObj, sampleFileUrl, data)
+		go config.SendEmail(userObj, config.ET_INSTRUCTOR_APP_RECV, nil)
+		response := InstructorApplicationResponseSchema{
+			ResponseSchema: base.ResponseMessage("Application Submitted Successfully"),
+			Data:           InstructorApplicationSchema{}.Assign(application),
+		}
+		return c.Status(201).JSON(response)
+	}
+}
+
+// @Summary Retrieve My Instructor Applications
+// @Description `This endpoint retrieves paginated instructor applications of the authenticated user, newest first`
+// @Tags Instructor Applications
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Success 200 {object} InstructorApplicationsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /instructor-applications [get]
+// @Security BearerAuth
+func GetMyInstructorApplications(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		applications := instructorManager.GetUserApplicationsPaginated(db, c, user)
+		response := InstructorApplicationsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Applications Fetched Successfully"),
+		}.Assign(applications)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Reply To A Review
+// @Description `This endpoint posts the instructor's public reply to a review on one of their courses`
+// @Description `A review has a single reply, so replying again replaces it`
+// @Tags Instructor
+// @Param id path string true "Review ID"
+// @Param data body courses.ReviewReplySchema true "Reply object"
+// @Success 200 {object} courses.ReviewResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /instructor/reviews/{id}/reply [put]
+// @Security BearerAuth
+func ReplyToReview(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		reviewID, _ := uuid.Parse(c.Params("id"))
+		review := instructorManager.GetCourseReview(db, ctx, user, reviewID)
+		if review == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
+		}
+		data := courses.ReviewReplySchema{}
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		isNewReply := review.Reply == nil
+		review = instructorManager.SetReviewReply(db, ctx, review, data.Content)
+		// Edits to a reply aren't notified
+		if isNewReply {
+			courseObj := review.Edges.Course
+			notifications.Notify(db, ctx, review.Edges.User, notification.TypeReviewReply, "The instructor replied to your review",
+				fmt.Sprintf("%s replied to your review of %s: %s", user.Name, courseObj.Title, data.Content), courseObj)
+		}
+		response := courses.ReviewResponseSchema{
+			ResponseSchema: base.ResponseMessage("Reply Saved Successfully"),
+			Data:           courses.ReviewResponseData{}.Assign(review),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete A Review Reply
+// @Description `This endpoint removes the instructor's reply to a review`
+// @Tags Instructor
+// @Param id path string true "Review ID"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /instructor/reviews/{id}/reply [delete]
+// @Security BearerAuth
+func DeleteReviewReply(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		reviewID, _ := uuid.Parse(c.Params("id"))
+		review := instructorManager.GetCourseReview(db, ctx, user, reviewID)
+		if review == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Review Not Found"))
+		}
+		if review.Reply == nil {
+			return config.APIError(c, 400, config.RequestErr(config.ERR_NOT_ALLOWED, "This review has no reply"))
+		}
+		instructorManager.DeleteReviewReply(db, ctx, review)
+		return c.Status(200).JSON(base.ResponseMessage("Reply Deleted Successfully"))
+	}
+}
+
+// @Summary Make An Announcement
+// @Description `This endpoint notifies every student enrolled in one of the instructor's courses. Students get it in-app or by email, depending on their preferences`
+// @Tags Instructor
+// @Param slug path string true "Course Slug"
+// @Param data body AnnouncementSchema true "Announcement object"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /instructor/courses/{slug}/announcements [post]
+// @Security BearerAuth
+func MakeAnnouncement(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		user := base.RequestUser(c)
+		courseObj := courseManager.GetCourseBySlug(db, ctx, c.Params("slug"), user, false)
+		if courseObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Instructor has no course with that slug"))
+		}
+		data := AnnouncementSchema{}
+		// Validate request
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		students := instructorManager.GetEnrolledStudents(db, ctx, courseObj)
+		notifications.NotifyMany(db, ctx, students, notification.TypeAnnouncement, fmt.Sprintf("%s: %s", courseObj.Title, data.Title), data.Message, courseObj)
+		return c.Status(200).JSON(base.ResponseMessage(fmt.Sprintf("Announcement Sent To %d Students", len(students))))
+	}
+}
@@ -1,7 +1,13 @@
 package instructors
 
 import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
 )
 
@@ -9,6 +15,7 @@ type CourseCreateSchema struct {
 	Title          string                `form:"title" validate:"required,max=50,min=10"`
 	Desc           string                `form:"desc" validate:"required,max=10000,min=10"`
 	CategorySlug   string                `form:"category_slug" validate:"required"`
+	TagSlugs       []string              `form:"tag_slugs"` // Repeat the field for each tag
 	Language       string                `form:"language" validate:"required" example:"English"`
 	Difficulty     course.Difficulty     `form:"difficulty" validate:"required,difficulty_type_validator"`
 	Duration       uint                  `form:"duration" validate:"required"`
@@ -27,6 +34,18 @@ type LessonCreateSchema struct {
 	Order         uint   `form:"order" validate:"required"`
 	IsFreePreview bool   `form:"is_free_preview"`
 	IsPublished   bool   `form:"is_published"`
+	SectionID     *string `form:"section_id" validate:"omitempty,uuid"` // Only read on creation. Use the move endpoint afterwards
+}
+
+type SectionCreateSchema struct {
+	Title       string `json:"title" validate:"required,max=200" example:"Getting Started"`
+	Description string `json:"description" validate:"max=1000" example:"Set up your environment and write your first program"`
+	Order       uint   `json:"order" validate:"required" example:"1"`
+}
+
+type LessonMoveSchema struct {
+	SectionID *uuid.UUID `json:"section_id"` // Null takes the lesson out of its section
+	Order     uint       `json:"order" validate:"required" example:"2"`
 }
 
 type QuizCreateSchema struct {
@@ -36,3 +55,66 @@ type QuizCreateSchema struct {
 	IsPublished bool                     `json:"is_published"`
 	Questions   []courses.QuestionSchema `json:"questions" validate:"required,min=1,dive"`
 }
+
+type InstructorApplicationCreateSchema struct {
+	Bio       string   `form:"bio" validate:"required,min=50,max=2000"`
+	Expertise []string `form:"expertise" validate:"required,min=1,max=10,dive,required,max=50" example:"Go,Databases"`
+	SampleUrl *string  `form:"sample_url" validate:"omitempty,url" example:"https://youtube.com/watch?v=sample"`
+}
+
+type InstructorApplicationSchema struct {
+	ID              uuid.UUID           `json:"id"`
+	User            base.UserDataSchema `json:"user"`
+	Bio             string              `json:"bio"`
+	Expertise       []string            `json:"expertise" example:"Go,Databases"`
+	SampleUrl       *string             `json:"sample_url" example:"https://youtube.com/watch?v=sample"`
+	SampleFileUrl   *string             `json:"sample_file_url" example:"https://file.url"`
+	Status          string              `json:"status" example:"pending"`
+	RejectionReason *string             `json:"rejection_reason"`
+	ReviewedAt      *time.Time          `json:"reviewed_at"`
+	CreatedAt       time.Time           `json:"created_at"`
+}
+
+func (i InstructorApplicationSchema) Assign(application *ent.InstructorApplication) InstructorApplicationSchema {
+	i.ID = application.ID
+	if application.Edges.User != nil {
+		i.User = i.User.Assign(application.Edges.User)
+	}
+	i.Bio = application.Bio
+	i.Expertise = application.Expertise
+	i.SampleUrl = application.SampleURL
+	i.SampleFileUrl = application.SampleFileURL
+	i.Status = string(application.Status)
+	i.RejectionReason = application.RejectionReason
+	i.ReviewedAt = application.ReviewedAt
+	i.CreatedAt = application.CreatedAt
+	return i
+}
+
+type InstructorApplicationResponseSchema struct {
+	base.ResponseSchema
+	Data InstructorApplicationSchema `json:"data"`
+}
+
+type InstructorApplicationsResponseSchema struct {
+	base.ResponseSchema
+	Data config.PaginationResponse[InstructorApplicationSchema] `json:"data"`
+}
+
+func (i InstructorApplicationsResponseSchema) Assign(applicationsData *config.PaginationResponse[*ent.InstructorApplication]) InstructorApplicationsResponseSchema {
+	items := make([]InstructorApplicationSchema, 0)
+	for _, application := range applicationsData.Items {
+		items = append(items, InstructorApplicationSchema{}.Assign(application))
+	}
+	i.Data.Items = items
+	i.Data.ItemsCount = applicationsData.ItemsCount
+	i.Data.Page = applicationsData.Page
+	i.Data.TotalPages = applicationsData.TotalPages
+	i.Data.Limit = applicationsData.Limit
+	return i
+}
+
+type AnnouncementSchema struct {
+	Title   string `json:"title" validate:"required,max=200" example:"Live session on Friday"`
+	Message string `json:"message" validate:"required,max=5000" example:"Join us at 5pm UTC to go through the project together."`
+}
@@ -0,0 +1,61 @@
+package notifications
+
+import (
+	"log"
+	"sync"
+
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+// Notification emails go through a queue served by a fixed number of workers, so notifying a large course
+// doesn't open an smtp connection per student at once.
+
+const (
+	emailWorkers   = 4
+	emailQueueSize = 256
+)
+
+type notificationEmail struct {
+	user    *ent.User
+	title   string
+	message string
+}
+
+var (
+	emailQueue       = make(chan notificationEmail, emailQueueSize)
+	emailWorkersOnce sync.Once
+)
+
+// queueEmails hands the emails to the workers in the background, so the caller never waits on a full queue
+func queueEmails(emails []notificationEmail) {
+	if len(emails) == 0 {
+		return
+	}
+	emailWorkersOnce.Do(func() {
+		for range emailWorkers {
+			go emailWorker()
+		}
+	})
+	go func() {
+		for _, email := range emails {
+			emailQueue <- email
+		}
+	}()
+}
+
+func emailWorker() {
+	for email := range emailQueue {
+		sendNotificationEmail(email)
+	}
+}
+
+// sendNotificationEmail keeps a failed email from stopping its worker
+func sendNotificationEmail(email notificationEmail) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Notification email to %s failed: %v\n", email.user.ID, r)
+		}
+	}()
+	config.SendNotificationEmail(email.user, email.title, email.message)
+}
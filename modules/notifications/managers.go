@@ -0,0 +1,90 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notificationpreference"
+)
+
+type NotificationManager struct{}
+
+func (n NotificationManager) GetNotificationsPaginated(db *ent.Client, fibCtx *fiber.Ctx, userObj *ent.User, unreadOnly bool) *config.PaginationResponse[*ent.Notification] {
+	query := db.Notification.Query().Where(notification.UserID(userObj.ID)).WithCourse()
+	if unreadOnly {
+		query = query.Where(notification.ReadAtIsNil())
+	}
+	query = query.Order(ent.Desc(notification.FieldCreatedAt))
+	return config.PaginateModel(fibCtx, query)
+}
+
+func (n NotificationManager) GetUnreadCount(db *ent.Client, ctx context.Context, userObj *ent.User) int {
+	return db.Notification.Query().Where(notification.UserID(userObj.ID), notification.ReadAtIsNil()).CountX(ctx)
+}
+
+func (n NotificationManager) GetNotification(db *ent.Client, ctx context.Context, userObj *ent.User, notificationID uuid.UUID) *ent.Notification {
+	notificationObj, _ := db.Notification.Query().
+		Where(notification.ID(notificationID), notification.UserID(userObj.ID)).
+		WithCourse().
+		Only(ctx)
+	return notificationObj
+}
+
+func (n NotificationManager) MarkRead(ctx context.Context, notificationObj *ent.Notification) *ent.Notification {
+	if notificationObj.ReadAt != nil {
+		return notificationObj
+	}
+	updatedNotification := notificationObj.Update().SetReadAt(time.Now()).SaveX(ctx)
+	updatedNotification.Edges = notificationObj.Edges
+	return updatedNotification
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns how many there were
+func (n NotificationManager) MarkAllRead(db *ent.Client, ctx context.Context, userObj *ent.User) int {
+	return db.Notification.Update().
+		Where(notification.UserID(userObj.ID), notification.ReadAtIsNil()).
+		SetReadAt(time.Now()).
+		SaveX(ctx)
+}
+
+// GetPreferences returns the user's preference for every type of notification, defaulting to every channel
+func (n NotificationManager) GetPreferences(db *ent.Client, ctx context.Context, userObj *ent.User) []NotificationPreferenceSchema {
+	saved := map[notificationpreference.Type]*ent.NotificationPreference{}
+	for _, preference := range db.NotificationPreference.Query().Where(notificationpreference.UserID(userObj.ID)).AllX(ctx) {
+		saved[preference.Type] = preference
+	}
+	preferences := []NotificationPreferenceSchema{}
+	for _, notificationType := range NotificationTypes {
+		preference := NotificationPreferenceSchema{Type: notificationpreference.Type(notificationType), InApp: true, Email: true}
+		if savedPreference, ok := saved[preference.Type]; ok {
+			preference.InApp = savedPreference.InApp
+			preference.Email = savedPreference.Email
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences
+}
+
+func (n NotificationManager) UpdatePreferences(db *ent.Client, ctx context.Context, userObj *ent.User, preferences []NotificationPreferenceSchema) []NotificationPreferenceSchema {
+	for _, preference := range preferences {
+		updated := db.NotificationPreference.Update().
+			Where(notificationpreference.UserID(userObj.ID), notificationpreference.TypeEQ(preference.Type)).
+			SetInApp(preference.InApp).
+			SetEmail(preference.Email).
+			SaveX(ctx)
+		if updated == 0 {
+			db.NotificationPreference.Create().
+				SetUserID(userObj.ID).
+				SetType(preference.Type).
+				SetInApp(preference.InApp).
+				SetEmail(preference.Email).
+				ExecX(ctx)
+		}
+	}
+	return n.GetPreferences(db, ctx, userObj)
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notificationpreference"
+)
+
+// Notify delivers a notification to the user on every channel they haven't turned off for its type.
+// The course it's about is optional
+func Notify(db *ent.Client, ctx context.Context, userObj *ent.User, notificationType notification.Type, title string, message string, courseObj *ent.Course) {
+	NotifyMany(db, ctx, []*ent.User{userObj}, notificationType, title, message, courseObj)
+}
+
+// NotifyMany delivers the same notification to several users (e.g the students of a course).
+// In-app notifications are saved and pushed to the users' open streams. Emails are queued and sent in the background
+func NotifyMany(db *ent.Client, ctx context.Context, users []*ent.User, notificationType notification.Type, title string, message string, courseObj *ent.Course) {
+	if len(users) == 0 {
+		return
+	}
+	userIDs := make([]uuid.UUID, 0, len(users))
+	for _, userObj := range users {
+		userIDs = append(userIDs, userObj.ID)
+	}
+	preferences := map[uuid.UUID]*ent.NotificationPreference{}
+	for _, preference := range db.NotificationPreference.Query().
+		Where(notificationpreference.UserIDIn(userIDs...), notificationpreference.TypeEQ(notificationpreference.Type(notificationType))).
+		AllX(ctx) {
+		preferences[preference.UserID] = preference
+	}
+
+	builders := []*ent.NotificationCreate{}
+	emails := []notificationEmail{}
+	for _, userObj := range users {
+		preference := preferences[userObj.ID]
+		if preference == nil || preference.InApp {
+			builder := db.Notification.Create().
+				SetUserID(userObj.ID).
+				SetType(notificationType).
+				SetTitle(title).
+				SetMessage(message)
+			if courseObj != nil {
+				builder = builder.SetCourseID(courseObj.ID)
+			}
+			builders = append(builders, builder)
+		}
+		if preference == nil || preference.Email {
+			emails = append(emails, notificationEmail{user: userObj, title: title, message: message})
+		}
+	}
+	queueEmails(emails)
+	if len(builders) == 0 {
+		return
+	}
+	for _, notificationObj := range db.Notification.CreateBulk(builders...).SaveX(ctx) {
+		notificationObj.Edges.Course = courseObj
+		payload, _ := json.Marshal(NotificationSchema{}.Assign(notificationObj))
+		notificationBroker.publish(notificationObj.UserID, payload)
+	}
+}
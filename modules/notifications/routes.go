@@ -0,0 +1,165 @@
+package notifications
+
+import (
+	"bufio"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+)
+
+var notificationManager = NotificationManager{}
+
+// @Summary Retrieve Notifications
+// @Description `This endpoint retrieves the user's paginated notifications, newest first, along with how many are unread`
+// @Tags Notifications
+// @Param page query int false "Current Page" default(1)
+// @Param limit query int false "Page Limit" default(100)
+// @Param unread query bool false "Only Unread Notifications"
+// @Success 200 {object} NotificationsResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /notifications [get]
+// @Security BearerAuth
+func GetNotifications(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		notifications := notificationManager.GetNotificationsPaginated(db, c, user, c.QueryBool("unread"))
+		response := NotificationsResponseSchema{
+			ResponseSchema: base.ResponseMessage("Notifications Fetched Successfully"),
+			UnreadCount:    notificationManager.GetUnreadCount(db, c.Context(), user),
+		}.Assign(notifications)
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Stream Notifications
+// @Description `This endpoint opens a Server-Sent Events stream of the user's new in-app notifications. Send the access token in the Authorization header or the token query param`
+// @Description `Each notification is sent as a notification event whose data is a NotificationSchema. Comments are sent on idle streams to keep them open`
+// @Tags Notifications
+// @Produce text/event-stream
+// @Param token query string false "Access token, for clients that can't set headers"
+// @Success 200 {object} NotificationSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /notifications/stream [get]
+// @Security BearerAuth
+func StreamNotifications(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			stream := notificationBroker.subscribe(user.ID)
+			defer notificationBroker.unsubscribe(user.ID, stream)
+			ticker := time.NewTicker(keepAlivePeriod)
+			defer ticker.Stop()
+
+			fmt.Fprint(w, ": connected\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+			// A failed flush means the client went away
+			for {
+				select {
+				case payload := <-stream:
+					fmt.Fprintf(w, "event: notification\ndata: %s\n\n", payload)
+				case <-ticker.C:
+					fmt.Fprint(w, ": keep-alive\n\n")
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		})
+		return nil
+	}
+}
+
+// @Summary Mark A Notification As Read
+// @Description `This endpoint marks one of the user's notifications as read`
+// @Tags Notifications
+// @Param id path string true "Notification ID"
+// @Success 200 {object} NotificationResponseSchema
+// @Failure 400 {object} base.InvalidErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /notifications/{id}/read [post]
+// @Security BearerAuth
+func MarkNotificationRead(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.Context()
+		notificationID, errData := config.ParseUUID(c.Params("id"))
+		if errData != nil {
+			return config.APIError(c, 400, *errData)
+		}
+		notificationObj := notificationManager.GetNotification(db, ctx, base.RequestUser(c), *notificationID)
+		if notificationObj == nil {
+			return config.APIError(c, 404, config.NotFoundErr("Notification Not Found"))
+		}
+		notificationObj = notificationManager.MarkRead(ctx, notificationObj)
+		response := NotificationResponseSchema{
+			ResponseSchema: base.ResponseMessage("Notification Marked As Read"),
+			Data:           NotificationSchema{}.Assign(notificationObj),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Mark All Notifications As Read
+// @Description `This endpoint marks every unread notification of the user as read`
+// @Tags Notifications
+// @Success 200 {object} base.ResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /notifications/read-all [post]
+// @Security BearerAuth
+func MarkAllNotificationsRead(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		count := notificationManager.MarkAllRead(db, c.Context(), base.RequestUser(c))
+		return c.Status(200).JSON(base.ResponseMessage(fmt.Sprintf("%d Notifications Marked As Read", count)))
+	}
+}
+
+// @Summary Retrieve Notification Preferences
+// @Description `This endpoint retrieves whether each type of notification is delivered in-app and by email. Every channel is on until turned off`
+// @Tags Notifications
+// @Success 200 {object} NotificationPreferencesResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /notifications/preferences [get]
+// @Security BearerAuth
+func GetNotificationPreferences(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		response := NotificationPreferencesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Notification Preferences Fetched Successfully"),
+			Data:           notificationManager.GetPreferences(db, c.Context(), base.RequestUser(c)),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Update Notification Preferences
+// @Description `This endpoint turns types of notifications on or off per channel. Types left out keep their current preference`
+// @Tags Notifications
+// @Param preferences body NotificationPreferencesUpdateSchema true "Preferences object"
+// @Success 200 {object} NotificationPreferencesResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 422 {object} base.ValidationErrorExample
+// @Router /notifications/preferences [put]
+// @Security BearerAuth
+func UpdateNotificationPreferences(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		data := NotificationPreferencesUpdateSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		response := NotificationPreferencesResponseSchema{
+			ResponseSchema: base.ResponseMessage("Notification Preferences Updated Successfully"),
+			Data:           notificationManager.UpdatePreferences(db, c.Context(), base.RequestUser(c), data.Preferences),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
@@ -0,0 +1,85 @@
+package notifications
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kayprogrammer/ednet-fiber-api/config"
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notificationpreference"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
+)
+
+// Every type of notification, in the order preferences are listed
+var NotificationTypes = []notification.Type{
+	notification.TypePaymentSucceeded, notification.TypePaymentFailed, notification.TypePaymentCanceled,
+	notification.TypeCertificateIssued, notification.TypeReviewReply, notification.TypeQuizGraded, notification.TypeAnnouncement,
+}
+
+type NotificationCourseSchema struct {
+	Title string `json:"title" example:"Introduction To Go"`
+	Slug  string `json:"slug" example:"introduction-to-go"`
+}
+
+type NotificationSchema struct {
+	ID        uuid.UUID                 `json:"id"`
+	Type      notification.Type         `json:"type" example:"quiz_graded"`
+	Title     string                    `json:"title" example:"Quiz graded"`
+	Message   string                    `json:"message" example:"You scored 8 on Loops."`
+	Course    *NotificationCourseSchema `json:"course"`
+	ReadAt    *time.Time                `json:"read_at"`
+	CreatedAt time.Time                 `json:"created_at"`
+}
+
+func (n NotificationSchema) Assign(notificationObj *ent.Notification) NotificationSchema {
+	n.ID = notificationObj.ID
+	n.Type = notificationObj.Type
+	n.Title = notificationObj.Title
+	n.Message = notificationObj.Message
+	if courseObj := notificationObj.Edges.Course; courseObj != nil {
+		n.Course = &NotificationCourseSchema{Title: courseObj.Title, Slug: courseObj.Slug}
+	}
+	n.ReadAt = notificationObj.ReadAt
+	n.CreatedAt = notificationObj.CreatedAt
+	return n
+}
+
+type NotificationResponseSchema struct {
+	base.ResponseSchema
+	Data NotificationSchema `json:"data"`
+}
+
+type NotificationsResponseSchema struct {
+	base.ResponseSchema
+	UnreadCount int                                           `json:"unread_count" example:"3"`
+	Data        config.PaginationResponse[NotificationSchema] `json:"data"`
+}
+
+func (n NotificationsResponseSchema) Assign(notificationsData *config.PaginationResponse[*ent.Notification]) NotificationsResponseSchema {
+	items := make([]NotificationSchema, 0)
+	for _, notificationObj := range notificationsData.Items {
+		items = append(items, NotificationSchema{}.Assign(notificationObj))
+	}
+	n.Data.Items = items
+	n.Data.ItemsCount = notificationsData.ItemsCount
+	n.Data.Page = notificationsData.Page
+	n.Data.TotalPages = notificationsData.TotalPages
+	n.Data.Limit = notificationsData.Limit
+	return n
+}
+
+type NotificationPreferenceSchema struct {
+	Type  notificationpreference.Type `json:"type" validate:"required,notification_type_validator" example:"quiz_graded"`
+	InApp bool                        `json:"in_app" example:"true"`
+	Email bool                        `json:"email" example:"false"`
+}
+
+type NotificationPreferencesUpdateSchema struct {
+	Preferences []NotificationPreferenceSchema `json:"preferences" validate:"required,min=1,dive"`
+}
+
+type NotificationPreferencesResponseSchema struct {
+	base.ResponseSchema
+	Data []NotificationPreferenceSchema `json:"data"`
+}
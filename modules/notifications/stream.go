@@ -0,0 +1,56 @@
+package notifications
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The broker fans notifications out to the Server-Sent Events streams users have open.
+// Streams live in memory, so a notification only reaches streams connected to the instance that created it.
+// Clients should refetch the list when they reconnect to catch up on anything missed.
+
+const (
+	streamBuffer    = 16               // Notifications queued for a stream before new ones are dropped
+	keepAlivePeriod = 25 * time.Second // Comments sent on idle streams so proxies don't close them
+)
+
+type broker struct {
+	mu      sync.RWMutex
+	streams map[uuid.UUID]map[chan []byte]struct{}
+}
+
+var notificationBroker = &broker{streams: map[uuid.UUID]map[chan []byte]struct{}{}}
+
+func (b *broker) subscribe(userID uuid.UUID) chan []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stream := make(chan []byte, streamBuffer)
+	if b.streams[userID] == nil {
+		b.streams[userID] = map[chan []byte]struct{}{}
+	}
+	b.streams[userID][stream] = struct{}{}
+	return stream
+}
+
+func (b *broker) unsubscribe(userID uuid.UUID, stream chan []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.streams[userID], stream)
+	if len(b.streams[userID]) == 0 {
+		delete(b.streams, userID)
+	}
+}
+
+// publish never blocks: a stream that can't keep up misses the notification
+func (b *broker) publish(userID uuid.UUID, payload []byte) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for stream := range b.streams[userID] {
+		select {
+		case stream <- payload:
+		default:
+		}
+	}
+}
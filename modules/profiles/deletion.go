@@ -0,0 +1,43 @@
+package profiles
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kayprogrammer/ednet-fiber-api/ent"
+)
+
+const (
+	// How long a user has to change their mind after requesting the deletion of their account
+	AccountDeletionGracePeriod = 14 * 24 * time.Hour
+	accountPurgeInterval       = time.Hour
+)
+
+// PurgeDeletedAccounts anonymises every account whose deletion grace period is over
+func PurgeDeletedAccounts(db *ent.Client, ctx context.Context) {
+	// This runs in the background, so a failure must not take the server down with it
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Account purge failed: %v\n", r)
+		}
+	}()
+	for _, userObj := range profileManager.GetUsersDueForDeletion(db, ctx) {
+		profileManager.Anonymise(db, ctx, userObj)
+		log.Printf("Account %s deleted (anonymised)\n", userObj.ID)
+	}
+}
+
+// StartAccountPurger runs PurgeDeletedAccounts periodically until the context is cancelled
+func StartAccountPurger(db *ent.Client, ctx context.Context) {
+	ticker := time.NewTicker(accountPurgeInterval)
+	defer ticker.Stop()
+	for {
+		PurgeDeletedAccounts(db, ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,24 +4,48 @@ import (
 	"context"
 	"math"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/accountlockout"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/answer"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/answervote"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/authevent"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/chatmessage"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/course"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/directmessage"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/enrollment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/instructorapplication"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/lesson"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/lessonanswer"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/lessonprogress"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/lessonquestion"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notification"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/notificationpreference"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/payment"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/personalaccesstoken"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/predicate"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/questionvote"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/quizresult"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/review"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewreport"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/reviewvote"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/token"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/usedrefreshtoken"
 	"github.com/kayprogrammer/ednet-fiber-api/ent/user"
+	"github.com/kayprogrammer/ednet-fiber-api/ent/useridentity"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
 )
 
 var courseManager = courses.CourseManager{}
 
+// What's left of the discussion posts of deleted accounts
+const deletedPostContent = "[deleted]"
+
 // ----------------------------------
 // PROFILES MANAGEMENT
 // --------------------------------
@@ -70,6 +94,34 @@ func (p ProfileManager) GetAllPaginatedEnrolledCourses(db *ent.Client, fibCtx *f
 	return courses
 }
 
+func (p ProfileManager) RequestEmailChange(db *ent.Client, ctx context.Context, userObj *ent.User, email string) *ent.User {
+	otp, otpExp := userManager.GetOtp()
+	return userObj.Update().
+		SetPendingEmail(email).
+		SetPendingEmailOtp(otp).
+		SetPendingEmailOtpExpiry(otpExp).
+		SetPendingEmailOtpAttempts(0).
+		SaveX(ctx)
+}
+
+// ConfirmEmailChange swaps in the pending email. It returns nil if the address was taken in the meantime.
+func (p ProfileManager) ConfirmEmailChange(db *ent.Client, ctx context.Context, userObj *ent.User) *ent.User {
+	updatedUser, err := userObj.Update().
+		SetEmail(*userObj.PendingEmail).
+		ClearPendingEmail().
+		ClearPendingEmailOtp().
+		ClearPendingEmailOtpExpiry().
+		SetPendingEmailOtpAttempts(0).
+		Save(ctx)
+	if ent.IsConstraintError(err) {
+		userObj.Update().ClearPendingEmail().ClearPendingEmailOtp().ClearPendingEmailOtpExpiry().ExecX(ctx)
+		return nil
+	} else if err != nil {
+		panic(err)
+	}
+	return updatedUser
+}
+
 // ----------------------------------
 // LESSON PROGRESS MANAGEMENT
 // --------------------------------
@@ -160,4 +212,172 @@ func (p ProfileManager) GetLeaderboard(db *ent.Client, ctx context.Context) []*L
 		}
 	}
 	return leaderboard
-}
\ No newline at end of file
+}
+
+// ----------------------------------
+// ACCOUNT EXPORT & DELETION
+// --------------------------------
+
+// AccountData holds everything a user can export about themselves
+type AccountData struct {
+	User           *ent.User
+	Enrollments    []*ent.Enrollment
+	LessonProgress []*ent.LessonProgress
+	QuizResults    []*ent.QuizResult
+	Reviews        []*ent.Review
+	Payments       []*ent.Payment
+}
+
+func (p ProfileManager) GetAccountData(db *ent.Client, ctx context.Context, userObj *ent.User) *AccountData {
+	return &AccountData{
+		User: userObj,
+		Enrollments: db.Enrollment.Query().
+			Where(enrollment.UserID(userObj.ID)).
+			WithCourse().
+			Order(ent.Asc(enrollment.FieldCreatedAt)).
+			AllX(ctx),
+		LessonProgress: db.LessonProgress.Query().
+			Where(lessonprogress.UserID(userObj.ID)).
+			WithLesson().
+			Order(ent.Asc(lessonprogress.FieldCreatedAt)).
+			AllX(ctx),
+		QuizResults: db.QuizResult.Query().
+			Where(quizresult.UserID(userObj.ID)).
+			WithQuiz().
+			WithAnswers(func(q *ent.AnswerQuery) {
+				q.WithQuestion().WithSelectedOption()
+			}).
+			Order(ent.Asc(quizresult.FieldCreatedAt)).
+			AllX(ctx),
+		Reviews: db.Review.Query().
+			Where(review.UserID(userObj.ID)).
+			WithCourse().
+			Order(ent.Asc(review.FieldCreatedAt)).
+			AllX(ctx),
+		Payments: db.Payment.Query().
+			Where(payment.UserID(userObj.ID)).
+			WithCourse().
+			Order(ent.Asc(payment.FieldCreatedAt)).
+			AllX(ctx),
+	}
+}
+
+func (p ProfileManager) OwnsCourses(db *ent.Client, ctx context.Context, userObj *ent.User) bool {
+	return db.Course.Query().Where(course.InstructorID(userObj.ID)).ExistX(ctx)
+}
+
+func (p ProfileManager) ScheduleDeletion(db *ent.Client, ctx context.Context, userObj *ent.User) *ent.User {
+	return userObj.Update().
+		SetDeletionScheduledAt(time.Now().Add(AccountDeletionGracePeriod)).
+		SaveX(ctx)
+}
+
+func (p ProfileManager) CancelDeletion(db *ent.Client, ctx context.Context, userObj *ent.User) *ent.User {
+	return userObj.Update().ClearDeletionScheduledAt().SaveX(ctx)
+}
+
+func (p ProfileManager) GetUsersDueForDeletion(db *ent.Client, ctx context.Context) []*ent.User {
+	return db.User.Query().
+		Where(user.DeletionScheduledAtLTE(time.Now()), user.DeletedAtIsNil()).
+		AllX(ctx)
+}
+
+// Anonymise deletes the user's personal data and turns the account into an anonymous placeholder.
+// The row itself is kept so that reviews and payments (which can't lose their user) stay valid without pointing at anyone.
+// Everything happens in one transaction, so a failure leaves the account as it was for the next purge to retry.
+func (p ProfileManager) Anonymise(db *ent.Client, ctx context.Context, userObj *ent.User) *ent.User {
+	tx, err := db.Tx(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	client := tx.Client()
+	userID := userObj.ID
+
+	// Learning data
+	client.Answer.Delete().Where(answer.HasResultWith(quizresult.UserID(userID))).ExecX(ctx)
+	client.QuizResult.Delete().Where(quizresult.UserID(userID)).ExecX(ctx)
+	client.LessonProgress.Delete().Where(lessonprogress.UserID(userID)).ExecX(ctx)
+	client.Enrollment.Update().Where(enrollment.UserID(userID)).ClearCert().ExecX(ctx)
+
+	// Messages. Attachments are only removed from storage once the transaction is committed
+	attachmentUrls := []string{}
+	for _, message := range client.DirectMessage.Query().Where(directmessage.SenderID(userID), directmessage.AttachmentURLNotNil()).AllX(ctx) {
+		attachmentUrls = append(attachmentUrls, *message.AttachmentURL)
+	}
+	client.DirectMessage.Delete().Where(directmessage.SenderID(userID)).ExecX(ctx)
+	client.ChatMessage.Delete().Where(chatmessage.SenderID(userID)).ExecX(ctx)
+	client.Notification.Delete().Where(notification.UserID(userID)).ExecX(ctx)
+	client.NotificationPreference.Delete().Where(notificationpreference.UserID(userID)).ExecX(ctx)
+
+	// Discussion posts keep their place in threads others took part in, but lose their content.
+	// Votes are removed along with the upvotes they add
+	client.LessonQuestion.Update().Where(lessonquestion.UserID(userID)).SetTitle(deletedPostContent).SetBody(deletedPostContent).ExecX(ctx)
+	client.LessonAnswer.Update().Where(lessonanswer.UserID(userID)).SetBody(deletedPostContent).ExecX(ctx)
+	votedQuestionIDs := client.QuestionVote.Query().Where(questionvote.UserID(userID)).QueryQuestion().IDsX(ctx)
+	client.LessonQuestion.Update().Where(lessonquestion.IDIn(votedQuestionIDs...)).AddUpvotesCount(-1).ExecX(ctx)
+	client.QuestionVote.Delete().Where(questionvote.UserID(userID)).ExecX(ctx)
+	votedAnswerIDs := client.AnswerVote.Query().Where(answervote.UserID(userID)).QueryAnswer().IDsX(ctx)
+	client.LessonAnswer.Update().Where(lessonanswer.IDIn(votedAnswerIDs...)).AddUpvotesCount(-1).ExecX(ctx)
+	client.AnswerVote.Delete().Where(answervote.UserID(userID)).ExecX(ctx)
+
+	// Review votes and reports, along with the counts they add to
+	votedReviewIDs := client.ReviewVote.Query().Where(reviewvote.UserID(userID)).QueryReview().IDsX(ctx)
+	client.Review.Update().Where(review.IDIn(votedReviewIDs...)).AddHelpfulCount(-1).ExecX(ctx)
+	client.ReviewVote.Delete().Where(reviewvote.UserID(userID)).ExecX(ctx)
+	reportedReviewIDs := client.ReviewReport.Query().Where(reviewreport.UserID(userID), reviewreport.ResolvedAtIsNil()).QueryReview().IDsX(ctx)
+	client.Review.Update().Where(review.IDIn(reportedReviewIDs...)).AddReportsCount(-1).ExecX(ctx)
+	client.ReviewReport.Delete().Where(reviewreport.UserID(userID)).ExecX(ctx)
+
+	// Account data
+	client.Token.Delete().Where(token.UserID(userID)).ExecX(ctx)
+	client.UsedRefreshToken.Delete().Where(usedrefreshtoken.UserID(userID)).ExecX(ctx)
+	client.PersonalAccessToken.Delete().Where(personalaccesstoken.UserID(userID)).ExecX(ctx)
+	client.UserIdentity.Delete().Where(useridentity.UserID(userID)).ExecX(ctx)
+	client.AccountLockout.Delete().Where(accountlockout.UserID(userID)).ExecX(ctx)
+	client.AuthEvent.Delete().Where(authevent.UserID(userID)).ExecX(ctx)
+	client.InstructorApplication.Delete().Where(instructorapplication.UserID(userID)).ExecX(ctx)
+
+	placeholder := "deleted-" + strings.ReplaceAll(userID.String(), "-", "")
+	anonymisedUser := client.User.UpdateOneID(userID).
+		SetName("Deleted User").
+		SetUsername(placeholder).
+		SetEmail(placeholder + "@deleted.invalid").
+		SetPassword(config.HashPassword(config.GenerateSecureToken(32))).
+		SetIsActive(false).
+		SetIsVerified(false).
+		SetSocialLogin(false).
+		SetRole(user.RoleStudent).
+		ClearRoles().
+		ClearBio().
+		ClearDob().
+		ClearAvatar().
+		ClearOtp().
+		ClearOtpExpiry().
+		ClearTotpSecret().
+		SetTwoFactorEnabled(false).
+		ClearRecoveryCodes().
+		ClearUnlockToken().
+		ClearMagicLinkToken().
+		ClearMagicLinkExpiry().
+		ClearLockedUntil().
+		ClearPendingEmail().
+		ClearPendingEmailOtp().
+		ClearPendingEmailOtpExpiry().
+		ClearDeletionScheduledAt().
+		SetDeletedAt(time.Now()).
+		SaveX(ctx)
+	if err := tx.Commit(); err != nil {
+		panic(err)
+	}
+
+	for _, attachmentUrl := range attachmentUrls {
+		config.DeleteUploadedFile(attachmentUrl)
+	}
+	return anonymisedUser.Unwrap()
+}
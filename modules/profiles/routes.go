@@ -7,12 +7,28 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/kayprogrammer/ednet-fiber-api/config"
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	"github.com/kayprogrammer/ednet-fiber-api/modules/accounts"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/base"
 	"github.com/kayprogrammer/ednet-fiber-api/modules/courses"
 )
 
 var profileManager = ProfileManager{}
 
+// checkPassword confirms sensitive actions with the user's password.
+// Social login accounts never chose a password, so they are let through.
+func checkPassword(c *fiber.Ctx, user *ent.User, password string) error {
+	if user.SocialLogin {
+		return nil
+	}
+	if password == "" {
+		return config.APIError(c, 422, config.ValidationErr("password", "This field is required."))
+	}
+	if !config.CheckPasswordHash(password, user.Password) {
+		return config.APIError(c, 401, config.RequestErr(config.ERR_INVALID_CREDENTIALS, "Incorrect password"))
+	}
+	return nil
+}
+
 // @Summary Get Your Profile
 // @Description `This endpoint allows a user to view his/her profile`
 // @Tags Profiles
@@ -76,6 +92,85 @@ func UpdateProfile(db *ent.Client) fiber.Handler {
 	}
 }
 
+// @Summary Change Your Email
+// @Description `This endpoint allows a user to change his/her email address`
+// @Description `An otp is sent to the new address and a notice to the current one. The email only changes once the otp is verified.`
+// @Description `Password is required unless the account was created via social login.`
+// @Tags Profiles
+// @Param data body EmailChangeSchema true "Email change object"
+// @Success 200 {object} base.ResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /profiles/email [post]
+// @Security BearerAuth
+func RequestEmailChange(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		ctx := c.Context()
+		data := EmailChangeSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if errData := checkPassword(c, user, data.Password); errData != nil {
+			return errData
+		}
+		data.Email = config.NormalizeEmail(data.Email)
+		if data.Email == user.Email {
+			return config.APIError(c, 422, config.ValidationErr("email", "This is already your email"))
+		}
+		if userManager.GetByEmail(db, ctx, data.Email) != nil {
+			return config.APIError(c, 422, config.ValidationErr("email", "Email already registered!"))
+		}
+
+		user = profileManager.RequestEmailChange(db, ctx, user, data.Email)
+
+		// The otp goes to the new address to prove it belongs to the user
+		newAddressUser := *user
+		newAddressUser.Email = data.Email
+		go config.SendEmail(&newAddressUser, config.ET_EMAIL_CHANGE, user.PendingEmailOtp)
+		go config.SendMessageEmail(user, config.ET_EMAIL_CHANGE_NOTICE, data.Email)
+		return c.Status(200).JSON(base.ResponseMessage("Otp sent to the new email"))
+	}
+}
+
+// @Summary Verify Your New Email
+// @Description `This endpoint confirms a pending email change with the otp sent to the new address`
+// @Description `The otp is invalidated after 5 wrong attempts`
+// @Tags Profiles
+// @Param data body EmailChangeVerifySchema true "Email change otp"
+// @Success 200 {object} ProfileResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /profiles/email/verify [post]
+// @Security BearerAuth
+func VerifyEmailChange(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		ctx := c.Context()
+		data := EmailChangeVerifySchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+		if user.PendingEmail == nil {
+			return config.APIError(c, 404, config.NotFoundErr("No pending email change"))
+		}
+		if errData := accounts.CheckEmailChangeOtp(db, c, user, data.Otp); errData != nil {
+			return errData
+		}
+
+		updatedUser := profileManager.ConfirmEmailChange(db, ctx, user)
+		if updatedUser == nil {
+			return config.APIError(c, 422, config.ValidationErr("email", "Email already registered!"))
+		}
+		response := ProfileResponseSchema{
+			ResponseSchema: base.ResponseMessage("Email changed successfully"),
+			Data:           ProfileSchema{}.Assign(updatedUser),
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
 // @Summary Get Your Enrolled Courses
 // @Description `This endpoint allows a user to view his/her enrolled courses`
 // @Tags Profiles
@@ -101,7 +196,7 @@ func GetEnrolledCourses(db *ent.Client) fiber.Handler {
 }
 
 // @Summary Create/Update Lesson Progress
-// @Description `This endpoint allows a user to create or update a lesson progress`
+// @Description `This endpoint allows a user to create or update a lesson progress. The lesson must be unlocked for the user`
 // @Tags Profiles
 // @Param slug path string true "Lesson Slug"
 // @Param lesson_progress body LessonProgressInputSchema true "Lesson Progress object"
@@ -109,6 +204,7 @@ func GetEnrolledCourses(db *ent.Client) fiber.Handler {
 // @Failure 422 {object} base.ValidationErrorExample
 // @Failure 404 {object} base.NotFoundErrorExample
 // @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
 // @Router /profiles/lessons/{slug}/progress [post]
 // @Security BearerAuth
 func CreateOrUpdateLessonProgress(db *ent.Client) fiber.Handler {
@@ -129,6 +225,9 @@ func CreateOrUpdateLessonProgress(db *ent.Client) fiber.Handler {
 		if enrollment == nil {
 			return config.APIError(c, 403, config.RequestErr(config.ERR_NOT_ALLOWED, "You are not enrolled in this lesson"))
 		}
+		if gate := courseManager.LessonGates(db, ctx, user, lesson.Edges.Course, []*ent.Lesson{lesson})[lesson.ID]; gate.IsLocked {
+			return config.APIError(c, 403, config.ForbiddenErr(gate.Reason))
+		}
 
 		lessonProgress, message := profileManager.CreateOrUpdateLessonProgress(db, ctx, user, lesson, data.IsCompleted)
 		response := LessonProgressResponseSchema{
@@ -221,4 +320,85 @@ func GetLeaderboard(db *ent.Client) fiber.Handler {
 			Data:           leaderboard,
 		})
 	}
-}
\ No newline at end of file
+}
+
+// @Summary Export Your Data
+// @Description `This endpoint allows a user to download a copy of his/her data (profile, enrollments, lesson progress, quiz results and answers, reviews and payments) as a json file`
+// @Tags Profiles
+// @Success 200 {object} AccountExportResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Router /profiles/export [get]
+// @Security BearerAuth
+func ExportAccountData(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		accountData := profileManager.GetAccountData(db, c.Context(), user)
+		response := AccountExportResponseSchema{
+			ResponseSchema: base.ResponseMessage("Account data exported successfully"),
+			Data:           AccountExportSchema{}.Assign(accountData),
+		}
+		c.Attachment(fmt.Sprintf("ednet-%s-export.json", user.Username))
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Delete Your Account
+// @Description `This endpoint allows a user to request the deletion of his/her account`
+// @Description `The account is only deleted after a grace period of 14 days, during which the request can be cancelled.`
+// @Description `Reviews and payments are kept but no longer linked to any personal data, and discussion posts stay in their threads without their content. Everything else is deleted, including messages and their attachments.`
+// @Description `Password is required unless the account was created via social login. Instructors must delete their courses first.`
+// @Tags Profiles
+// @Param data body AccountDeletionSchema true "Account deletion object"
+// @Success 200 {object} AccountDeletionResponseSchema
+// @Failure 422 {object} base.ValidationErrorExample
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 403 {object} base.ForbiddenErrorExample
+// @Router /profiles/deletion [post]
+// @Security BearerAuth
+func RequestAccountDeletion(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		ctx := c.Context()
+		data := AccountDeletionSchema{}
+		if errCode, errData := config.ValidateRequest(c, &data); errData != nil {
+			return config.APIError(c, *errCode, *errData)
+		}
+
+		if errData := checkPassword(c, user, data.Password); errData != nil {
+			return errData
+		}
+		if user.DeletionScheduledAt != nil {
+			return config.APIError(c, 403, config.RequestErr(config.ERR_NOT_ALLOWED, "Account deletion has already been requested"))
+		}
+		if profileManager.OwnsCourses(db, ctx, user) {
+			return config.APIError(c, 403, config.RequestErr(config.ERR_NOT_ALLOWED, "Delete your courses before deleting your account"))
+		}
+
+		user = profileManager.ScheduleDeletion(db, ctx, user)
+		go config.SendMessageEmail(user, config.ET_ACCOUNT_DELETION, user.DeletionScheduledAt.Format("January 2, 2006 15:04 MST"))
+		response := AccountDeletionResponseSchema{
+			ResponseSchema: base.ResponseMessage("Account scheduled for deletion"),
+			Data:           AccountDeletionData{ScheduledAt: *user.DeletionScheduledAt},
+		}
+		return c.Status(200).JSON(response)
+	}
+}
+
+// @Summary Cancel Account Deletion
+// @Description `This endpoint allows a user to cancel a pending deletion of his/her account`
+// @Tags Profiles
+// @Success 200 {object} base.ResponseSchema
+// @Failure 401 {object} base.UnauthorizedErrorExample
+// @Failure 404 {object} base.NotFoundErrorExample
+// @Router /profiles/deletion [delete]
+// @Security BearerAuth
+func CancelAccountDeletion(db *ent.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := base.RequestUser(c)
+		if user.DeletionScheduledAt == nil {
+			return config.APIError(c, 404, config.NotFoundErr("No pending account deletion"))
+		}
+		profileManager.CancelDeletion(db, c.Context(), user)
+		return c.Status(200).JSON(base.ResponseMessage("Account deletion cancelled"))
+	}
+}
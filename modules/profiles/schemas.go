@@ -17,6 +17,9 @@ type ProfileSchema struct {
 	Dob      *time.Time `json:"dob" example:"2000-09-12"`
 	Avatar   *string    `json:"avatar" example:"https://ednet-images.com/users/john-doe"`
 	Role     user.Role  `json:"role" example:"student"`
+
+	PendingEmail        *string    `json:"pending_email" example:"john@newmail.com"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
 }
 
 func (p ProfileSchema) Assign(u *ent.User) ProfileSchema {
@@ -27,6 +30,8 @@ func (p ProfileSchema) Assign(u *ent.User) ProfileSchema {
 	p.Dob = u.Dob
 	p.Avatar = u.Avatar
 	p.Role = u.Role
+	p.PendingEmail = u.PendingEmail
+	p.DeletionScheduledAt = u.DeletionScheduledAt
 	return p
 }
 
@@ -42,6 +47,15 @@ type ProfileUpdateSchema struct {
 	Dob      *string `form:"dob" validate:"omitempty,datetime=2006-01-02" example:"2000-09-12"`
 }
 
+type EmailChangeSchema struct {
+	Email    string `json:"email" validate:"required,min=5,email" example:"john@newmail.com"`
+	Password string `json:"password" example:"strongpassword"` // Not needed for social login accounts
+}
+
+type EmailChangeVerifySchema struct {
+	Otp uint32 `json:"otp" validate:"required" example:"123456"`
+}
+
 type LessonProgressInputSchema struct {
 	IsCompleted bool `json:"is_completed"`
 }
@@ -82,3 +96,159 @@ type LeaderboardResponseSchema struct {
 	base.ResponseSchema
 	Data []*LeaderboardEntry `json:"data"`
 }
+
+// ----------------------------------
+// ACCOUNT EXPORT & DELETION
+// --------------------------------
+type ExportEnrollmentSchema struct {
+	Course        string    `json:"course" example:"Learn Go"`
+	Status        string    `json:"status" example:"active"`
+	PaymentStatus string    `json:"payment_status" example:"successful"`
+	Progress      int       `json:"progress" example:"40"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (e ExportEnrollmentSchema) Assign(enrollmentObj *ent.Enrollment) ExportEnrollmentSchema {
+	e.Course = enrollmentObj.Edges.Course.Title
+	e.Status = string(enrollmentObj.Status)
+	e.PaymentStatus = string(enrollmentObj.PaymentStatus)
+	e.Progress = enrollmentObj.Progress
+	e.CreatedAt = enrollmentObj.CreatedAt
+	return e
+}
+
+type ExportLessonProgressSchema struct {
+	Lesson      string    `json:"lesson" example:"Introduction"`
+	CompletedAt time.Time `json:"completed_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (l ExportLessonProgressSchema) Assign(lessonProgress *ent.LessonProgress) ExportLessonProgressSchema {
+	l.Lesson = lessonProgress.Edges.Lesson.Title
+	l.CompletedAt = lessonProgress.CompletedAt
+	l.CreatedAt = lessonProgress.CreatedAt
+	return l
+}
+
+type ExportAnswerSchema struct {
+	Question       string `json:"question" example:"What is a goroutine?"`
+	SelectedOption string `json:"selected_option" example:"A lightweight thread"`
+	IsCorrect      bool   `json:"is_correct"`
+}
+
+func (a ExportAnswerSchema) Assign(answer *ent.Answer) ExportAnswerSchema {
+	a.Question = answer.Edges.Question.Text
+	a.SelectedOption = answer.Edges.SelectedOption.Text
+	a.IsCorrect = answer.IsCorrect
+	return a
+}
+
+type ExportQuizResultSchema struct {
+	Quiz        string               `json:"quiz" example:"Go Basics"`
+	Score       float64              `json:"score" example:"80"`
+	TimeTaken   int                  `json:"time_taken" example:"300"`
+	StartedAt   time.Time            `json:"started_at"`
+	CompletedAt *time.Time           `json:"completed_at"`
+	Answers     []ExportAnswerSchema `json:"answers"`
+}
+
+func (q ExportQuizResultSchema) Assign(result *ent.QuizResult) ExportQuizResultSchema {
+	q.Quiz = result.Edges.Quiz.Title
+	q.Score = result.Score
+	q.TimeTaken = result.TimeTaken
+	q.StartedAt = result.StartedAt
+	q.CompletedAt = result.CompletedAt
+	q.Answers = []ExportAnswerSchema{}
+	for _, answer := range result.Edges.Answers {
+		q.Answers = append(q.Answers, ExportAnswerSchema{}.Assign(answer))
+	}
+	return q
+}
+
+type ExportReviewSchema struct {
+	Course    string    `json:"course" example:"Learn Go"`
+	Rating    float64   `json:"rating" example:"4.5"`
+	Comment   string    `json:"comment" example:"Great course"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r ExportReviewSchema) Assign(review *ent.Review) ExportReviewSchema {
+	r.Course = review.Edges.Course.Title
+	r.Rating = review.Rating
+	r.Comment = review.Comment
+	r.CreatedAt = review.CreatedAt
+	return r
+}
+
+type ExportPaymentSchema struct {
+	Course        string    `json:"course" example:"Learn Go"`
+	Amount        float64   `json:"amount" example:"50"`
+	Status        string    `json:"status" example:"successful"`
+	PaymentMethod string    `json:"payment_method" example:"stripe"`
+	TransactionID string    `json:"transaction_id" example:"cs_test_a1b2c3"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (p ExportPaymentSchema) Assign(payment *ent.Payment) ExportPaymentSchema {
+	p.Course = payment.Edges.Course.Title
+	p.Amount = payment.Amount
+	p.Status = string(payment.Status)
+	p.PaymentMethod = payment.PaymentMethod
+	p.TransactionID = payment.TransactionID
+	p.CreatedAt = payment.CreatedAt
+	return p
+}
+
+type AccountExportSchema struct {
+	ExportedAt     time.Time                    `json:"exported_at"`
+	Profile        ProfileSchema                `json:"profile"`
+	Enrollments    []ExportEnrollmentSchema     `json:"enrollments"`
+	LessonProgress []ExportLessonProgressSchema `json:"lesson_progress"`
+	QuizResults    []ExportQuizResultSchema     `json:"quiz_results"`
+	Reviews        []ExportReviewSchema         `json:"reviews"`
+	Payments       []ExportPaymentSchema        `json:"payments"`
+}
+
+func (a AccountExportSchema) Assign(data *AccountData) AccountExportSchema {
+	a.ExportedAt = time.Now()
+	a.Profile = ProfileSchema{}.Assign(data.User)
+	a.Enrollments = []ExportEnrollmentSchema{}
+	for _, enrollmentObj := range data.Enrollments {
+		a.Enrollments = append(a.Enrollments, ExportEnrollmentSchema{}.Assign(enrollmentObj))
+	}
+	a.LessonProgress = []ExportLessonProgressSchema{}
+	for _, lessonProgress := range data.LessonProgress {
+		a.LessonProgress = append(a.LessonProgress, ExportLessonProgressSchema{}.Assign(lessonProgress))
+	}
+	a.QuizResults = []ExportQuizResultSchema{}
+	for _, result := range data.QuizResults {
+		a.QuizResults = append(a.QuizResults, ExportQuizResultSchema{}.Assign(result))
+	}
+	a.Reviews = []ExportReviewSchema{}
+	for _, review := range data.Reviews {
+		a.Reviews = append(a.Reviews, ExportReviewSchema{}.Assign(review))
+	}
+	a.Payments = []ExportPaymentSchema{}
+	for _, payment := range data.Payments {
+		a.Payments = append(a.Payments, ExportPaymentSchema{}.Assign(payment))
+	}
+	return a
+}
+
+type AccountExportResponseSchema struct {
+	base.ResponseSchema
+	Data AccountExportSchema `json:"data"`
+}
+
+type AccountDeletionSchema struct {
+	Password string `json:"password" example:"strongpassword"` // Not needed for social login accounts
+}
+
+type AccountDeletionData struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+type AccountDeletionResponseSchema struct {
+	base.ResponseSchema
+	Data AccountDeletionData `json:"data"`
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/kayprogrammer/ednet-fiber-api/ent"
+	courseStatus "github.com/kayprogrammer/ednet-fiber-api/ent/course"
 )
 
 func createCategories(db *ent.Client, ctx context.Context) []*ent.Category {
@@ -35,7 +36,13 @@ func createCourses(db *ent.Client, ctx context.Context, instructor *ent.User, ca
 			rand.Shuffle(len(categories), func(i, j int) {
 				categories[i], categories[j] = categories[j], categories[i]
 			})
-			// Create the Course record
+			// Create the Course record. Seeded courses skip moderation
+			status, isPublished := courseStatus.StatusApproved, rand.Intn(2) == 1
+			var publishedAt *time.Time
+			if isPublished {
+				now := time.Now()
+				status, publishedAt = courseStatus.StatusPublished, &now
+			}
 			courseRecord, err := db.Course.Create().
 				SetInstructor(instructor).
 				SetTitle(course.Title).
@@ -48,7 +55,9 @@ func createCourses(db *ent.Client, ctx context.Context, instructor *ent.User, ca
 				SetIsFree(course.IsFree).
 				SetPrice(course.Price).
 				SetDiscountPrice(course.DiscountPrice).
-				SetIsPublished(rand.Intn(2) == 1).
+				SetIsPublished(isPublished).
+				SetStatus(status).
+				SetNillablePublishedAt(publishedAt).
 				Save(ctx)
 
 			if err != nil {
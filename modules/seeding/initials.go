@@ -10,6 +10,8 @@ import (
 
 func CreateInitialData(db *ent.Client, ctx context.Context, cfg config.Config) {
 	log.Println("Creating Initial Data....")
+	adminManager.EnsureSystemRoles(db, ctx)
+	adminManager.EnsureCourseStatuses(db, ctx)
 	admin := createAdmin(db, ctx, cfg)
 	student := createStudent(db, ctx, cfg)
 	instructor := createInstructor(db, ctx, cfg)